@@ -3,10 +3,14 @@ package main
 import (
 	"fmt"
 	"os"
+	"strings"
+	"time"
 
 	"github.com/gin-contrib/cors"
 	"github.com/gin-gonic/gin"
+	"github.com/makersacademy/go-react-acebook-template/api/src/achievements"
 	"github.com/makersacademy/go-react-acebook-template/api/src/env"
+	"github.com/makersacademy/go-react-acebook-template/api/src/jobs"
 	"github.com/makersacademy/go-react-acebook-template/api/src/models"
 	"github.com/makersacademy/go-react-acebook-template/api/src/routes"
 	"github.com/makersacademy/go-react-acebook-template/api/src/seeds"
@@ -31,7 +35,7 @@ func main() {
 	for _, arg := range args {
 		if arg == "seed" {
 			seeds.Reseed(models.Database)
-		} else {
+		} else if !isMaintenanceCommand(arg) {
 			fmt.Println("INCORRECT COMMAND LINE ARGUMENT, did you mean 'seed'?")
 		}
 	}
@@ -39,12 +43,48 @@ func main() {
 	// Migrate the database
 	models.AutoMigrateModels()
 
+	// Run any maintenance commands now that the tables are up to date
+	for _, arg := range args {
+		if isMaintenanceCommand(arg) {
+			runMaintenanceCommand(arg)
+		}
+	}
+
+	// Start the background jobs (e.g. resolving disputes the author has ignored)
+	jobs.Start()
+
 	// Start the server
 	app.Run(":8082")
 }
 
+// Maintenance commands fix up or fill in data, e.g. "./api rebuild-xp".
+// They run after the database has been migrated, and then the server starts as normal.
+var maintenanceCommands = map[string]func() error{
+	"rebuild-xp":         models.RebuildXPLedger,
+	"backfill-badges":    achievements.Backfill,
+	"grant-points":       models.GrantStartingPoints,
+	"reconcile-counters": reconcileCounters,
+	"make-moderators":    makeModerators,
+	"migrate-likes":      models.MigrateLikesToReactions,
+}
+
+func isMaintenanceCommand(arg string) bool {
+	_, exists := maintenanceCommands[arg]
+	return exists
+}
+
+func runMaintenanceCommand(arg string) {
+	fmt.Printf("Running %s...\n", arg)
+	if err := maintenanceCommands[arg](); err != nil {
+		fmt.Printf("%s failed: %v\n", arg, err)
+		return
+	}
+	fmt.Printf("%s finished\n", arg)
+}
+
 func setupApp() *gin.Engine {
-	app := gin.Default()
+	app := gin.New()
+	app.Use(gin.LoggerWithFormatter(logFormatter), gin.Recovery())
 	setupCORS(app)
 
 	app.Static("/uploads", "./uploads") // used to serve the profile pictures
@@ -61,3 +101,69 @@ func setupCORS(app *gin.Engine) {
 
 	app.Use(cors.New(config))
 }
+
+// logFormatter writes the same access log line as gin's default logger, but with any ?token= in
+// the URL blanked out. Event streams and websockets send the JWT that way (see
+// middleware.StreamAuthenticationMiddleware), and it mustn't end up in the logs.
+func logFormatter(params gin.LogFormatterParams) string {
+	if params.Latency > time.Minute {
+		params.Latency = params.Latency.Truncate(time.Second)
+	}
+	return fmt.Sprintf("[GIN] %v | %3d | %13v | %15s | %-7s %#v\n%s",
+		params.TimeStamp.Format("2006/01/02 - 15:04:05"),
+		params.StatusCode,
+		params.Latency,
+		params.ClientIP,
+		params.Method,
+		redactToken(params.Path),
+		params.ErrorMessage,
+	)
+}
+
+// redactToken replaces the value of any token query parameter in the path with REDACTED
+func redactToken(path string) string {
+	base, query, found := strings.Cut(path, "?")
+	if !found {
+		return path
+	}
+
+	params := strings.Split(query, "&")
+	for index, param := range params {
+		if name, _, _ := strings.Cut(param, "="); name == "token" {
+			params[index] = "token=REDACTED"
+		}
+	}
+	return base + "?" + strings.Join(params, "&")
+}
+
+// reconcileCounters recounts every post's likes and comments and reports any that had drifted
+func reconcileCounters() error {
+	drifts, err := models.ReconcilePostCounters()
+	if err != nil {
+		return err
+	}
+
+	for _, drift := range drifts {
+		fmt.Printf("Post %d: likes %d -> %d, comments %d -> %d\n",
+			drift.PostID, drift.LikeCount, drift.ActualLikes, drift.CommentCount, drift.ActualComments)
+	}
+	fmt.Printf("%d post(s) had drifted\n", len(drifts))
+	return nil
+}
+
+// makeModerators gives the moderator role to the comma separated usernames in MODERATOR_USERNAMES
+func makeModerators() error {
+	var usernames []string
+	for _, username := range strings.Split(os.Getenv("MODERATOR_USERNAMES"), ",") {
+		if username = strings.TrimSpace(username); username != "" {
+			usernames = append(usernames, username)
+		}
+	}
+
+	changed, err := models.MakeModerators(usernames)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("%d user(s) made moderators\n", changed)
+	return nil
+}
@@ -0,0 +1,151 @@
+package achievements
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+)
+
+// Events that can earn a badge. Controllers call Evaluate with one of these
+// after the action has been saved.
+const (
+	EventPostCreated       = "post_created"       // the user posted a question
+	EventAttemptMade       = "attempt_made"       // the user guessed at someone's question
+	EventQuestionAttempted = "question_attempted" // someone guessed at the user's question
+	EventLikeReceived      = "like_received"      // someone liked the user's question
+	EventCommentCreated    = "comment_created"    // the user left a comment
+)
+
+// A Badge is an achievement rule: earn it once Metric reaches Threshold.
+// Rules are declared in badges.json rather than in code, so adding a badge only needs a new entry there.
+type Badge struct {
+	Code        string   `json:"code"`
+	Name        string   `json:"name"`
+	Description string   `json:"description"`
+	Metric      string   `json:"metric"`
+	Threshold   int64    `json:"threshold"`
+	Events      []string `json:"events"` // which events could change the metric, so we only check relevant rules
+}
+
+// Metrics that rules can use. Each one works out a number for a user from their history.
+var metrics = map[string]func(userID uint) (int64, error){
+	"posts_created":          models.CountPostsByUserID,
+	"comments_posted":        models.CountCommentsByUserID,
+	"most_likes_on_a_post":   models.CountMostLikesOnUserPost,
+	"users_stumped":          models.CountUsersStumpedByUser,
+	"longest_correct_streak": models.CountLongestCorrectStreak,
+}
+
+//go:embed badges.json
+var badgesJSON []byte
+
+var badges = mustLoadBadges(badgesJSON)
+
+func mustLoadBadges(data []byte) []Badge {
+	var loaded []Badge
+	if err := json.Unmarshal(data, &loaded); err != nil {
+		panic(fmt.Sprintf("invalid badges.json: %v", err))
+	}
+	for _, badge := range loaded {
+		if _, exists := metrics[badge.Metric]; !exists {
+			panic(fmt.Sprintf("badge %q uses unknown metric %q", badge.Code, badge.Metric))
+		}
+	}
+	return loaded
+}
+
+// Returns every badge that can be earned
+func AllBadges() []Badge {
+	return badges
+}
+
+// Looks up a badge's details by its code
+func FindBadge(code string) (Badge, bool) {
+	for _, badge := range badges {
+		if badge.Code == code {
+			return badge, true
+		}
+	}
+	return Badge{}, false
+}
+
+// Evaluate checks the rules that the event could affect and grants any badges the user has now earned.
+// Badges the user already has aren't checked again, so their metrics stop costing anything once earned.
+// Badges are a bonus on top of the real action, so errors are logged rather than returned.
+func Evaluate(userID uint, event string) {
+	earned, err := earnedBadgeCodes(userID)
+	if err != nil {
+		fmt.Printf("Error fetching badges for user %d: %v\n", userID, err)
+		return
+	}
+
+	for _, badge := range badges {
+		if !listens(badge, event) || earned[badge.Code] {
+			continue
+		}
+		if err := evaluateBadge(userID, badge, time.Now()); err != nil {
+			fmt.Printf("Error checking badge %s for user %d: %v\n", badge.Code, userID, err)
+		}
+	}
+}
+
+// Backfill checks every rule for every user, granting badges earned from existing history.
+// Granting is idempotent, so it's safe to run more than once.
+func Backfill() error {
+	userIDs, err := models.FetchAllUserIDs()
+	if err != nil {
+		return err
+	}
+
+	for _, userID := range userIDs {
+		earned, err := earnedBadgeCodes(userID)
+		if err != nil {
+			return err
+		}
+		for _, badge := range badges {
+			if earned[badge.Code] {
+				continue
+			}
+			if err := evaluateBadge(userID, badge, time.Now()); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func earnedBadgeCodes(userID uint) (map[string]bool, error) {
+	userBadges, err := models.FetchBadgesByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	earned := make(map[string]bool, len(*userBadges))
+	for _, userBadge := range *userBadges {
+		earned[userBadge.BadgeCode] = true
+	}
+	return earned, nil
+}
+
+func listens(badge Badge, event string) bool {
+	for _, badgeEvent := range badge.Events {
+		if badgeEvent == event {
+			return true
+		}
+	}
+	return false
+}
+
+func evaluateBadge(userID uint, badge Badge, now time.Time) error {
+	value, err := metrics[badge.Metric](userID)
+	if err != nil {
+		return err
+	}
+	if value < badge.Threshold {
+		return nil
+	}
+	return models.GrantBadge(userID, badge.Code, now)
+}
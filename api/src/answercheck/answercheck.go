@@ -0,0 +1,101 @@
+package answercheck
+
+import (
+	"strings"
+	"unicode"
+)
+
+// IsCorrect compares a user's guess against a post's answer.
+// Case, punctuation and extra spaces are ignored and small typos are allowed, but the guess
+// has to be the whole answer: part of it (e.g. "his name" for "38 years old! His name was
+// Cream Puff.") doesn't count. Other ways of saying it can be added as alternate answers.
+func IsCorrect(guess string, answer string) bool {
+	normalisedGuess := Normalise(guess)
+	normalisedAnswer := Normalise(answer)
+
+	if normalisedGuess == "" || normalisedAnswer == "" {
+		return false
+	}
+
+	// Exact match once everything has been tidied up
+	if normalisedGuess == normalisedAnswer {
+		return true
+	}
+
+	// Allow a typo or two, scaled to the length of the answer
+	if levenshtein(normalisedGuess, normalisedAnswer) <= allowedTypos(normalisedAnswer) {
+		return true
+	}
+
+	return false
+}
+
+// Contains reports whether some text gives away the answer, e.g. a comment that
+// says "Canberra is correct". It checks each run of words as long as the whole answer
+// with the same forgiving rules as IsCorrect, so typos are allowed but sharing a word or
+// two with it (e.g. "What was his name again?" for "His name was Cream Puff") isn't enough.
+func Contains(text string, answer string) bool {
+	normalisedAnswer := Normalise(answer)
+	if len(normalisedAnswer) < 3 {
+		return false
+	}
+
+	words := strings.Fields(Normalise(text))
+	answerLength := len(strings.Fields(normalisedAnswer))
+
+	for start := 0; start+answerLength <= len(words); start++ {
+		if IsCorrect(strings.Join(words[start:start+answerLength], " "), answer) {
+			return true
+		}
+	}
+	return false
+}
+
+// Normalise lowercases the text, removes punctuation and squashes whitespace
+func Normalise(text string) string {
+	var builder strings.Builder
+	for _, r := range strings.ToLower(text) {
+		if unicode.IsLetter(r) || unicode.IsNumber(r) {
+			builder.WriteRune(r)
+		} else {
+			builder.WriteRune(' ')
+		}
+	}
+	return strings.Join(strings.Fields(builder.String()), " ")
+}
+
+func allowedTypos(answer string) int {
+	switch {
+	case len(answer) <= 4:
+		return 0
+	case len(answer) <= 8:
+		return 1
+	default:
+		return 2
+	}
+}
+
+// levenshtein returns the number of single character edits needed to turn a into b
+func levenshtein(a string, b string) int {
+	first, second := []rune(a), []rune(b)
+	previous := make([]int, len(second)+1)
+	current := make([]int, len(second)+1)
+
+	for j := range previous {
+		previous[j] = j
+	}
+
+	for i := 1; i <= len(first); i++ {
+		current[0] = i
+		for j := 1; j <= len(second); j++ {
+			cost := 1
+			if first[i-1] == second[j-1] {
+				cost = 0
+			}
+			current[j] = min(previous[j]+1, current[j-1]+1, previous[j-1]+cost)
+		}
+		previous, current = current, previous
+	}
+
+	return previous[len(second)]
+}
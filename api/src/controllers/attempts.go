@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/makersacademy/go-react-acebook-template/api/src/achievements"
+	"github.com/makersacademy/go-react-acebook-template/api/src/answercheck"
+	"github.com/makersacademy/go-react-acebook-template/api/src/auth"
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+)
+
+type createAttemptRequestBody struct {
+	Guess string `json:"guess"`
+}
+
+func CreateAttempt(ctx *gin.Context) {
+	// ======================= Get the post ID from the URL params ==============================
+	postIDParam := ctx.Param("id")
+	postID, err := strconv.ParseUint(postIDParam, 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid post ID"})
+		return
+	}
+
+	// ============================= Get the request body =========================================
+	var requestBody createAttemptRequestBody
+	err = ctx.BindJSON(&requestBody)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": err})
+		return
+	}
+
+	if len(answercheck.Normalise(requestBody.Guess)) == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Guess cannot be blank"})
+		return
+	}
+
+	// ========== Get the user ID from the context (set by AuthenticationMiddleware) ============
+	val, _ := ctx.Get("userID")
+	userID := val.(string)
+	userIDUint, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+	token, _ := auth.GenerateToken(userID) // Generate new token for the response
+
+	// ============================= Fetch the post by ID =======================================
+	post, err := models.FetchPostForViewer(uint(postID), uint(userIDUint))
+	if err != nil {
+		if err.Error() == "record not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"message": "Post not found"})
+			return
+		}
+		SendInternalError(ctx, err)
+		return
+	}
+
+	// ============================= Authors can't answer their own questions ==================
+	if post.UserID == uint(userIDUint) {
+		ctx.JSON(http.StatusForbidden, gin.H{"message": "You can't answer your own question"})
+		return
+	}
+
+	// ============================= No need to keep guessing once you've got it ===============
+	alreadyCorrect, err := models.HasUserAnsweredCorrectly(uint(userIDUint), post.ID)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+	if alreadyCorrect {
+		ctx.JSON(http.StatusConflict, gin.H{"message": "You have already answered this question correctly", "token": token})
+		return
+	}
+
+	// ============================= Was the answer already on show to them? ===================
+	// A guess made with the answer in front of you still counts, but earns nothing
+	_, answerSeen, err := answerForViewer(post, uint(userIDUint))
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	// ============================= Check the guess and save the attempt =======================
+	correct, err := post.IsGuessCorrect(requestBody.Guess)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	newAttempt := models.Attempt{
+		PostID:     post.ID,
+		UserID:     uint(userIDUint),
+		Guess:      requestBody.Guess,
+		Correct:    correct,
+		AnswerSeen: answerSeen,
+	}
+
+	_, err = newAttempt.Save()
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	// XP and points are awarded once per post, however many guesses it took, and only for working it out
+	var bountyWon *JSONBounty
+	if newAttempt.Correct && !newAttempt.AnswerSeen {
+		bountyWon = rewardCorrectAnswer(newAttempt.UserID, post.ID)
+	}
+
+	// ============================= Check for any badges this has earned ======================
+	achievements.Evaluate(newAttempt.UserID, achievements.EventAttemptMade)
+	achievements.Evaluate(post.UserID, achievements.EventQuestionAttempted)
+
+	// ============================= The attempt may have unlocked the answer ==================
+	answer, answerRevealed, err := answerForViewer(post, uint(userIDUint))
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	// ========================== Send response (including token) ================================
+	ctx.JSON(http.StatusCreated, gin.H{
+		"correct":        newAttempt.Correct,
+		"answer":         answer,
+		"answerRevealed": answerRevealed,
+		"bountyWon":      bountyWon,
+		"token":          token,
+	})
+}
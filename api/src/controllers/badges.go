@@ -0,0 +1,40 @@
+package controllers
+
+import (
+	"time"
+
+	"github.com/makersacademy/go-react-acebook-template/api/src/achievements"
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+)
+
+type JSONBadge struct {
+	Code        string `json:"code"`
+	Name        string `json:"name"`
+	Description string `json:"description"`
+	AwardedAt   string `json:"awarded_at"`
+}
+
+// userBadges returns the badges a user has earned, with their names and descriptions filled in
+func userBadges(userID uint) ([]JSONBadge, error) {
+	earned, err := models.FetchBadgesByUserID(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonBadges := make([]JSONBadge, 0)
+	for _, userBadge := range *earned {
+		// Skip badges that have since been removed from badges.json
+		badge, exists := achievements.FindBadge(userBadge.BadgeCode)
+		if !exists {
+			continue
+		}
+
+		jsonBadges = append(jsonBadges, JSONBadge{
+			Code:        badge.Code,
+			Name:        badge.Name,
+			Description: badge.Description,
+			AwardedAt:   userBadge.AwardedAt.Format(time.RFC3339),
+		})
+	}
+	return jsonBadges, nil
+}
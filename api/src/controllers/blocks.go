@@ -0,0 +1,133 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/makersacademy/go-react-acebook-template/api/src/auth"
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+)
+
+// ===== POST /users/:id/block =====
+// Blocks a user. Neither of you will see the other's posts and comments, or be able to comment on,
+// like or message them, and you both stop following each other.
+func BlockUser(ctx *gin.Context) {
+	blockerID, blockedID, token, ok := parseUserRelationRequest(ctx, "block")
+	if !ok {
+		return
+	}
+
+	// ========== Block them (blocking twice is fine) ==========
+	if _, err := models.BlockUser(blockerID, blockedID); err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Blocked", "token": token})
+}
+
+// ===== DELETE /users/:id/block =====
+func UnblockUser(ctx *gin.Context) {
+	blockerID, blockedID, token, ok := parseFollowRequest(ctx)
+	if !ok {
+		return
+	}
+
+	// ========== Unblock them (unblocking twice is fine) ==========
+	if err := models.UnblockUser(blockerID, blockedID); err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Unblocked", "token": token})
+}
+
+// ===== POST /users/:id/mute =====
+// Mutes a user, hiding their posts and comments from you. They aren't told, and nothing changes for them.
+func MuteUser(ctx *gin.Context) {
+	muterID, mutedID, token, ok := parseUserRelationRequest(ctx, "mute")
+	if !ok {
+		return
+	}
+
+	// ========== Mute them (muting twice is fine) ==========
+	if err := models.MuteUser(muterID, mutedID); err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Muted", "token": token})
+}
+
+// ===== DELETE /users/:id/mute =====
+func UnmuteUser(ctx *gin.Context) {
+	muterID, mutedID, token, ok := parseFollowRequest(ctx)
+	if !ok {
+		return
+	}
+
+	// ========== Unmute them (unmuting twice is fine) ==========
+	if err := models.UnmuteUser(muterID, mutedID); err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Unmuted", "token": token})
+}
+
+// ===== GET /users/me/blocks =====
+func GetBlockedUsers(ctx *gin.Context) {
+	sendOwnUserList(ctx, "blocked", models.FetchBlockedUsers)
+}
+
+// ===== GET /users/me/mutes =====
+func GetMutedUsers(ctx *gin.Context) {
+	sendOwnUserList(ctx, "muted", models.FetchMutedUsers)
+}
+
+// ======================== Helper functions for blocks and mutes ==============================
+
+// parseUserRelationRequest gets the current user and the (existing) user in the URL they want to
+// block or mute, which can't be themselves
+func parseUserRelationRequest(ctx *gin.Context, action string) (uint, uint, string, bool) {
+	userID, otherUserID, token, ok := parseFollowRequest(ctx)
+	if !ok {
+		return 0, 0, "", false
+	}
+
+	// ========== You can't block or mute yourself ==========
+	if userID == otherUserID {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "You can't " + action + " yourself"})
+		return 0, 0, "", false
+	}
+
+	// ========== Check the user exists ==========
+	if _, err := models.FindUser(strconv.Itoa(int(otherUserID))); err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "User not found"})
+		return 0, 0, "", false
+	}
+
+	return userID, otherUserID, token, true
+}
+
+// sendOwnUserList sends one of the current user's private lists of people (like who they've blocked)
+func sendOwnUserList(ctx *gin.Context, key string, fetch func(userID uint) (*[]models.User, error)) {
+	val, _ := ctx.Get("userID")
+	userID := val.(string)
+	userIDUint, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	users, err := fetch(uint(userIDUint))
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	token, _ := auth.GenerateToken(userID)
+	jsonUsers := toJSONFollowUsers(users)
+	ctx.JSON(http.StatusOK, gin.H{key: jsonUsers, "count": len(jsonUsers), "token": token})
+}
@@ -0,0 +1,201 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/makersacademy/go-react-acebook-template/api/src/auth"
+	"github.com/makersacademy/go-react-acebook-template/api/src/env"
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+	"gorm.io/gorm"
+)
+
+type JSONBounty struct {
+	Amount    int    `json:"amount"`
+	ExpiresAt string `json:"expires_at"`
+	Status    string `json:"status"`
+	WinnerID  *uint  `json:"winner_id"`
+}
+
+type createBountyRequestBody struct {
+	Amount      int `json:"amount"`
+	WindowHours int `json:"window_hours"`
+}
+
+// Lets an author put a points bounty on their own question
+func CreateBounty(ctx *gin.Context) {
+	// ======================= Get the post ID from the URL params ==============================
+	postID, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid post ID"})
+		return
+	}
+
+	// ============================= Get the request body =========================================
+	var requestBody createBountyRequestBody
+	if err := ctx.BindJSON(&requestBody); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": err})
+		return
+	}
+
+	if requestBody.Amount < 1 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Bounty must be at least 1 point"})
+		return
+	}
+
+	// Default to a day, and don't let bounties run forever
+	maxWindowHours := env.GetInt("BOUNTY_MAX_WINDOW_HOURS", 24*7)
+	if requestBody.WindowHours == 0 {
+		requestBody.WindowHours = 24
+	}
+	if requestBody.WindowHours < 1 || requestBody.WindowHours > maxWindowHours {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": fmt.Sprintf("Bounty window must be between 1 and %d hours", maxWindowHours)})
+		return
+	}
+
+	// ========== Get the user ID from the context (set by AuthenticationMiddleware) ============
+	val, _ := ctx.Get("userID")
+	userID := val.(string)
+	userIDUint, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	// ============================= Check the post belongs to the user ==========================
+	post, err := models.FetchPostForViewer(uint(postID), uint(userIDUint))
+	if err != nil {
+		if err.Error() == "record not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"message": "Post not found"})
+			return
+		}
+		SendInternalError(ctx, err)
+		return
+	}
+
+	if post.UserID != uint(userIDUint) {
+		ctx.JSON(http.StatusForbidden, gin.H{"message": "You can only put a bounty on your own posts"})
+		return
+	}
+
+	// Nobody else can see a post a moderator has hidden, so nobody could win it
+	if post.HiddenAt != nil {
+		ctx.JSON(http.StatusConflict, gin.H{"message": "This post has been hidden by a moderator"})
+		return
+	}
+
+	// ============================= The answer has to stay hidden while the bounty runs ========
+	expiresAt := time.Now().Add(time.Duration(requestBody.WindowHours) * time.Hour)
+	if !post.KeepsAnswerHiddenUntilSolved(expiresAt) {
+		ctx.JSON(http.StatusConflict, gin.H{"message": bountyRevealPolicyMessage})
+		return
+	}
+
+	// ============================= Create the bounty (and take the stake) ======================
+	bounty, err := models.CreateBounty(post.ID, post.UserID, requestBody.Amount, expiresAt)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrNotEnoughPoints):
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "You don't have enough points for that bounty"})
+		case errors.Is(err, models.ErrBountyAlreadyExists):
+			ctx.JSON(http.StatusConflict, gin.H{"message": "This post already has a bounty"})
+		case errors.Is(err, models.ErrAlreadyAnswered):
+			ctx.JSON(http.StatusConflict, gin.H{"message": "Someone has already answered this question correctly"})
+		default:
+			SendInternalError(ctx, err)
+		}
+		return
+	}
+
+	// ========================== Generate token & send response ================================
+	token, _ := auth.GenerateToken(userID)
+	ctx.JSON(http.StatusCreated, gin.H{"message": "Bounty created", "bounty": toJSONBounty(bounty), "token": token})
+}
+
+// ======================== Helper functions for points and bounties ==============================
+
+const bountyRevealPolicyMessage = "A bounty needs the answer kept hidden until someone gets it right: " +
+	"use the after_correct_count reveal policy, or at_time with a reveal time after the bounty ends"
+
+// checkRevealPolicyKeepsBounty refuses a reveal policy change that would show the answer while
+// the post has an open bounty. post has the reveal settings as they would be after the change.
+func checkRevealPolicyKeepsBounty(ctx *gin.Context, post *models.Post) bool {
+	bounty, err := models.FetchBountyByPostID(post.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return true
+		}
+		SendInternalError(ctx, err)
+		return false
+	}
+
+	if bounty.Status == models.BountyOpen && !post.KeepsAnswerHiddenUntilSolved(bounty.ExpiresAt) {
+		ctx.JSON(http.StatusConflict, gin.H{"message": bountyRevealPolicyMessage})
+		return false
+	}
+	return true
+}
+
+// checkAnswerKeepsBounty refuses a change to the answer once the post has a bounty that's open or
+// has been won, so the author can't move the goalposts on a prize. A better answer can still be
+// put forward with a dispute, which the community votes on.
+func checkAnswerKeepsBounty(ctx *gin.Context, post *models.Post, updates map[string]interface{}) bool {
+	if answer, exists := updates["answer"].(string); !exists || answer == post.Answer {
+		return true
+	}
+
+	bounty, err := models.FetchBountyByPostID(post.ID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return true
+		}
+		SendInternalError(ctx, err)
+		return false
+	}
+
+	if bounty.Status != models.BountyCancelled {
+		ctx.JSON(http.StatusConflict, gin.H{"message": "The answer can't be changed once the post has a bounty: open a dispute instead"})
+		return false
+	}
+	return true
+}
+
+// rewardCorrectAnswer gives XP and points for a correct answer and pays out the post's bounty if
+// this user was the first to get it right. It returns the bounty they won (if any) for the response.
+// It's only for answers worked out without the answer on show (see Attempt.AnswerSeen).
+// Rewards are a bonus on top of the attempt itself, so errors are logged rather than failing the request.
+func rewardCorrectAnswer(userID uint, postID uint) *JSONBounty {
+	// Rewards are booked against the current version of the answer (see models.AnswerRewardSource)
+	sourceType, sourceID, err := models.AnswerRewardSource(models.Database, postID)
+	if err != nil {
+		fmt.Printf("Error rewarding user %d for answering post %d: %v\n", userID, postID, err)
+		return nil
+	}
+	awardXP(userID, models.XPCorrectAnswer, sourceType, sourceID)
+	if err := models.AddPoints(models.Database, userID, models.CorrectAnswerPoints, models.PointsCorrectAnswer, sourceType, sourceID); err != nil {
+		fmt.Printf("Error adding points for user %d: %v\n", userID, err)
+	}
+
+	bounty, err := models.ClaimBounty(postID, userID, time.Now())
+	if err != nil {
+		fmt.Printf("Error claiming bounty on post %d for user %d: %v\n", postID, userID, err)
+		return nil
+	}
+	if bounty == nil {
+		return nil
+	}
+	return toJSONBounty(bounty)
+}
+
+func toJSONBounty(bounty *models.Bounty) *JSONBounty {
+	return &JSONBounty{
+		Amount:    bounty.Amount,
+		ExpiresAt: bounty.ExpiresAt.Format(time.RFC3339),
+		Status:    bounty.Status,
+		WinnerID:  bounty.WinnerID,
+	}
+}
@@ -1,20 +1,36 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
+	"strings"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/makersacademy/go-react-acebook-template/api/src/achievements"
 	"github.com/makersacademy/go-react-acebook-template/api/src/auth"
+	"github.com/makersacademy/go-react-acebook-template/api/src/env"
 	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+	"gorm.io/gorm"
 )
 
 type JSONComment struct {
-	ID       uint   `json:"_id"`
-	Content  string `json:"content"`
-	UserID   uint   `json:"userID"`
-	Username string `json:"username"`
-	PostID   uint   `json:"post_id"`
+	ID         uint          `json:"_id"`
+	Content    string        `json:"content"`
+	UserID     uint          `json:"userID"`
+	Username   string        `json:"username"`
+	PostID     uint          `json:"post_id"`
+	ParentID   *uint         `json:"parent_id"`
+	Depth      int           `json:"depth"`
+	ReplyCount int           `json:"replyCount"`
+	Deleted    bool          `json:"deleted"`
+	Moderated  bool          `json:"hiddenByModerator"` // only its author can see it
+	EditedAt   *time.Time    `json:"edited_at"`         // null if the comment has never been edited
+	Spoiler    bool          `json:"spoiler"`           // the author tagged it as a spoiler
+	Hidden     bool          `json:"spoilerHidden"`     // hidden because it gives away the answer
+	Mentions   []JSONMention `json:"mentions"`
+	Reactions  JSONReactions `json:"reactions"`
 }
 
 func GetCommentsByPostID(ctx *gin.Context) {
@@ -27,44 +43,129 @@ func GetCommentsByPostID(ctx *gin.Context) {
 		return
 	}
 
-	// ========== Fetch the comments for the post ==========
-	comments, err := models.FetchCommentsByPostID(uint(postIDUint))
-	if err != nil {
-		SendInternalError(ctx, err)
-		return
+	// ========== Only fetch the replies to one comment if asked ==========
+	var parentID *uint
+	if parent := ctx.Query("parent_id"); parent != "" {
+		parentIDUint, err := strconv.ParseUint(parent, 10, 32)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid parent ID"})
+			return
+		}
+		id := uint(parentIDUint)
+		parentID = &id
 	}
 
 	// ========== Get the user ID from the context ==========
 	val, _ := ctx.Get("userID")
 	userID := val.(string)
 	token, _ := auth.GenerateToken(userID)
+	viewerID, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	// ========== Check the user can see the post ==========
+	if _, err := models.FetchPostForViewer(uint(postIDUint), uint(viewerID)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"message": "Post not found"})
+			return
+		}
+		SendInternalError(ctx, err)
+		return
+	}
+
+	// ========== Fetch a page of the post's comments ==========
+	page, ok := parsePage(ctx)
+	if !ok {
+		return
+	}
+	comments, pageInfo, err := models.FetchCommentsPageByPostID(uint(postIDUint), parentID, uint(viewerID), page)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	// ========== Get the reactions for the page of comments ==========
+	targets := make([]models.ReactionTarget, 0, len(*comments))
+	for _, comment := range *comments {
+		targets = append(targets, models.OnComment(comment.ID))
+	}
+	reactions, err := models.FetchReactionSummaries(targets, uint(viewerID))
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	// ========== Get the @mentions in the page of comments ==========
+	mentions, err := models.FetchMentions(targets)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	// ========== Find the comments that would spoil the answer for the viewer ==========
+	hiddenSpoilers, err := models.FindHiddenSpoilers(uint(postIDUint), *comments, uint(viewerID))
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	// ========== Get the authors of the page of comments in one go ==========
+	authorIDs := make([]uint, 0, len(*comments))
+	for _, comment := range *comments {
+		authorIDs = append(authorIDs, comment.UserID)
+	}
+	authors, err := models.FindUsersByIDs(authorIDs)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
 
 	// ========== Convert comments to JSON Structs ==========
 	jsonComments := make([]JSONComment, 0)
 	for _, comment := range *comments {
-		// Find the user who made the comment to get their username
 		username := "Unknown" // Default if user not found
-		user, err := models.FindUser(strconv.Itoa(int(comment.UserID)))
-		if err == nil {
-			username = user.Username
+		if author, found := authors[comment.UserID]; found {
+			username = author.Username
 		}
 
-		jsonComments = append(jsonComments, JSONComment{
-			ID:       comment.ID,
-			Content:  comment.Content,
-			UserID:   comment.UserID,
-			Username: username,
-			PostID:   comment.PostID,
-		})
+		jsonComment := JSONComment{
+			ID:         comment.ID,
+			Content:    comment.Content,
+			UserID:     comment.UserID,
+			Username:   username,
+			PostID:     comment.PostID,
+			ParentID:   comment.ParentID,
+			Depth:      comment.Depth,
+			ReplyCount: comment.ReplyCount,
+			EditedAt:   comment.EditedAt,
+			Spoiler:    comment.Spoiler,
+			Moderated:  comment.HiddenAt != nil,
+			Mentions:   []JSONMention{},
+			Reactions:  toJSONReactions(reactions[comment.ID]),
+		}
+		// Deleted comments that still have replies are kept as placeholders
+		if comment.IsDeleted() {
+			jsonComment.UserID, jsonComment.Username = 0, ""
+			jsonComment.Content, jsonComment.Deleted = models.DeletedCommentContent, true
+		} else if hiddenSpoilers[comment.ID] {
+			jsonComment.Content, jsonComment.Hidden = models.HiddenSpoilerContent, true
+		} else {
+			jsonComment.Mentions = toJSONMentions(comment.Content, mentions[comment.ID])
+		}
+		jsonComments = append(jsonComments, jsonComment)
 	}
 
 	// ========== Send the response (w/ token) ==========
-	ctx.JSON(http.StatusOK, gin.H{"comments": jsonComments, "token": token})
+	ctx.JSON(http.StatusOK, withPageInfo(gin.H{"comments": jsonComments, "token": token}, pageInfo))
 }
 
 type createCommentRequestBody struct {
-	Content string `json:"content"`
-	PostID  uint   `json:"post_id"`
+	Content  string `json:"content"`
+	PostID   uint   `json:"post_id"`
+	ParentID *uint  `json:"parent_id"` // set when replying to another comment
+	Spoiler  bool   `json:"spoiler"`   // the author says the comment gives away the answer
 }
 
 func CreateComment(ctx *gin.Context) {
@@ -92,11 +193,46 @@ func CreateComment(ctx *gin.Context) {
 		return
 	}
 
+	// ========== Check the user can see (and so comment on) the post ==========
+	post, err := models.FetchPostForViewer(requestBody.PostID, uint(userIDUint))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"message": "Post not found"})
+			return
+		}
+		SendInternalError(ctx, err)
+		return
+	}
+
 	// ========== Create a new comment ==========
 	newComment := models.Comment{
 		Content: requestBody.Content,
 		PostID:  requestBody.PostID,
 		UserID:  uint(userIDUint),
+		Spoiler: requestBody.Spoiler,
+	}
+
+	// ========== Attach a reply to the comment it's replying to ==========
+	if requestBody.ParentID != nil {
+		parent, err := models.FetchCommentForViewer(*requestBody.ParentID, uint(userIDUint))
+		if err != nil {
+			if err.Error() == "record not found" {
+				ctx.JSON(http.StatusNotFound, gin.H{"message": "Parent comment not found"})
+				return
+			}
+			SendInternalError(ctx, err)
+			return
+		}
+		if parent.PostID != requestBody.PostID {
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "Parent comment is on a different post"})
+			return
+		}
+		if parent.Depth+1 > env.GetInt("COMMENT_MAX_DEPTH", 3) {
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "Replies can't be nested any deeper"})
+			return
+		}
+		newComment.ParentID = &parent.ID
+		newComment.Depth = parent.Depth + 1
 	}
 
 	// ========= Save new comment to DB =========
@@ -106,6 +242,13 @@ func CreateComment(ctx *gin.Context) {
 		return
 	}
 
+	// ========= Tell the post's author, and anyone mentioned in the comment =========
+	notify(models.NotificationEvent{UserID: post.UserID, ActorID: newComment.UserID, Kind: models.NotificationCommented, PostID: &post.ID})
+	recordMentions(newComment.UserID, models.OnComment(newComment.ID), newComment.PostID, newComment.Content)
+
+	// ========= Check for any badges this has earned =========
+	achievements.Evaluate(newComment.UserID, achievements.EventCommentCreated)
+
 	// ========== Send the response (w/ token) ==========
 	token, _ := auth.GenerateToken(userID)
 	ctx.JSON(http.StatusCreated, gin.H{"message": "Comment created", "token": token})
@@ -169,3 +312,140 @@ func DeleteCommentByID(ctx *gin.Context) {
 	token, _ := auth.GenerateToken(userIDstring)
 	ctx.JSON(http.StatusOK, gin.H{"message": "Comment deleted successfully", "token": token})
 }
+
+type editCommentRequestBody struct {
+	Content string `json:"content"`
+}
+
+// ===== PUT /comments/:id =====
+// Lets the author fix their comment for a short while after posting it.
+// The old content is kept as a revision, and the comment is marked as edited.
+func EditComment(ctx *gin.Context) {
+	comment, userID, token, ok := parseCommentRequest(ctx)
+	if !ok {
+		return
+	}
+
+	// ========== Get the request body ==========
+	var requestBody editCommentRequestBody
+	if err := ctx.BindJSON(&requestBody); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": err})
+		return
+	}
+	if len(strings.TrimSpace(requestBody.Content)) == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Comment content empty"})
+		return
+	}
+
+	// ========== Only the author can edit, and only within the edit window ==========
+	if comment.UserID != userID {
+		ctx.JSON(http.StatusForbidden, gin.H{"message": "You are not authorised to edit this comment"})
+		return
+	}
+	now := time.Now()
+	if now.After(comment.CreatedAt.Add(env.GetDuration("COMMENT_EDIT_WINDOW", 15*time.Minute))) {
+		ctx.JSON(http.StatusForbidden, gin.H{"message": "This comment can no longer be edited"})
+		return
+	}
+
+	// ========== Save the edit ==========
+	edited, err := models.EditComment(comment.ID, requestBody.Content, now)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	// ========== Tell anyone newly mentioned in the comment ==========
+	recordMentions(edited.UserID, models.OnComment(edited.ID), edited.PostID, edited.Content)
+
+	// ========== Send the response (w/ token) ==========
+	ctx.JSON(http.StatusOK, gin.H{
+		"message":   "Comment updated",
+		"content":   edited.Content,
+		"edited_at": edited.EditedAt,
+		"token":     token,
+	})
+}
+
+type JSONCommentRevision struct {
+	Content    string    `json:"content"`
+	ReplacedAt time.Time `json:"replaced_at"`
+}
+
+// ===== GET /comments/:id/revisions =====
+// Shows every earlier version of a comment. Moderators can see the history of any comment,
+// everyone else only of their own.
+func GetCommentRevisions(ctx *gin.Context) {
+	comment, userID, token, ok := parseCommentRequest(ctx)
+	if !ok {
+		return
+	}
+
+	// ========== Check the user is allowed to see the history ==========
+	if comment.UserID != userID {
+		viewer, err := models.FindUser(strconv.Itoa(int(userID)))
+		if err != nil {
+			SendInternalError(ctx, err)
+			return
+		}
+		if !viewer.IsModerator() {
+			ctx.JSON(http.StatusForbidden, gin.H{"message": "You are not authorised to see this comment's history"})
+			return
+		}
+	}
+
+	// ========== Fetch the earlier versions ==========
+	revisions, err := models.FetchCommentRevisions(comment.ID)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	jsonRevisions := make([]JSONCommentRevision, 0, len(*revisions))
+	for _, revision := range *revisions {
+		jsonRevisions = append(jsonRevisions, JSONCommentRevision{
+			Content:    revision.Content,
+			ReplacedAt: revision.CreatedAt,
+		})
+	}
+
+	// ========== Send the response (w/ token) ==========
+	ctx.JSON(http.StatusOK, gin.H{
+		"content":   comment.Content,
+		"edited_at": comment.EditedAt,
+		"revisions": jsonRevisions,
+		"token":     token,
+	})
+}
+
+// ======================== Helper functions for comments ==============================
+
+// parseCommentRequest gets the comment from the URL (checking it exists) and the current user
+func parseCommentRequest(ctx *gin.Context) (*models.Comment, uint, string, bool) {
+	commentID, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid comment ID"})
+		return nil, 0, "", false
+	}
+
+	comment, err := models.FetchCommentByID(uint(commentID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"message": "Comment not found"})
+			return nil, 0, "", false
+		}
+		SendInternalError(ctx, err)
+		return nil, 0, "", false
+	}
+
+	val, _ := ctx.Get("userID")
+	userID := val.(string)
+	userIDUint, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return nil, 0, "", false
+	}
+
+	token, _ := auth.GenerateToken(userID)
+	return comment, uint(userIDUint), token, true
+}
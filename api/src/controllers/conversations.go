@@ -0,0 +1,307 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/makersacademy/go-react-acebook-template/api/src/env"
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+	"gorm.io/gorm"
+)
+
+type JSONConversationMember struct {
+	UserID   uint   `json:"userID"`
+	Username string `json:"username"` // "" if they've deleted their account
+}
+
+type JSONMessage struct {
+	ID             uint      `json:"_id"`
+	ConversationID uint      `json:"conversation_id"`
+	SenderID       uint      `json:"senderID"`
+	Sender         string    `json:"sender"` // their username, or "" if they've deleted their account
+	Content        string    `json:"content"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type JSONConversation struct {
+	ID          uint                     `json:"_id"`
+	Title       string                   `json:"title"`  // only groups have a title
+	Direct      bool                     `json:"direct"` // a conversation between just two people
+	Members     []JSONConversationMember `json:"members"`
+	LastMessage *JSONMessage             `json:"lastMessage"` // null if nobody has said anything yet
+	Unread      int64                    `json:"unread"`
+	CreatedAt   time.Time                `json:"created_at"`
+	UpdatedAt   time.Time                `json:"updated_at"` // when the last message was sent
+}
+
+// ===== GET /conversations =====
+// Returns a page of the current user's conversations, most recently active first
+func GetConversations(ctx *gin.Context) {
+	userID, token, ok := parseNotificationUser(ctx)
+	if !ok {
+		return
+	}
+
+	page, ok := parsePage(ctx)
+	if !ok {
+		return
+	}
+	conversations, pageInfo, err := models.FetchConversationsPage(userID, page)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	ids := make([]uint, 0, len(*conversations))
+	for _, conversation := range *conversations {
+		ids = append(ids, conversation.ID)
+	}
+	lastMessages, err := models.FetchLastMessages(ids)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+	unread, err := models.CountUnreadMessages(userID, ids)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	jsonConversations := make([]JSONConversation, 0, len(*conversations))
+	for index := range *conversations {
+		conversation := &(*conversations)[index]
+		jsonConversation := toJSONConversation(conversation)
+		if message, exists := lastMessages[conversation.ID]; exists {
+			jsonMessage := toJSONMessage(&message)
+			jsonConversation.LastMessage = &jsonMessage
+		}
+		jsonConversation.Unread = unread[conversation.ID]
+		jsonConversations = append(jsonConversations, jsonConversation)
+	}
+
+	ctx.JSON(http.StatusOK, withPageInfo(gin.H{"conversations": jsonConversations, "token": token}, pageInfo))
+}
+
+type createConversationRequestBody struct {
+	UserIDs []uint `json:"user_ids"` // everyone else in the conversation
+	Title   string `json:"title"`    // ignored for conversations between two people
+	Content string `json:"content"`  // optional first message
+}
+
+// ===== POST /conversations =====
+// Starts a conversation with one or more other users, optionally with a first message.
+// Starting one with someone you already have a conversation with returns that one instead.
+func CreateConversation(ctx *gin.Context) {
+	userID, token, ok := parseNotificationUser(ctx)
+	if !ok {
+		return
+	}
+
+	var requestBody createConversationRequestBody
+	if err := ctx.BindJSON(&requestBody); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request body"})
+		return
+	}
+
+	// ========== Work out who else is in it ==========
+	memberIDs := []uint{}
+	seen := map[uint]bool{userID: true}
+	for _, memberID := range requestBody.UserIDs {
+		if !seen[memberID] {
+			seen[memberID] = true
+			memberIDs = append(memberIDs, memberID)
+		}
+	}
+	if len(memberIDs) == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "A conversation needs someone else in it"})
+		return
+	}
+	if maxMembers := env.GetInt("CONVERSATION_MAX_MEMBERS", 8); len(memberIDs)+1 > maxMembers {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "A conversation can have at most " + strconv.Itoa(maxMembers) + " people in it"})
+		return
+	}
+	title := strings.TrimSpace(requestBody.Title)
+	if len(title) > 100 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Title must be 100 characters or fewer"})
+		return
+	}
+
+	conversation, created, err := models.StartConversation(userID, memberIDs, title)
+	if err != nil {
+		sendConversationError(ctx, err)
+		return
+	}
+
+	// ========== Send the first message, if there is one ==========
+	var jsonMessage *JSONMessage
+	if strings.TrimSpace(requestBody.Content) != "" {
+		if conversation, err = models.FetchConversationForMember(conversation.ID, userID); err != nil {
+			SendInternalError(ctx, err)
+			return
+		}
+		message, err := models.SendMessage(conversation, userID, requestBody.Content)
+		if err != nil {
+			sendConversationError(ctx, err)
+			return
+		}
+		message.Sender = conversation.Member(userID).User
+		converted := toJSONMessage(message)
+		jsonMessage = &converted
+	}
+
+	status, response := http.StatusOK, "Conversation already exists"
+	if created {
+		status, response = http.StatusCreated, "Conversation created"
+	}
+	ctx.JSON(status, gin.H{"message": response, "conversation_id": conversation.ID, "sent": jsonMessage, "token": token})
+}
+
+// ===== GET /conversations/:id/messages =====
+// Returns a page of a conversation's messages, newest first
+func GetConversationMessages(ctx *gin.Context) {
+	conversation, userID, token, ok := parseConversationRequest(ctx)
+	if !ok {
+		return
+	}
+
+	page, ok := parsePage(ctx)
+	if !ok {
+		return
+	}
+	messages, pageInfo, err := models.FetchMessagesPage(conversation.ID, page)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	jsonMessages := make([]JSONMessage, 0, len(*messages))
+	for index := range *messages {
+		jsonMessages = append(jsonMessages, toJSONMessage(&(*messages)[index]))
+	}
+
+	ctx.JSON(http.StatusOK, withPageInfo(gin.H{
+		"conversation":      toJSONConversation(conversation),
+		"messages":          jsonMessages,
+		"lastReadMessageID": conversation.Member(userID).LastReadMessageID, // the newest message the user has read
+		"token":             token,
+	}, pageInfo))
+}
+
+type sendMessageRequestBody struct {
+	Content string `json:"content"`
+}
+
+// ===== POST /conversations/:id/messages =====
+// Sends a message to everyone in the conversation
+func SendMessage(ctx *gin.Context) {
+	conversation, userID, token, ok := parseConversationRequest(ctx)
+	if !ok {
+		return
+	}
+
+	var requestBody sendMessageRequestBody
+	if err := ctx.BindJSON(&requestBody); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request body"})
+		return
+	}
+	if strings.TrimSpace(requestBody.Content) == "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Message content empty"})
+		return
+	}
+
+	message, err := models.SendMessage(conversation, userID, requestBody.Content)
+	if err != nil {
+		sendConversationError(ctx, err)
+		return
+	}
+	message.Sender = conversation.Member(userID).User
+
+	ctx.JSON(http.StatusCreated, gin.H{"message": "Message sent", "sent": toJSONMessage(message), "token": token})
+}
+
+// ===== POST /conversations/:id/read =====
+// Marks every message in the conversation as read by the current user
+func MarkConversationRead(ctx *gin.Context) {
+	conversation, userID, token, ok := parseConversationRequest(ctx)
+	if !ok {
+		return
+	}
+
+	if err := models.MarkConversationRead(conversation.ID, userID, time.Now()); err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Conversation marked as read", "token": token})
+}
+
+// ======================== Helper functions for conversations ==============================
+
+// parseConversationRequest gets the current user and the conversation in the URL. Conversations
+// the user isn't in are reported as not found, so nobody can find out which conversations exist.
+func parseConversationRequest(ctx *gin.Context) (*models.Conversation, uint, string, bool) {
+	userID, token, ok := parseNotificationUser(ctx)
+	if !ok {
+		return nil, 0, "", false
+	}
+
+	conversationID, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid conversation ID"})
+		return nil, 0, "", false
+	}
+
+	conversation, err := models.FetchConversationForMember(uint(conversationID), userID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"message": "Conversation not found"})
+			return nil, 0, "", false
+		}
+		SendInternalError(ctx, err)
+		return nil, 0, "", false
+	}
+	return conversation, userID, token, true
+}
+
+func sendConversationError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, models.ErrMemberNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "User not found"})
+	case errors.Is(err, models.ErrBlocked):
+		ctx.JSON(http.StatusForbidden, gin.H{"message": "You can't message someone you've blocked or who has blocked you"})
+	case errors.Is(err, models.ErrNotAMember):
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Conversation not found"})
+	default:
+		SendInternalError(ctx, err)
+	}
+}
+
+func toJSONConversation(conversation *models.Conversation) JSONConversation {
+	members := make([]JSONConversationMember, 0, len(conversation.Members))
+	for _, member := range conversation.Members {
+		members = append(members, JSONConversationMember{UserID: member.UserID, Username: member.User.Username})
+	}
+	return JSONConversation{
+		ID:        conversation.ID,
+		Title:     conversation.Title,
+		Direct:    conversation.DirectKey != nil,
+		Members:   members,
+		CreatedAt: conversation.CreatedAt,
+		UpdatedAt: conversation.UpdatedAt,
+	}
+}
+
+func toJSONMessage(message *models.Message) JSONMessage {
+	return JSONMessage{
+		ID:             message.ID,
+		ConversationID: message.ConversationID,
+		SenderID:       message.SenderID,
+		Sender:         message.Sender.Username,
+		Content:        message.Content,
+		CreatedAt:      message.CreatedAt,
+	}
+}
@@ -0,0 +1,388 @@
+package controllers
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/makersacademy/go-react-acebook-template/api/src/achievements"
+	"github.com/makersacademy/go-react-acebook-template/api/src/answercheck"
+	"github.com/makersacademy/go-react-acebook-template/api/src/auth"
+	"github.com/makersacademy/go-react-acebook-template/api/src/env"
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+	"gorm.io/gorm"
+)
+
+// Daily question numbers count up from this date, like "Quizbook Daily #42"
+var dailyLaunchDate = time.Date(2025, time.April, 1, 0, 0, 0, 0, time.UTC)
+
+const maxDailyHints = 2
+
+type JSONDaily struct {
+	Date       string   `json:"date"`
+	Number     int      `json:"number"`
+	PostID     uint     `json:"post_id"`
+	Question   string   `json:"question"`
+	Username   string   `json:"username"`
+	Guesses    int      `json:"guesses"`
+	MaxGuesses int      `json:"maxGuesses"`
+	Hints      []string `json:"hints"`
+	Finished   bool     `json:"finished"`
+	Correct    bool     `json:"correct"`
+	Answer     string   `json:"answer"`
+	Share      string   `json:"share"`
+	Streak     int      `json:"streak"`
+}
+
+type JSONDailyHistory struct {
+	Date     string `json:"date"`
+	Number   int    `json:"number"`
+	PostID   uint   `json:"post_id"`
+	Question string `json:"question"`
+	Answer   string `json:"answer"`
+	Played   bool   `json:"played"`
+	Correct  bool   `json:"correct"`
+	Share    string `json:"share"`
+}
+
+// Returns today's question along with the current user's progress on it
+func GetDailyQuestion(ctx *gin.Context) {
+	dailyQuestion, dailyAttempt, userID, ok := startDailyQuestion(ctx)
+	if !ok {
+		return
+	}
+
+	sendDailyResponse(ctx, http.StatusOK, dailyQuestion, dailyAttempt, userID)
+}
+
+// Uses up one hint on today's question
+func UseDailyHint(ctx *gin.Context) {
+	dailyQuestion, dailyAttempt, userID, ok := startDailyQuestion(ctx)
+	if !ok {
+		return
+	}
+
+	// ========== Save the extra hint, if there are any left to give ==========
+	if err := models.RecordDailyHint(dailyAttempt, maxDailyHints); err != nil {
+		switch {
+		case errors.Is(err, models.ErrDailyFinished):
+			ctx.JSON(http.StatusConflict, gin.H{"message": "You have already played today's question"})
+		case errors.Is(err, models.ErrNoDailyHints):
+			ctx.JSON(http.StatusConflict, gin.H{"message": "There are no more hints for today's question"})
+		default:
+			SendInternalError(ctx, err)
+		}
+		return
+	}
+
+	sendDailyResponse(ctx, http.StatusOK, dailyQuestion, dailyAttempt, userID)
+}
+
+type dailyGuessRequestBody struct {
+	Guess string `json:"guess"`
+}
+
+// Submits a guess at today's question. Each user gets one play per day, made up of a few guesses.
+func GuessDailyQuestion(ctx *gin.Context) {
+	// ========== Get the request body ==========
+	var requestBody dailyGuessRequestBody
+	if err := ctx.BindJSON(&requestBody); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": err})
+		return
+	}
+	if len(answercheck.Normalise(requestBody.Guess)) == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Guess cannot be blank"})
+		return
+	}
+
+	dailyQuestion, dailyAttempt, userID, ok := startDailyQuestion(ctx)
+	if !ok {
+		return
+	}
+
+	// ========== One play per day ==========
+	if dailyAttempt.IsFinished() {
+		ctx.JSON(http.StatusConflict, gin.H{"message": "You have already played today's question"})
+		return
+	}
+
+	post := &dailyQuestion.Post
+	if post.UserID == userID {
+		ctx.JSON(http.StatusForbidden, gin.H{"message": "You can't answer your own question"})
+		return
+	}
+
+	// ========== Was the answer already on show to them? ==========
+	// Curated questions can have a public answer. The guess still counts for the play, but earns nothing
+	_, answerSeen, err := answerForViewer(post, userID)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	// ========== Check the guess ==========
+	correct, err := post.IsGuessCorrect(requestBody.Guess)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	// Saved under a lock, so two guesses sent at once can't both get in under the limit
+	if err := models.RecordDailyGuess(dailyAttempt, correct, maxDailyGuesses(), time.Now()); err != nil {
+		if errors.Is(err, models.ErrDailyFinished) {
+			ctx.JSON(http.StatusConflict, gin.H{"message": "You have already played today's question"})
+			return
+		}
+		SendInternalError(ctx, err)
+		return
+	}
+
+	// ========== Record it as a normal attempt at the post too ==========
+	attempt := models.Attempt{PostID: post.ID, UserID: userID, Guess: requestBody.Guess, Correct: correct, AnswerSeen: answerSeen}
+	if _, err := attempt.Save(); err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	// ========== Reward a correct answer (plus the streak bonus) ==========
+	if correct && !answerSeen {
+		awardDailyStreakXP(userID, dailyAttempt.ID)
+		rewardCorrectAnswer(userID, post.ID)
+	}
+	achievements.Evaluate(userID, achievements.EventAttemptMade)
+	achievements.Evaluate(post.UserID, achievements.EventQuestionAttempted)
+
+	sendDailyResponse(ctx, http.StatusCreated, dailyQuestion, dailyAttempt, userID)
+}
+
+// Returns the past daily questions along with how the current user did on each
+func GetDailyHistory(ctx *gin.Context) {
+	// ========== Get the user ID from the context ==========
+	val, _ := ctx.Get("userID")
+	userIDString := val.(string)
+	userID, err := strconv.ParseUint(userIDString, 10, 32)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+	token, _ := auth.GenerateToken(userIDString)
+
+	page, ok := parsePage(ctx)
+	if !ok {
+		return
+	}
+
+	// ========== Fetch a page of past questions and the user's plays of them ==========
+	dailyQuestions, info, err := models.FetchDailyQuestionsBefore(models.DailyDate(time.Now()), uint(userID), page)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	dates := make([]string, 0, len(*dailyQuestions))
+	posts := make([]models.Post, 0, len(*dailyQuestions))
+	for _, dailyQuestion := range *dailyQuestions {
+		dates = append(dates, dailyQuestion.Date)
+		posts = append(posts, dailyQuestion.Post)
+	}
+
+	dailyAttempts, err := models.FetchDailyAttemptsByUserID(uint(userID), dates)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	// Past questions still follow the post's reveal policy
+	answersVisible, err := models.AnswersVisibleTo(posts, uint(userID))
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	// ========== Convert to JSON Structs ==========
+	jsonHistory := make([]JSONDailyHistory, 0)
+	for _, dailyQuestion := range *dailyQuestions {
+		entry := JSONDailyHistory{
+			Date:     dailyQuestion.Date,
+			Number:   dailyNumber(dailyQuestion.Date),
+			PostID:   dailyQuestion.PostID,
+			Question: dailyQuestion.Post.Question,
+		}
+		if answersVisible[dailyQuestion.PostID] {
+			entry.Answer = dailyQuestion.Post.Answer
+		}
+
+		if dailyAttempt, played := dailyAttempts[dailyQuestion.Date]; played {
+			entry.Played = true
+			entry.Correct = dailyAttempt.Correct
+			if dailyAttempt.IsFinished() {
+				entry.Share = buildDailyShareString(dailyQuestion.Date, &dailyAttempt, 0)
+			}
+		}
+
+		jsonHistory = append(jsonHistory, entry)
+	}
+
+	// ========== Send the response (w/ token) ==========
+	ctx.JSON(http.StatusOK, withPageInfo(gin.H{"history": jsonHistory, "token": token}, info))
+}
+
+// ======================== Helper functions for the daily question ==============================
+
+// startDailyQuestion finds (or picks) today's question, with its post, and the current user's play
+// of it, starting the clock on their first look. It sends the error response itself if anything fails.
+func startDailyQuestion(ctx *gin.Context) (*models.DailyQuestion, *models.DailyAttempt, uint, bool) {
+	// ========== Get the user ID from the context ==========
+	val, _ := ctx.Get("userID")
+	userIDString := val.(string)
+	userID, err := strconv.ParseUint(userIDString, 10, 32)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return nil, nil, 0, false
+	}
+
+	// ========== Find today's question ==========
+	today := models.DailyDate(time.Now())
+	dailyQuestion, err := models.FetchOrChooseDailyQuestion(today, env.GetInt("DAILY_MIN_LIKES", 1))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"message": "There is no daily question yet"})
+			return nil, nil, 0, false
+		}
+		SendInternalError(ctx, err)
+		return nil, nil, 0, false
+	}
+
+	// ========== Check the user can see it (they might have blocked its author) ==========
+	post, err := models.FetchPostForViewer(dailyQuestion.PostID, uint(userID))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"message": "Today's question isn't available to you"})
+			return nil, nil, 0, false
+		}
+		SendInternalError(ctx, err)
+		return nil, nil, 0, false
+	}
+	dailyQuestion.Post = *post
+
+	// ========== Find the user's play for today ==========
+	dailyAttempt, err := models.FetchOrStartDailyAttempt(uint(userID), today, dailyQuestion.PostID)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return nil, nil, 0, false
+	}
+
+	return dailyQuestion, dailyAttempt, uint(userID), true
+}
+
+func sendDailyResponse(ctx *gin.Context, status int, dailyQuestion *models.DailyQuestion, dailyAttempt *models.DailyAttempt, userID uint) {
+	post := &dailyQuestion.Post
+
+	username := "Unknown" // Default if author not found
+	author, err := models.FindUser(strconv.Itoa(int(post.UserID)))
+	if err == nil {
+		username = author.Username
+	}
+
+	streak, err := models.CalculateDailyStreak(userID, time.Now())
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	jsonDaily := JSONDaily{
+		Date:       dailyQuestion.Date,
+		Number:     dailyNumber(dailyQuestion.Date),
+		PostID:     post.ID,
+		Question:   post.Question,
+		Username:   username,
+		Guesses:    dailyAttempt.Guesses,
+		MaxGuesses: maxDailyGuesses(),
+		Hints:      dailyHints(post.Answer, dailyAttempt.HintsUsed),
+		Finished:   dailyAttempt.IsFinished(),
+		Correct:    dailyAttempt.Correct,
+		Streak:     streak,
+	}
+
+	// Only give away the answer and the share string once they've finished playing, and even then
+	// the answer follows the post's reveal policy, the same as in the history
+	if dailyAttempt.IsFinished() {
+		answer, _, err := answerForViewer(post, userID)
+		if err != nil {
+			SendInternalError(ctx, err)
+			return
+		}
+		jsonDaily.Answer = answer
+		jsonDaily.Share = buildDailyShareString(dailyQuestion.Date, dailyAttempt, streak)
+	}
+
+	token, _ := auth.GenerateToken(strconv.Itoa(int(userID)))
+	ctx.JSON(status, gin.H{"daily": jsonDaily, "token": token})
+}
+
+func maxDailyGuesses() int {
+	return env.GetInt("DAILY_MAX_GUESSES", 3)
+}
+
+func dailyNumber(date string) int {
+	day, err := time.Parse(models.DailyDateFormat, date)
+	if err != nil {
+		return 0
+	}
+	return int(day.Sub(dailyLaunchDate).Hours()/24) + 1
+}
+
+// dailyHints gives progressively bigger clues about the answer without giving it away
+func dailyHints(answer string, hintsUsed int) []string {
+	hints := make([]string, 0)
+	words := strings.Fields(answercheck.Normalise(answer))
+
+	if hintsUsed >= 1 {
+		hints = append(hints, fmt.Sprintf("The answer has %d word(s)", len(words)))
+	}
+	if hintsUsed >= 2 {
+		initials := make([]string, 0, len(words))
+		for _, word := range words {
+			initials = append(initials, strings.ToUpper(string([]rune(word)[0])))
+		}
+		hints = append(hints, "The words start with: "+strings.Join(initials, " "))
+	}
+	return hints
+}
+
+// buildDailyShareString makes a Wordle-style summary that can be shared without spoiling the answer, e.g.
+//
+//	Quizbook Daily #42 2/3
+//	🟥🟩
+//	💡 1  ⏱ 0:42  🔥 5
+func buildDailyShareString(date string, dailyAttempt *models.DailyAttempt, streak int) string {
+	score := "X"
+	if dailyAttempt.Correct {
+		score = strconv.Itoa(dailyAttempt.Guesses)
+	}
+
+	squares := ""
+	for _, result := range dailyAttempt.Results {
+		if result == 'Y' {
+			squares += "🟩"
+		} else {
+			squares += "🟥"
+		}
+	}
+
+	elapsed := time.Duration(0)
+	if dailyAttempt.FinishedAt != nil {
+		elapsed = dailyAttempt.FinishedAt.Sub(dailyAttempt.StartedAt).Round(time.Second)
+	}
+	timeTaken := fmt.Sprintf("%d:%02d", int(elapsed.Minutes()), int(elapsed.Seconds())%60)
+
+	share := fmt.Sprintf("Quizbook Daily #%d %s/%d\n%s\n💡 %d  ⏱ %s", dailyNumber(date), score, maxDailyGuesses(), squares, dailyAttempt.HintsUsed, timeTaken)
+	if streak > 0 {
+		share += fmt.Sprintf("  🔥 %d", streak)
+	}
+	return share
+}
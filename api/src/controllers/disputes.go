@@ -0,0 +1,392 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/makersacademy/go-react-acebook-template/api/src/auth"
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+)
+
+type JSONDispute struct {
+	ID              uint   `json:"_id"`
+	PostID          uint   `json:"post_id"`
+	UserID          uint   `json:"userID"`
+	Username        string `json:"username"`
+	ProposedAnswer  string `json:"proposed_answer"`
+	Justification   string `json:"justification"`
+	Status          string `json:"status"`
+	Resolution      string `json:"resolution"`
+	RejectionReason string `json:"rejection_reason"`
+	ResolvedByVote  bool   `json:"resolved_by_vote"`
+	VotesFor        int64  `json:"votes_for"`
+	VotesAgainst    int64  `json:"votes_against"`
+	CreatedAt       string `json:"created_at"`
+}
+
+type createDisputeRequestBody struct {
+	PostID         uint   `json:"post_id"`
+	ProposedAnswer string `json:"proposed_answer"`
+	Justification  string `json:"justification"`
+}
+
+func CreateDispute(ctx *gin.Context) {
+	// ========== Get the request body ==========
+	var requestBody createDisputeRequestBody
+	err := ctx.BindJSON(&requestBody)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": err})
+		return
+	}
+
+	if len(strings.TrimSpace(requestBody.ProposedAnswer)) == 0 || len(strings.TrimSpace(requestBody.Justification)) == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Both a proposed answer and a justification are required"})
+		return
+	}
+
+	// ========== Get the user ID from the context ==========
+	val, _ := ctx.Get("userID")
+	userID := val.(string)
+	userIDUint, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	// ========== Fetch the post being disputed ==========
+	post, err := models.FetchPostForViewer(requestBody.PostID, uint(userIDUint))
+	if err != nil {
+		if err.Error() == "record not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"message": "Post not found"})
+			return
+		}
+		SendInternalError(ctx, err)
+		return
+	}
+
+	// ========== You can only dispute an answer you're allowed to see ==========
+	visible, err := post.IsAnswerVisibleTo(uint(userIDUint))
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+	if !visible {
+		ctx.JSON(http.StatusForbidden, gin.H{"message": "You can't dispute an answer that hasn't been revealed to you"})
+		return
+	}
+
+	// ========== Create and save the dispute ==========
+	newDispute := models.Dispute{
+		PostID:         post.ID,
+		UserID:         uint(userIDUint),
+		ProposedAnswer: requestBody.ProposedAnswer,
+		Justification:  requestBody.Justification,
+		Status:         models.DisputeOpen,
+	}
+
+	_, err = newDispute.Save()
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	// ========== Send the response (w/ token) ==========
+	token, _ := auth.GenerateToken(userID)
+	ctx.JSON(http.StatusCreated, gin.H{"message": "Dispute created", "token": token})
+}
+
+func GetDisputesByPostID(ctx *gin.Context) {
+	// ========== Get the post ID from the URL params ==========
+	postID := ctx.Param("post_id")
+	postIDUint, err := strconv.ParseUint(postID, 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid post ID"})
+		return
+	}
+
+	// ========== Get the user ID from the context ==========
+	val, _ := ctx.Get("userID")
+	userID := val.(string)
+	userIDUint, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+	token, _ := auth.GenerateToken(userID)
+
+	// ========== Fetch the post ==========
+	post, err := models.FetchPostForViewer(uint(postIDUint), uint(userIDUint))
+	if err != nil {
+		if err.Error() == "record not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"message": "Post not found"})
+			return
+		}
+		SendInternalError(ctx, err)
+		return
+	}
+
+	// ========== Disputes give away the answer, so respect the reveal policy ==========
+	visible, err := post.IsAnswerVisibleTo(uint(userIDUint))
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+	if !visible {
+		ctx.JSON(http.StatusForbidden, gin.H{"message": "The answer hasn't been revealed to you yet"})
+		return
+	}
+
+	// ========== Fetch the disputes for the post ==========
+	disputes, err := models.FetchDisputesByPostID(post.ID)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	// ========== Convert disputes to JSON Structs ==========
+	jsonDisputes := make([]JSONDispute, 0)
+	for _, dispute := range *disputes {
+		// Find the user who opened the dispute to get their username
+		username := "Unknown" // Default if user not found
+		user, err := models.FindUser(strconv.Itoa(int(dispute.UserID)))
+		if err == nil {
+			username = user.Username
+		}
+
+		votesFor, votesAgainst, err := models.CountDisputeVotes(dispute.ID)
+		if err != nil {
+			SendInternalError(ctx, err)
+			return
+		}
+
+		jsonDisputes = append(jsonDisputes, JSONDispute{
+			ID:              dispute.ID,
+			PostID:          dispute.PostID,
+			UserID:          dispute.UserID,
+			Username:        username,
+			ProposedAnswer:  dispute.ProposedAnswer,
+			Justification:   dispute.Justification,
+			Status:          dispute.Status,
+			Resolution:      dispute.Resolution,
+			RejectionReason: dispute.RejectionReason,
+			ResolvedByVote:  dispute.ResolvedByVote,
+			VotesFor:        votesFor,
+			VotesAgainst:    votesAgainst,
+			CreatedAt:       dispute.CreatedAt.Format(time.RFC3339),
+		})
+	}
+
+	// ========== Send the response (w/ token) ==========
+	ctx.JSON(http.StatusOK, gin.H{"disputes": jsonDisputes, "token": token})
+}
+
+type acceptDisputeRequestBody struct {
+	Resolution string `json:"resolution"` // "replace" or "alternate"
+}
+
+func AcceptDispute(ctx *gin.Context) {
+	// ========== Get the request body ==========
+	var requestBody acceptDisputeRequestBody
+	if err := ctx.BindJSON(&requestBody); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": err})
+		return
+	}
+
+	if requestBody.Resolution != models.ResolutionReplace && requestBody.Resolution != models.ResolutionAlternate {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Resolution must be 'replace' or 'alternate'"})
+		return
+	}
+
+	// ========== Check the current user is the post's author ==========
+	dispute, userID, ok := fetchDisputeForAuthor(ctx)
+	if !ok {
+		return
+	}
+
+	// ========== Accept the dispute (this also re-scores past attempts) ==========
+	err := models.AcceptDispute(dispute.ID, requestBody.Resolution, false)
+	if err != nil {
+		if errors.Is(err, models.ErrDisputeNotOpen) {
+			ctx.JSON(http.StatusConflict, gin.H{"message": "Dispute has already been resolved"})
+			return
+		}
+		SendInternalError(ctx, err)
+		return
+	}
+
+	// ========== Send the response (w/ token) ==========
+	token, _ := auth.GenerateToken(userID)
+	ctx.JSON(http.StatusOK, gin.H{"message": "Dispute accepted", "token": token})
+}
+
+type rejectDisputeRequestBody struct {
+	Reason string `json:"reason"`
+}
+
+func RejectDispute(ctx *gin.Context) {
+	// ========== Get the request body ==========
+	var requestBody rejectDisputeRequestBody
+	if err := ctx.BindJSON(&requestBody); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": err})
+		return
+	}
+
+	if len(strings.TrimSpace(requestBody.Reason)) == 0 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "A reason is required to reject a dispute"})
+		return
+	}
+
+	// ========== Check the current user is the post's author ==========
+	dispute, userID, ok := fetchDisputeForAuthor(ctx)
+	if !ok {
+		return
+	}
+
+	// ========== Reject the dispute ==========
+	err := models.RejectDispute(dispute.ID, requestBody.Reason, false)
+	if err != nil {
+		if errors.Is(err, models.ErrDisputeNotOpen) {
+			ctx.JSON(http.StatusConflict, gin.H{"message": "Dispute has already been resolved"})
+			return
+		}
+		SendInternalError(ctx, err)
+		return
+	}
+
+	// ========== Send the response (w/ token) ==========
+	token, _ := auth.GenerateToken(userID)
+	ctx.JSON(http.StatusOK, gin.H{"message": "Dispute rejected", "token": token})
+}
+
+type voteOnDisputeRequestBody struct {
+	Support bool `json:"support"`
+}
+
+func VoteOnDispute(ctx *gin.Context) {
+	// ========== Get the dispute ID from the URL ==========
+	disputeID, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid dispute ID"})
+		return
+	}
+
+	// ========== Get the request body ==========
+	var requestBody voteOnDisputeRequestBody
+	if err := ctx.BindJSON(&requestBody); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": err})
+		return
+	}
+
+	// ========== Get the user ID from the context ==========
+	val, _ := ctx.Get("userID")
+	userID := val.(string)
+	userIDUint, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	// ========== Fetch the dispute and its post ==========
+	dispute, err := models.FetchDisputeByID(uint(disputeID))
+	if err != nil {
+		if err.Error() == "record not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"message": "Dispute not found"})
+			return
+		}
+		SendInternalError(ctx, err)
+		return
+	}
+
+	if dispute.Status != models.DisputeOpen {
+		ctx.JSON(http.StatusConflict, gin.H{"message": "Dispute has already been resolved"})
+		return
+	}
+
+	// Disputes on posts the user can't see don't exist as far as they're concerned
+	post, err := models.FetchPostForViewer(dispute.PostID, uint(userIDUint))
+	if err != nil {
+		if err.Error() == "record not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"message": "Dispute not found"})
+			return
+		}
+		SendInternalError(ctx, err)
+		return
+	}
+
+	// ========== The author decides directly, so they don't get a vote ==========
+	if post.UserID == uint(userIDUint) {
+		ctx.JSON(http.StatusForbidden, gin.H{"message": "Authors accept or reject disputes instead of voting"})
+		return
+	}
+
+	// ========== Save the vote ==========
+	err = models.SaveDisputeVote(dispute, post, uint(userIDUint), requestBody.Support)
+	if err != nil {
+		switch {
+		case errors.Is(err, models.ErrOwnDisputeVote):
+			ctx.JSON(http.StatusForbidden, gin.H{"message": "You can't vote on your own dispute"})
+		case errors.Is(err, models.ErrAnswerNotVisible):
+			ctx.JSON(http.StatusForbidden, gin.H{"message": "You can't vote on a dispute until the answer has been revealed to you"})
+		default:
+			SendInternalError(ctx, err)
+		}
+		return
+	}
+
+	// ========== Send the response (w/ token) ==========
+	token, _ := auth.GenerateToken(userID)
+	ctx.JSON(http.StatusCreated, gin.H{"message": "Vote saved", "token": token})
+}
+
+// fetchDisputeForAuthor loads the dispute from the URL and checks the current user wrote the
+// post it's about. If anything is wrong it sends the error response and returns ok = false.
+func fetchDisputeForAuthor(ctx *gin.Context) (*models.Dispute, string, bool) {
+	// ========== Get the dispute ID from the URL ==========
+	disputeID, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid dispute ID"})
+		return nil, "", false
+	}
+
+	// ========== Get the user ID from the context ==========
+	val, _ := ctx.Get("userID")
+	userID := val.(string)
+	userIDUint, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return nil, "", false
+	}
+
+	// ========== Fetch the dispute and its post ==========
+	dispute, err := models.FetchDisputeByID(uint(disputeID))
+	if err != nil {
+		if err.Error() == "record not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"message": "Dispute not found"})
+			return nil, "", false
+		}
+		SendInternalError(ctx, err)
+		return nil, "", false
+	}
+
+	post, err := models.FetchPostForViewer(dispute.PostID, uint(userIDUint))
+	if err != nil {
+		if err.Error() == "record not found" {
+			ctx.JSON(http.StatusNotFound, gin.H{"message": "Dispute not found"})
+			return nil, "", false
+		}
+		SendInternalError(ctx, err)
+		return nil, "", false
+	}
+
+	// ========== Only the author can resolve disputes on their post ==========
+	if post.UserID != uint(userIDUint) {
+		ctx.JSON(http.StatusForbidden, gin.H{"message": "Only the author can resolve disputes on this post"})
+		return nil, "", false
+	}
+
+	return dispute, userID, true
+}
@@ -0,0 +1,111 @@
+package controllers
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/makersacademy/go-react-acebook-template/api/src/env"
+	"github.com/makersacademy/go-react-acebook-template/api/src/events"
+)
+
+// ===== GET /events/stream =====
+// Streams events for the current user (comments and likes on their questions, and their
+// notifications) as Server-Sent Events. The connection stays open until the client goes away.
+// Clients that reconnect send the ID of the last event they saw, and are sent what they missed.
+func StreamEvents(ctx *gin.Context) {
+	userID, _, ok := parseNotificationUser(ctx)
+	if !ok {
+		return
+	}
+	topic := events.UserTopic(userID)
+
+	// ========== Don't let one user hold open lots of streams ==========
+	if events.Default.SubscriberCountForUser(topic, userID) >= env.GetInt("EVENTS_MAX_STREAMS_PER_USER", 5) {
+		ctx.JSON(http.StatusTooManyRequests, gin.H{"message": "Too many open event streams"})
+		return
+	}
+
+	// ========== Work out where a reconnecting client got up to ==========
+	lastEventIDParam := ctx.GetHeader("Last-Event-ID")
+	if lastEventIDParam == "" {
+		lastEventIDParam = ctx.Query("last_event_id")
+	}
+	var lastEventID uint64
+	if lastEventIDParam != "" {
+		parsed, err := strconv.ParseUint(lastEventIDParam, 10, 64)
+		if err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid Last-Event-ID"})
+			return
+		}
+		lastEventID = parsed
+	}
+
+	subscription, missed, complete := events.Default.Subscribe(topic, userID, lastEventID)
+	defer events.Default.Unsubscribe(subscription)
+
+	// ========== Start the stream ==========
+	ctx.Header("Content-Type", "text/event-stream")
+	ctx.Header("Cache-Control", "no-cache")
+	ctx.Header("Connection", "keep-alive")
+	ctx.Header("X-Accel-Buffering", "no") // stop proxies holding events back
+	ctx.Status(http.StatusOK)
+
+	// Ask the browser to wait a few seconds before reconnecting if the connection drops
+	if _, err := fmt.Fprint(ctx.Writer, "retry: 3000\n\n"); err != nil {
+		return
+	}
+	if !complete {
+		if !writeEvent(ctx, events.Event{ID: lastEventID, Type: events.Resync}) {
+			return
+		}
+	}
+	for _, event := range missed {
+		if !writeEvent(ctx, event) {
+			return
+		}
+	}
+	ctx.Writer.Flush()
+
+	// ========== Send events as they happen, with a heartbeat so idle connections stay open ==========
+	heartbeat := time.NewTicker(env.GetDuration("EVENTS_HEARTBEAT", 25*time.Second))
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-ctx.Request.Context().Done():
+			// The client has gone away
+			return
+		case event, open := <-subscription.Events():
+			if !open {
+				// The client fell too far behind, so end the stream and let it reconnect
+				return
+			}
+			if !writeEvent(ctx, event) {
+				return
+			}
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(ctx.Writer, ": heartbeat\n\n"); err != nil {
+				return
+			}
+			ctx.Writer.Flush()
+		}
+	}
+}
+
+// writeEvent sends one event in the Server-Sent Events format. It returns false if the
+// connection has gone.
+func writeEvent(ctx *gin.Context, event events.Event) bool {
+	data, err := json.Marshal(event.Data)
+	if err != nil {
+		data = []byte("{}")
+	}
+	if _, err := fmt.Fprintf(ctx.Writer, "id: %d\nevent: %s\ndata: %s\n\n", event.ID, event.Type, data); err != nil {
+		return false
+	}
+	ctx.Writer.Flush()
+	return true
+}
@@ -0,0 +1,148 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/makersacademy/go-react-acebook-template/api/src/auth"
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+)
+
+type JSONFollowUser struct {
+	ID                uint   `json:"_id"`
+	Username          string `json:"username"`
+	ProfilePictureURL string `json:"profilePicture"`
+}
+
+func FollowUser(ctx *gin.Context) {
+	followerID, followeeID, token, ok := parseFollowRequest(ctx)
+	if !ok {
+		return
+	}
+
+	// ========== You can't follow yourself ==========
+	if followerID == followeeID {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "You can't follow yourself"})
+		return
+	}
+
+	// ========== Check the user exists ==========
+	if _, err := models.FindUser(strconv.Itoa(int(followeeID))); err != nil {
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "User not found"})
+		return
+	}
+
+	// ========== Blocking works both ways, and stops any following ==========
+	blocked, err := models.IsBlockedBetween(followerID, followeeID)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+	if blocked {
+		ctx.JSON(http.StatusForbidden, gin.H{"message": "You can't follow someone you've blocked or who has blocked you"})
+		return
+	}
+
+	// ========== Follow them (following twice is fine) ==========
+	followed, err := models.FollowUser(followerID, followeeID)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+	if followed {
+		notify(models.NotificationEvent{UserID: followeeID, ActorID: followerID, Kind: models.NotificationFollowed})
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Followed", "token": token})
+}
+
+func UnfollowUser(ctx *gin.Context) {
+	followerID, followeeID, token, ok := parseFollowRequest(ctx)
+	if !ok {
+		return
+	}
+
+	// ========== Unfollow them (unfollowing twice is fine) ==========
+	if err := models.UnfollowUser(followerID, followeeID); err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Unfollowed", "token": token})
+}
+
+func GetFollowersByUserID(ctx *gin.Context) {
+	sendFollowList(ctx, "followers", models.FetchFollowersPage, func(follow models.Follow) models.User { return follow.Follower })
+}
+
+func GetFollowingByUserID(ctx *gin.Context) {
+	sendFollowList(ctx, "following", models.FetchFollowingPage, func(follow models.Follow) models.User { return follow.Followee })
+}
+
+// ======================== Helper functions for follows ==============================
+
+// parseFollowRequest gets the current user (the follower) and the user in the URL (the followee)
+func parseFollowRequest(ctx *gin.Context) (uint, uint, string, bool) {
+	// ========== Get the user ID from the URL params ==========
+	followeeID, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid user ID"})
+		return 0, 0, "", false
+	}
+
+	// ========== Get the user ID from the context ==========
+	val, _ := ctx.Get("userID")
+	userID := val.(string)
+	followerID, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return 0, 0, "", false
+	}
+
+	token, _ := auth.GenerateToken(userID)
+	return uint(followerID), uint(followeeID), token, true
+}
+
+func sendFollowList(ctx *gin.Context, key string, fetch func(userID uint, page models.Page) (*[]models.Follow, models.PageInfo, error), userOf func(models.Follow) models.User) {
+	// ========== Get the user ID from the URL params ==========
+	userID, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid user ID"})
+		return
+	}
+
+	page, ok := parsePage(ctx)
+	if !ok {
+		return
+	}
+
+	// ========== Fetch the page ==========
+	follows, info, err := fetch(uint(userID), page)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	val, _ := ctx.Get("userID")
+	token, _ := auth.GenerateToken(val.(string))
+
+	// ========== Send the response (w/ token) ==========
+	users := make([]models.User, 0, len(*follows))
+	for _, follow := range *follows {
+		users = append(users, userOf(follow))
+	}
+	ctx.JSON(http.StatusOK, withPageInfo(gin.H{key: toJSONFollowUsers(&users), "token": token}, info))
+}
+
+func toJSONFollowUsers(users *[]models.User) []JSONFollowUser {
+	jsonUsers := make([]JSONFollowUser, 0)
+	for _, user := range *users {
+		jsonUsers = append(jsonUsers, JSONFollowUser{
+			ID:                user.ID,
+			Username:          user.Username,
+			ProfilePictureURL: user.ProfilePictureURL,
+		})
+	}
+	return jsonUsers
+}
@@ -0,0 +1,132 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/makersacademy/go-react-acebook-template/api/src/auth"
+	"github.com/makersacademy/go-react-acebook-template/api/src/jobs"
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+	"gorm.io/gorm"
+)
+
+type JSONLeagueStanding struct {
+	Rank     int    `json:"rank"`
+	UserID   uint   `json:"userID"`
+	Username string `json:"username"`
+	Points   int    `json:"points"`
+	Zone     string `json:"zone"` // "promotion", "relegation" or "" for the middle of the table
+}
+
+type JSONSeasonResult struct {
+	Season   string `json:"season"`
+	Division int    `json:"division"`
+	Points   int    `json:"points"`
+	Rank     int    `json:"rank"`
+	Outcome  string `json:"outcome"`
+}
+
+// Returns the current user's division table for this season
+func GetCurrentLeague(ctx *gin.Context) {
+	// ========== Get the user ID from the context ==========
+	val, _ := ctx.Get("userID")
+	userID := val.(string)
+	userIDUint, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+	token, _ := auth.GenerateToken(userID)
+
+	// ========== Find this season and the user's division ==========
+	season, err := models.EnsureSeason(models.Database, time.Now())
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	settings := jobs.LeagueSettings()
+	membership, err := models.EnsureLeagueMembership(uint(userIDUint), season, settings)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			// Last season hasn't been rolled over yet, so divisions aren't ready
+			ctx.JSON(http.StatusServiceUnavailable, gin.H{"message": "The new season is still being set up, try again shortly", "token": token})
+			return
+		}
+		SendInternalError(ctx, err)
+		return
+	}
+
+	// ========== Rank the division ==========
+	standings, err := models.FetchDivisionStandings(season, membership.Division)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	// ========== Convert to JSON Structs ==========
+	jsonStandings := make([]JSONLeagueStanding, 0)
+	for _, standing := range standings {
+		zone := ""
+		if membership.Division > 1 && standing.Rank <= settings.MoveCount {
+			zone = "promotion"
+		} else if standing.Rank > len(standings)-settings.MoveCount {
+			zone = "relegation"
+		}
+
+		jsonStandings = append(jsonStandings, JSONLeagueStanding{
+			Rank:     standing.Rank,
+			UserID:   standing.UserID,
+			Username: standing.Username,
+			Points:   standing.Points,
+			Zone:     zone,
+		})
+	}
+
+	// ========== Send the response (w/ token) ==========
+	ctx.JSON(http.StatusOK, gin.H{
+		"season":    season.Key,
+		"ends_at":   season.EndsAt.Format(time.RFC3339),
+		"division":  membership.Division,
+		"standings": jsonStandings,
+		"token":     token,
+	})
+}
+
+// Returns how a user did in each of their past seasons
+func GetSeasonHistoryByUserID(ctx *gin.Context) {
+	// ========== Get the user ID from the URL params ==========
+	userID, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid user ID"})
+		return
+	}
+
+	// ========== Fetch their finished seasons ==========
+	memberships, err := models.FetchClosedMembershipsByUserID(uint(userID))
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	val, _ := ctx.Get("userID")
+	token, _ := auth.GenerateToken(val.(string))
+
+	// ========== Convert to JSON Structs ==========
+	jsonSeasons := make([]JSONSeasonResult, 0)
+	for _, membership := range *memberships {
+		jsonSeasons = append(jsonSeasons, JSONSeasonResult{
+			Season:   membership.Season.Key,
+			Division: membership.Division,
+			Points:   membership.FinalPoints,
+			Rank:     membership.FinalRank,
+			Outcome:  membership.Outcome,
+		})
+	}
+
+	// ========== Send the response (w/ token) ==========
+	ctx.JSON(http.StatusOK, gin.H{"seasons": jsonSeasons, "token": token})
+}
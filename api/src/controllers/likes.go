@@ -1,12 +1,15 @@
 package controllers
 
 import (
+	"errors"
 	"net/http"
 	"strconv"
 
 	"github.com/gin-gonic/gin"
+	"github.com/makersacademy/go-react-acebook-template/api/src/achievements"
 	"github.com/makersacademy/go-react-acebook-template/api/src/auth"
 	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+	"gorm.io/gorm"
 )
 
 type JSONLike struct {
@@ -24,30 +27,49 @@ func GetLikesByPostID(ctx *gin.Context) {
 		return
 	}
 
-	// ========== Fetch the likes for the post ==========
-	likes, err := models.FetchLikesByPostID(uint(postIDUint))
+	// ========== Get the user ID from the context ==========
+	val, _ := ctx.Get("userID")
+	userID := val.(string)
+	userIDUint, err := strconv.ParseUint(userID, 10, 32)
 	if err != nil {
 		SendInternalError(ctx, err)
 		return
 	}
-
-	// ========== Get the user ID from the context ==========
-	val, _ := ctx.Get("userID")
-	userID := val.(string)
 	token, _ := auth.GenerateToken(userID)
 
+	// ========== Check the user can see the post ==========
+	if _, err := models.FetchPostForViewer(uint(postIDUint), uint(userIDUint)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"message": "Post not found"})
+			return
+		}
+		SendInternalError(ctx, err)
+		return
+	}
+
+	// ========== Fetch a page of the post's likes ==========
+	page, ok := parsePage(ctx)
+	if !ok {
+		return
+	}
+	likes, pageInfo, err := models.FetchLikesPageByPostID(uint(postIDUint), page)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
 	// ========== Convert the likes to JSON Structs ==========
 	jsonLikes := make([]JSONLike, 0)
 	for _, like := range *likes {
 		jsonLikes = append(jsonLikes, JSONLike{
 			ID:     like.ID,
 			UserID: like.UserID,
-			PostID: like.PostID,
+			PostID: *like.PostID,
 		})
 	}
 
 	// ========== Send the response (w/ token)==========
-	ctx.JSON(http.StatusOK, gin.H{"likes": jsonLikes, "token": token})
+	ctx.JSON(http.StatusOK, withPageInfo(gin.H{"likes": jsonLikes, "token": token}, pageInfo))
 }
 
 type createLikeRequestBody struct {
@@ -84,11 +106,14 @@ func CreateLike(ctx *gin.Context) {
 	// ========== Toggle like status ==========
 	if err == nil && existingLike != nil {
 		// Case 1: Like exists, so unlike (delete it)
-		err = existingLike.Delete() // gorm does this by adding to the deleted_at column
+		removedLike, err := models.UnlikePost(uint(userIDUint), requestBody.PostID) // gorm does this by adding to the deleted_at column
 		if err != nil {
 			SendInternalError(ctx, err)
 			return
 		}
+		if removedLike != nil {
+			afterLikeRemoved(removedLike)
+		}
 		// ========== Send success message ==========
 		ctx.JSON(http.StatusOK, gin.H{"message": "Like removed", "token": token})
 		return
@@ -97,19 +122,122 @@ func CreateLike(ctx *gin.Context) {
 		return
 	}
 
-	// Case 2: Like doesn't exist, so create it
-	newLike := models.Like{
-		PostID: requestBody.PostID,
-		UserID: uint(userIDUint),
+	// Case 2: Like doesn't exist, so create it (a double click won't create two),
+	// as long as the user can see the post
+	if _, err := models.FetchPostForViewer(requestBody.PostID, uint(userIDUint)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"message": "Post not found"})
+			return
+		}
+		SendInternalError(ctx, err)
+		return
 	}
-
-	// and then save the new like to the DB
-	_, err = newLike.Save()
+	newLike, created, err := models.LikePost(uint(userIDUint), requestBody.PostID)
 	if err != nil {
 		SendInternalError(ctx, err)
 		return
 	}
+	if created {
+		afterLikeCreated(newLike)
+	}
 
 	// ========== Send success message ==========
 	ctx.JSON(http.StatusCreated, gin.H{"message": "Like created", "token": token})
 }
+
+// ===== PUT /posts/:id/like =====
+// Likes a post. Liking a post you've already liked does nothing, so it's safe to retry.
+func LikePost(ctx *gin.Context) {
+	postID, userID, token, ok := parseLikeRequest(ctx)
+	if !ok {
+		return
+	}
+
+	like, created, err := models.LikePost(userID, postID)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+	if created {
+		afterLikeCreated(like)
+	}
+
+	sendLikeState(ctx, postID, true, token)
+}
+
+// ===== DELETE /posts/:id/like =====
+// Unlikes a post. Unliking a post you haven't liked does nothing, so it's safe to retry.
+func UnlikePost(ctx *gin.Context) {
+	postID, userID, token, ok := parseLikeRequest(ctx)
+	if !ok {
+		return
+	}
+
+	like, err := models.UnlikePost(userID, postID)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+	if like != nil {
+		afterLikeRemoved(like)
+	}
+
+	sendLikeState(ctx, postID, false, token)
+}
+
+// ======================== Helper functions for likes ==============================
+
+// parseLikeRequest gets the post from the URL (checking it exists, and the user can see it) and the current user
+func parseLikeRequest(ctx *gin.Context) (uint, uint, string, bool) {
+	postID, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid post ID"})
+		return 0, 0, "", false
+	}
+
+	val, _ := ctx.Get("userID")
+	userID := val.(string)
+	userIDUint, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return 0, 0, "", false
+	}
+
+	if _, err := models.FetchPostForViewer(uint(postID), uint(userIDUint)); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"message": "Post not found"})
+			return 0, 0, "", false
+		}
+		SendInternalError(ctx, err)
+		return 0, 0, "", false
+	}
+
+	token, _ := auth.GenerateToken(userID)
+	return uint(postID), uint(userIDUint), token, true
+}
+
+// sendLikeState responds with whether the user now likes the post and its up to date like count
+func sendLikeState(ctx *gin.Context, postID uint, liked bool, token string) {
+	post, err := models.FetchPostByID(postID)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+	ctx.JSON(http.StatusOK, gin.H{"liked": liked, "numOfLikes": post.LikeCount, "token": token})
+}
+
+// afterLikeCreated rewards and notifies the post's author for a new like (but not for liking your own post)
+func afterLikeCreated(like *models.Reaction) {
+	if post, err := models.FetchPostByID(*like.PostID); err == nil && post.UserID != like.UserID {
+		awardXP(post.UserID, models.XPLikeReceived, "like", like.ID)
+		achievements.Evaluate(post.UserID, achievements.EventLikeReceived)
+		notify(models.NotificationEvent{UserID: post.UserID, ActorID: like.UserID, Kind: models.NotificationLiked, PostID: &post.ID})
+	}
+}
+
+// afterLikeRemoved takes back the XP the author got for a like
+func afterLikeRemoved(like *models.Reaction) {
+	if post, err := models.FetchPostByID(*like.PostID); err == nil && post.UserID != like.UserID {
+		awardXP(post.UserID, models.XPLikeRemoved, "like", like.ID)
+	}
+}
@@ -0,0 +1,126 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/makersacademy/go-react-acebook-template/api/src/env"
+	"github.com/makersacademy/go-react-acebook-template/api/src/events"
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+	"golang.org/x/net/websocket"
+	"gorm.io/gorm"
+)
+
+// ===== GET /posts/:id/live =====
+// A WebSocket for everyone looking at a post. Comments being added and deleted are sent as they
+// happen, along with how many people are looking at the post. The client doesn't need to send
+// anything, and closing the socket (or the tab) is all it takes to leave.
+func LivePost(ctx *gin.Context) {
+	userID, _, ok := parseNotificationUser(ctx)
+	if !ok {
+		return
+	}
+	postID, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid post ID"})
+		return
+	}
+
+	// ========== Check the user can still see the post before subscribing ==========
+	if !canWatchPost(ctx, uint(postID), userID) {
+		return
+	}
+	topic := events.PostTopic(uint(postID))
+	if events.Default.SubscriberCountForUser(topic, userID) >= env.GetInt("EVENTS_MAX_STREAMS_PER_USER", 5) {
+		ctx.JSON(http.StatusTooManyRequests, gin.H{"message": "Too many open connections to this post"})
+		return
+	}
+
+	// The CORS settings allow every origin, so the socket does too
+	server := websocket.Server{Handler: func(conn *websocket.Conn) {
+		watchPost(conn, uint(postID), userID)
+	}}
+	server.ServeHTTP(ctx.Writer, ctx.Request)
+}
+
+// watchPost sends the post's events down the socket until either side goes away
+func watchPost(conn *websocket.Conn, postID uint, userID uint) {
+	defer conn.Close()
+	topic := events.PostTopic(postID)
+
+	subscription, _, _ := events.Default.Subscribe(topic, userID, 0)
+	publishPresence(postID)
+	defer func() {
+		events.Default.Unsubscribe(subscription)
+		publishPresence(postID)
+	}()
+
+	// Read (and ignore) anything the client sends, so we notice when it closes the socket.
+	// This goroutine finishes as soon as the connection is closed, by either side.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		var message string
+		for websocket.Message.Receive(conn, &message) == nil {
+		}
+	}()
+
+	// A heartbeat stops proxies closing quiet connections, and finds ones that have died
+	heartbeat := time.NewTicker(env.GetDuration("EVENTS_HEARTBEAT", 25*time.Second))
+	defer heartbeat.Stop()
+
+	for {
+		var event events.Event
+		select {
+		case <-closed:
+			return
+		case received, open := <-subscription.Events():
+			if !open {
+				// The client fell too far behind, so close the socket and let it reconnect
+				return
+			}
+			event = received
+		case <-heartbeat.C:
+			event = events.Event{Type: "heartbeat"}
+		}
+
+		conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+		if err := websocket.JSON.Send(conn, event); err != nil {
+			return
+		}
+	}
+}
+
+// publishPresence tells everyone looking at the post how many people are looking at it
+func publishPresence(postID uint) {
+	topic := events.PostTopic(postID)
+	events.Default.PublishTransient(events.Presence, map[string]interface{}{
+		"post_id": postID,
+		"viewers": events.Default.UserCount(topic),
+	}, topic)
+}
+
+// canWatchPost checks the post exists, the user can see it and is still allowed to use the site
+func canWatchPost(ctx *gin.Context, postID uint, userID uint) bool {
+	if _, err := models.FindUser(strconv.Itoa(int(userID))); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"message": "User not found"})
+			return false
+		}
+		SendInternalError(ctx, err)
+		return false
+	}
+
+	if _, err := models.FetchPostForViewer(postID, userID); err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"message": "Post not found"})
+			return false
+		}
+		SendInternalError(ctx, err)
+		return false
+	}
+	return true
+}
@@ -0,0 +1,37 @@
+package controllers
+
+import (
+	"fmt"
+
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+)
+
+// A JSONMention marks an @mention in a question or comment, so the client can turn it into a
+// link to the user's profile. Start and End are JavaScript string indexes.
+type JSONMention struct {
+	Start    int    `json:"start"`
+	End      int    `json:"end"`
+	UserID   uint   `json:"userID"`
+	Username string `json:"username"`
+}
+
+func toJSONMentions(text string, mentions []models.Mention) []JSONMention {
+	jsonMentions := make([]JSONMention, 0)
+	for _, span := range models.MentionSpans(text, mentions) {
+		jsonMentions = append(jsonMentions, JSONMention{
+			Start:    span.Start,
+			End:      span.End,
+			UserID:   span.UserID,
+			Username: span.Username,
+		})
+	}
+	return jsonMentions
+}
+
+// recordMentions stores the @mentions in a new or edited question or comment and notifies the
+// people mentioned. The post or comment is already saved, so a failure here is only logged.
+func recordMentions(authorID uint, target models.ReactionTarget, postID uint, text string) {
+	if err := models.RecordMentions(authorID, target, postID, text); err != nil {
+		fmt.Printf("Error recording mentions by user %d: %v\n", authorID, err)
+	}
+}
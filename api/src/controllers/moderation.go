@@ -0,0 +1,331 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/makersacademy/go-react-acebook-template/api/src/env"
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+	"gorm.io/gorm"
+)
+
+type JSONReport struct {
+	ID           uint       `json:"_id"`
+	ReporterID   uint       `json:"reporterID"`
+	Reporter     string     `json:"reporter"` // their username, or "" if they've deleted their account
+	TargetType   string     `json:"target_type"`
+	TargetID     uint       `json:"target_id"`
+	Reason       string     `json:"reason"`
+	Details      string     `json:"details"`
+	Status       string     `json:"status"`
+	ClaimedByID  *uint      `json:"claimed_by_id"`
+	ClaimedAt    *time.Time `json:"claimed_at"`
+	ResolvedByID *uint      `json:"resolved_by_id"`
+	ResolvedAt   *time.Time `json:"resolved_at"`
+	Action       string     `json:"action"` // what the moderator did, once it's resolved
+	CreatedAt    time.Time  `json:"created_at"`
+}
+
+type JSONModerationLogEntry struct {
+	ID          uint      `json:"_id"`
+	ModeratorID uint      `json:"moderatorID"`
+	Moderator   string    `json:"moderator"`
+	ReportID    *uint     `json:"report_id"`
+	Action      string    `json:"action"`
+	TargetType  string    `json:"target_type"`
+	TargetID    uint      `json:"target_id"`
+	Note        string    `json:"note"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type createReportRequestBody struct {
+	TargetType string `json:"target_type"` // "post", "comment" or "user"
+	TargetID   uint   `json:"target_id"`
+	Reason     string `json:"reason"`
+	Details    string `json:"details"`
+}
+
+// ===== POST /reports =====
+// Asks the moderators to look at a post, comment or user. Reporting the same thing again
+// before it's been dealt with returns the existing report.
+func CreateReport(ctx *gin.Context) {
+	userID, token, ok := parseNotificationUser(ctx)
+	if !ok {
+		return
+	}
+
+	var requestBody createReportRequestBody
+	if err := ctx.BindJSON(&requestBody); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request body"})
+		return
+	}
+	if !models.IsReportReason(requestBody.Reason) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Reason must be one of: " + strings.Join(models.ReportReasons, ", ")})
+		return
+	}
+	details := strings.TrimSpace(requestBody.Details)
+	if len(details) > 1000 {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Details must be 1000 characters or fewer"})
+		return
+	}
+
+	// ========== Check the reported thing exists, and isn't the user's own ==========
+	authorID, err := models.ReportTargetAuthor(models.Database, requestBody.TargetType, requestBody.TargetID)
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"message": "Nothing to report"})
+			return
+		}
+		SendInternalError(ctx, err)
+		return
+	}
+	if authorID == userID {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "You can't report yourself"})
+		return
+	}
+
+	report, created, err := models.CreateReport(&models.Report{
+		ReporterID: userID,
+		TargetType: requestBody.TargetType,
+		TargetID:   requestBody.TargetID,
+		Reason:     requestBody.Reason,
+		Details:    details,
+	})
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	status, response := http.StatusOK, "Already reported"
+	if created {
+		status, response = http.StatusCreated, "Report sent"
+	}
+	ctx.JSON(status, gin.H{"message": response, "report_id": report.ID, "token": token})
+}
+
+// ===== GET /moderation/reports =====
+// Moderators only. Returns a page of reports, oldest first. ?status= can be open, claimed or
+// resolved (or a comma separated list of them), and defaults to everything not yet resolved.
+func GetReports(ctx *gin.Context) {
+	_, token, ok := parseModeratorRequest(ctx)
+	if !ok {
+		return
+	}
+
+	statuses := []string{models.ReportOpen, models.ReportClaimed}
+	if query := ctx.Query("status"); query != "" {
+		statuses = strings.Split(query, ",")
+		for _, status := range statuses {
+			if status != models.ReportOpen && status != models.ReportClaimed && status != models.ReportResolved {
+				ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid status"})
+				return
+			}
+		}
+	}
+
+	page, ok := parsePage(ctx)
+	if !ok {
+		return
+	}
+	reports, pageInfo, err := models.FetchReportsPage(statuses, page)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	jsonReports := make([]JSONReport, 0, len(*reports))
+	for index := range *reports {
+		jsonReports = append(jsonReports, toJSONReport(&(*reports)[index]))
+	}
+
+	ctx.JSON(http.StatusOK, withPageInfo(gin.H{"reports": jsonReports, "token": token}, pageInfo))
+}
+
+// ===== POST /moderation/reports/:id/claim =====
+// Moderators only. Marks the report as being dealt with by the current moderator.
+func ClaimReport(ctx *gin.Context) {
+	moderatorID, reportID, token, ok := parseReportRequest(ctx)
+	if !ok {
+		return
+	}
+
+	report, err := models.ClaimReport(reportID, moderatorID, time.Now())
+	if err != nil {
+		sendModerationError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Report claimed", "report": toJSONReport(report), "token": token})
+}
+
+type resolveReportRequestBody struct {
+	Action      string `json:"action"` // "dismiss", "hide", "warn" or "suspend"
+	Note        string `json:"note"`
+	SuspendDays int    `json:"suspend_days"` // only for "suspend"
+}
+
+// ===== POST /moderation/reports/:id/resolve =====
+// Moderators only. Carries out a decision on a report the moderator has claimed, and resolves
+// every other open report about the same thing with it.
+func ResolveReport(ctx *gin.Context) {
+	moderatorID, reportID, token, ok := parseReportRequest(ctx)
+	if !ok {
+		return
+	}
+
+	var requestBody resolveReportRequestBody
+	if err := ctx.BindJSON(&requestBody); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request body"})
+		return
+	}
+
+	now := time.Now()
+	resolution := models.Resolution{Action: requestBody.Action, Note: strings.TrimSpace(requestBody.Note)}
+	if requestBody.Action == models.ModerationSuspend {
+		days := requestBody.SuspendDays
+		if days == 0 {
+			days = env.GetInt("MODERATION_SUSPEND_DAYS", 7)
+		}
+		if days < 0 {
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "suspend_days must be positive"})
+			return
+		}
+		until := now.AddDate(0, 0, days)
+		resolution.SuspendedUntil = &until
+	}
+
+	report, err := models.ResolveReport(reportID, moderatorID, resolution, now)
+	if err != nil {
+		sendModerationError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Report resolved", "report": toJSONReport(report), "token": token})
+}
+
+// ===== GET /moderation/log =====
+// Moderators only. Returns a page of everything moderators have done, newest first.
+// ?target_type=&target_id= narrows it down to one post, comment or user.
+func GetModerationLog(ctx *gin.Context) {
+	_, token, ok := parseModeratorRequest(ctx)
+	if !ok {
+		return
+	}
+
+	targetType := ctx.Query("target_type")
+	var targetID uint64
+	if targetType != "" {
+		var err error
+		if targetID, err = strconv.ParseUint(ctx.Query("target_id"), 10, 32); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid target ID"})
+			return
+		}
+	}
+
+	page, ok := parsePage(ctx)
+	if !ok {
+		return
+	}
+	entries, pageInfo, err := models.FetchModerationLogPage(targetType, uint(targetID), page)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	jsonEntries := make([]JSONModerationLogEntry, 0, len(*entries))
+	for _, entry := range *entries {
+		jsonEntries = append(jsonEntries, JSONModerationLogEntry{
+			ID:          entry.ID,
+			ModeratorID: entry.ModeratorID,
+			Moderator:   entry.Moderator.Username,
+			ReportID:    entry.ReportID,
+			Action:      entry.Action,
+			TargetType:  entry.TargetType,
+			TargetID:    entry.TargetID,
+			Note:        entry.Note,
+			CreatedAt:   entry.CreatedAt,
+		})
+	}
+
+	ctx.JSON(http.StatusOK, withPageInfo(gin.H{"entries": jsonEntries, "token": token}, pageInfo))
+}
+
+// ======================== Helper functions for moderation ==============================
+
+// parseModeratorRequest gets the current user, and checks they're a moderator
+func parseModeratorRequest(ctx *gin.Context) (uint, string, bool) {
+	userID, token, ok := parseNotificationUser(ctx)
+	if !ok {
+		return 0, "", false
+	}
+
+	user, err := models.FindUser(strconv.Itoa(int(userID)))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusUnauthorized, gin.H{"message": "User not found"})
+			return 0, "", false
+		}
+		SendInternalError(ctx, err)
+		return 0, "", false
+	}
+	if !user.IsModerator() {
+		ctx.JSON(http.StatusForbidden, gin.H{"message": "Only moderators can do this"})
+		return 0, "", false
+	}
+	return userID, token, true
+}
+
+// parseReportRequest gets the current moderator and the report ID in the URL
+func parseReportRequest(ctx *gin.Context) (uint, uint, string, bool) {
+	moderatorID, token, ok := parseModeratorRequest(ctx)
+	if !ok {
+		return 0, 0, "", false
+	}
+
+	reportID, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid report ID"})
+		return 0, 0, "", false
+	}
+	return moderatorID, uint(reportID), token, true
+}
+
+func sendModerationError(ctx *gin.Context, err error) {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "Report not found"})
+	case errors.Is(err, models.ErrReportResolved):
+		ctx.JSON(http.StatusConflict, gin.H{"message": "This report has already been resolved"})
+	case errors.Is(err, models.ErrReportClaimed):
+		ctx.JSON(http.StatusConflict, gin.H{"message": "Another moderator has claimed this report"})
+	case errors.Is(err, models.ErrReportNotClaimed):
+		ctx.JSON(http.StatusConflict, gin.H{"message": "Claim the report before resolving it"})
+	case errors.Is(err, models.ErrInvalidAction):
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "That action can't be used on this report"})
+	default:
+		SendInternalError(ctx, err)
+	}
+}
+
+func toJSONReport(report *models.Report) JSONReport {
+	return JSONReport{
+		ID:           report.ID,
+		ReporterID:   report.ReporterID,
+		Reporter:     report.Reporter.Username,
+		TargetType:   report.TargetType,
+		TargetID:     report.TargetID,
+		Reason:       report.Reason,
+		Details:      report.Details,
+		Status:       report.Status,
+		ClaimedByID:  report.ClaimedByID,
+		ClaimedAt:    report.ClaimedAt,
+		ResolvedByID: report.ResolvedByID,
+		ResolvedAt:   report.ResolvedAt,
+		Action:       report.Action,
+		CreatedAt:    report.CreatedAt,
+	}
+}
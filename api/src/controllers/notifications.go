@@ -0,0 +1,147 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/makersacademy/go-react-acebook-template/api/src/auth"
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+)
+
+type JSONNotification struct {
+	ID         uint       `json:"_id"`
+	Kind       string     `json:"kind"`
+	Message    string     `json:"message"`
+	PostID     *uint      `json:"post_id"`
+	ActorID    *uint      `json:"actorID"`    // the person who most recently caused it
+	Actor      string     `json:"actor"`      // their username, or "" if there isn't one (or they've been deleted)
+	ActorCount int        `json:"actorCount"` // how many different people are behind it
+	Read       bool       `json:"read"`
+	CreatedAt  time.Time  `json:"created_at"`
+	UpdatedAt  time.Time  `json:"updated_at"` // when the latest person was added
+	ReadAt     *time.Time `json:"read_at"`
+}
+
+// ===== GET /notifications =====
+// Returns a page of the current user's notifications, most recent first, and how many are unread
+func GetNotifications(ctx *gin.Context) {
+	userID, token, ok := parseNotificationUser(ctx)
+	if !ok {
+		return
+	}
+
+	page, ok := parsePage(ctx)
+	if !ok {
+		return
+	}
+	notifications, pageInfo, err := models.FetchNotificationsPage(userID, page)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+	unread, err := models.CountUnreadNotifications(userID)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	jsonNotifications := make([]JSONNotification, 0, len(*notifications))
+	for _, notification := range *notifications {
+		actor := ""
+		if notification.Actor != nil {
+			actor = notification.Actor.Username
+		}
+		jsonNotifications = append(jsonNotifications, JSONNotification{
+			ID:         notification.ID,
+			Kind:       notification.Kind,
+			Message:    notification.Message,
+			PostID:     notification.PostID,
+			ActorID:    notification.ActorID,
+			Actor:      actor,
+			ActorCount: notification.ActorCount,
+			Read:       notification.ReadAt != nil,
+			CreatedAt:  notification.CreatedAt,
+			UpdatedAt:  notification.UpdatedAt,
+			ReadAt:     notification.ReadAt,
+		})
+	}
+
+	ctx.JSON(http.StatusOK, withPageInfo(gin.H{"notifications": jsonNotifications, "unread": unread, "token": token}, pageInfo))
+}
+
+// ===== GET /notifications/unread_count =====
+// A cheap way for the client to check for new notifications
+func GetUnreadNotificationCount(ctx *gin.Context) {
+	userID, token, ok := parseNotificationUser(ctx)
+	if !ok {
+		return
+	}
+
+	unread, err := models.CountUnreadNotifications(userID)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"unread": unread, "token": token})
+}
+
+type markNotificationsReadRequestBody struct {
+	IDs []uint `json:"ids"` // leave out to mark every notification as read
+}
+
+// ===== POST /notifications/read =====
+// Marks some (or all) of the current user's notifications as read
+func MarkNotificationsRead(ctx *gin.Context) {
+	userID, token, ok := parseNotificationUser(ctx)
+	if !ok {
+		return
+	}
+
+	// An empty body means mark everything as read
+	var requestBody markNotificationsReadRequestBody
+	if ctx.Request.ContentLength != 0 {
+		if err := ctx.BindJSON(&requestBody); err != nil {
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid request body"})
+			return
+		}
+	}
+
+	if err := models.MarkNotificationsRead(userID, requestBody.IDs, time.Now()); err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+	unread, err := models.CountUnreadNotifications(userID)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, gin.H{"message": "Notifications marked as read", "unread": unread, "token": token})
+}
+
+// ======================== Helper functions for notifications ==============================
+
+func parseNotificationUser(ctx *gin.Context) (uint, string, bool) {
+	val, _ := ctx.Get("userID")
+	userID := val.(string)
+	userIDUint, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return 0, "", false
+	}
+
+	token, _ := auth.GenerateToken(userID)
+	return uint(userIDUint), token, true
+}
+
+// notify tells a user about something someone else did. The action has already happened,
+// so a failure here is only logged.
+func notify(event models.NotificationEvent) {
+	if err := models.Notify(models.Database, event); err != nil {
+		fmt.Printf("Error sending %s notification to user %d: %v\n", event.Kind, event.UserID, err)
+	}
+}
@@ -0,0 +1,50 @@
+package controllers
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+)
+
+// parsePage reads the ?limit=, ?after= and ?before= query params used by every list endpoint.
+// It sends a 400 and returns false if any of them are invalid.
+func parsePage(ctx *gin.Context) (models.Page, bool) {
+	page := models.Page{Limit: models.DefaultPageSize}
+
+	if limit := ctx.Query("limit"); limit != "" {
+		parsed, err := strconv.Atoi(limit)
+		if err != nil || parsed < 1 || parsed > models.MaxPageSize {
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "limit must be between 1 and " + strconv.Itoa(models.MaxPageSize)})
+			return page, false
+		}
+		page.Limit = parsed
+	}
+
+	after, before := ctx.Query("after"), ctx.Query("before")
+	if after != "" && before != "" {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Use either after or before, not both"})
+		return page, false
+	}
+
+	var err error
+	if after != "" {
+		page.After, err = models.DecodeCursor(after)
+	} else if before != "" {
+		page.Before, err = models.DecodeCursor(before)
+	}
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid cursor"})
+		return page, false
+	}
+
+	return page, true
+}
+
+// withPageInfo adds the cursors for the next and previous pages to a list response
+func withPageInfo(response gin.H, info models.PageInfo) gin.H {
+	response["next_cursor"] = info.NextCursor
+	response["prev_cursor"] = info.PrevCursor
+	return response
+}
@@ -8,27 +8,47 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/makersacademy/go-react-acebook-template/api/src/achievements"
 	"github.com/makersacademy/go-react-acebook-template/api/src/auth"
 	"github.com/makersacademy/go-react-acebook-template/api/src/models"
 )
 
 type PostCommentJSON struct {
-	UserID   uint   `json:"userID"`
-	Username string `json:"username"`
-	Contents string `json:"contents"`
+	ID         uint          `json:"_id"`
+	UserID     uint          `json:"userID"`
+	Username   string        `json:"username"`
+	Contents   string        `json:"contents"`
+	ParentID   *uint         `json:"parent_id"`
+	Depth      int           `json:"depth"`
+	ReplyCount int           `json:"replyCount"`
+	Deleted    bool          `json:"deleted"`
+	Moderated  bool          `json:"hiddenByModerator"` // only its author can see it
+	EditedAt   *time.Time    `json:"edited_at"`         // null if the comment has never been edited
+	Spoiler    bool          `json:"spoiler"`           // the author tagged it as a spoiler
+	Hidden     bool          `json:"spoilerHidden"`     // hidden because it gives away the answer
+	Mentions   []JSONMention `json:"mentions"`
+	Reactions  JSONReactions `json:"reactions"`
 }
 
 type JSONPost struct {
-	ID         uint              `json:"_id"`
-	Question   string            `json:"question"`
-	Answer     string            `json:"answer"`
-	UserID     uint              `json:"user_id"`
-	Username   string            `json:"username"`
-	User       JSONPostUser      `json:"user"`
-	Comments   []PostCommentJSON `json:"comments"`
-	NumOfLikes int               `json:"numOfLikes"`
-	Liked      bool              `json:"liked"`
-	CreatedAt  string            `json:"created_at"`
+	ID             uint              `json:"_id"`
+	Question       string            `json:"question"`
+	Mentions       []JSONMention     `json:"mentions"` // @mentions in the question
+	Answer         string            `json:"answer"`
+	AnswerRevealed bool              `json:"answerRevealed"`
+	RevealPolicy   string            `json:"revealPolicy"`
+	MayBeOutdated  bool              `json:"mayBeOutdated"`
+	Moderated      bool              `json:"hiddenByModerator"` // only its author can see it
+	Bounty         *JSONBounty       `json:"bounty"`
+	UserID         uint              `json:"user_id"`
+	Username       string            `json:"username"`
+	User           JSONPostUser      `json:"user"`
+	Comments       []PostCommentJSON `json:"comments"`
+	NumOfLikes     int               `json:"numOfLikes"`
+	NumOfComments  int               `json:"numOfComments"`
+	Reactions      JSONReactions     `json:"reactions"`
+	Liked          bool              `json:"liked"`
+	CreatedAt      string            `json:"created_at"`
 }
 
 type JSONPostUser struct {
@@ -39,12 +59,6 @@ type JSONPostUser struct {
 
 func GetAllPosts(ctx *gin.Context) {
 	// ============================= Fetch all posts from the database ==========================
-	posts, err := models.FetchAllPosts()
-	if err != nil {
-		SendInternalError(ctx, err)
-		return
-	}
-
 	// ========== Get the user ID from the context (set by AuthenticationMiddleware) ============
 	val, _ := ctx.Get("userID")
 	userID := val.(string)
@@ -53,86 +67,57 @@ func GetAllPosts(ctx *gin.Context) {
 		SendInternalError(ctx, err)
 		return
 	}
-	token, _ := auth.GenerateToken(userID) // Generate new token for the response
-
-	// ============================= Convert posts to JSON Structs ==============================
-	jsonPosts := make([]JSONPost, 0)
-	for _, post := range *posts {
-		// Grab the post author's username
-		author, err := models.FindUser(strconv.Itoa(int(post.UserID)))
-		authorUsername := "Unknown" // Default if author not found, although this shouldn't happen
-		if err == nil {
-			authorUsername = author.Username
-		}
 
-		// grab comments for the post
-		comments, err := models.FetchCommentsByPostID(post.ID)
-		if err != nil {
-			// I think this is probably the best way to hangle this? maybe continue to next post?
-			// For now, we'll just send an internal error for simplicity.
-			SendInternalError(ctx, err)
-			return
-		}
-
-		// ============================= Convert comments to JSON Structs ==========================
-		jsonComments := make([]PostCommentJSON, 0)
-		for _, comment := range *comments {
-			// Find the user who made the comment to get their username
-			username := "Unknown" // Default username if user not found
-			user, err := models.FindUser(strconv.Itoa(int(comment.UserID)))
-			if err == nil {
-				username = user.Username
-			}
-
-			// Append the comment to the JSON comments
-			jsonComments = append(jsonComments, PostCommentJSON{
-				UserID:   comment.UserID,
-				Username: username,
-				Contents: comment.Content,
-			})
-		}
+	// ?limit=, ?after= and ?before= pick which page of the feed to send
+	page, ok := parsePage(ctx)
+	if !ok {
+		return
+	}
 
-		// ============================= Fetch likes for the post ===================================
-		likes, err := models.FetchLikesByPostID(post.ID)
-		if err != nil {
-			SendInternalError(ctx, err)
+	// ?feed=following only shows posts from people the current user follows
+	// ?bounty=open narrows the feed down to questions with a bounty still up for grabs
+	// ?sort=trending|new|top&window=day|week|all orders the main feed
+	var posts *[]models.Post
+	var pageInfo models.PageInfo
+	if ctx.Query("feed") == "following" {
+		posts, pageInfo, err = models.FetchPostsFromFollowedUsers(uint(userIDUint), page)
+	} else if ctx.Query("bounty") == "open" {
+		posts, pageInfo, err = models.FetchPostsWithOpenBounty(time.Now(), uint(userIDUint), page)
+	} else if ctx.Query("sort") != "" || ctx.Query("window") != "" {
+		sort, since, ok := parseFeedSort(ctx.DefaultQuery("sort", models.SortNew), ctx.DefaultQuery("window", "all"), time.Now())
+		if !ok {
+			ctx.JSON(http.StatusBadRequest, gin.H{"message": "sort must be trending, new or top, and window must be day, week or all"})
 			return
 		}
-		numOfLikes := len(*likes)
-
-		// ============================= Check if current user has liked this post ==================
-		liked := false
-		existingLike, err := models.FindLikeByUserIDAndPostID(uint(userIDUint), post.ID)
-		if err == nil && existingLike != nil {
-			liked = true
-		}
+		posts, pageInfo, err = models.FetchSortedPosts(sort, since, uint(userIDUint), page)
+	} else {
+		posts, pageInfo, err = models.FetchAllPosts(uint(userIDUint), page)
+	}
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+	token, _ := auth.GenerateToken(userID) // Generate new token for the response
 
-		// ============================= Append to JSON posts for response ==========================
-		jsonPosts = append(jsonPosts, JSONPost{
-			ID:       post.ID,
-			Question: post.Question,
-			Answer:   post.Answer,
-			UserID:   post.UserID,
-			Username: authorUsername,
-			User: JSONPostUser{
-				ID:                author.ID,
-				Username:          author.Username,
-				ProfilePictureURL: author.ProfilePictureURL,
-			},
-			Comments:   jsonComments,
-			NumOfLikes: numOfLikes,
-			Liked:      liked,
-			CreatedAt:  post.CreatedAt.Format(time.RFC3339),
-		})
+	// ============================= Convert posts to JSON Structs ==============================
+	jsonPosts, err := toJSONPosts(*posts, uint(userIDUint))
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
 	}
 
 	// ============================ Send response (including token) ================================
-	ctx.JSON(http.StatusOK, gin.H{"posts": jsonPosts, "token": token})
+	ctx.JSON(http.StatusOK, withPageInfo(gin.H{"posts": jsonPosts, "token": token}, pageInfo))
 }
 
 type createPostRequestBody struct {
-	Question string `json:"question"`
-	Answer   string `json:"answer"`
+	Question           string     `json:"question"`
+	Answer             string     `json:"answer"`
+	RevealPolicy       string     `json:"reveal_policy"`
+	RevealAfterCorrect int        `json:"reveal_after_correct"`
+	RevealAt           *time.Time `json:"reveal_at"`
+	ValidUntil         *time.Time `json:"valid_until"`
+	ReviewAfter        *time.Time `json:"review_after"`
 }
 
 func CreatePost(ctx *gin.Context) {
@@ -149,6 +134,15 @@ func CreatePost(ctx *gin.Context) {
 		return
 	}
 
+	// ============================= Check the reveal policy is valid =============================
+	if err := models.ValidateRevealPolicy(requestBody.RevealPolicy, requestBody.RevealAfterCorrect, requestBody.RevealAt); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if requestBody.RevealPolicy == "" {
+		requestBody.RevealPolicy = models.RevealImmediately
+	}
+
 	// ========== Get the user ID from the context (set by AuthenticationMiddleware) ============
 	val, _ := ctx.Get("userID")
 	userID, ok := val.(string)
@@ -167,9 +161,14 @@ func CreatePost(ctx *gin.Context) {
 	// ============================= Create the new post =========================================
 	// Create the new post
 	newPost := models.Post{
-		Question: requestBody.Question,
-		Answer:   requestBody.Answer,
-		UserID:   uint(parsed),
+		Question:           requestBody.Question,
+		Answer:             requestBody.Answer,
+		RevealPolicy:       requestBody.RevealPolicy,
+		RevealAfterCorrect: requestBody.RevealAfterCorrect,
+		RevealAt:           requestBody.RevealAt,
+		ValidUntil:         requestBody.ValidUntil,
+		ReviewAfter:        requestBody.ReviewAfter,
+		UserID:             uint(parsed),
 	}
 
 	// Save the new post to the database
@@ -179,6 +178,13 @@ func CreatePost(ctx *gin.Context) {
 		return
 	}
 
+	// Tell anyone mentioned in the question
+	recordMentions(newPost.UserID, models.OnPost(newPost.ID), newPost.ID, newPost.Question)
+
+	// Reward the author for adding to the question bank
+	awardXP(newPost.UserID, models.XPPostCreated, "post", newPost.ID)
+	achievements.Evaluate(newPost.UserID, achievements.EventPostCreated)
+
 	// ========================== Generate token & send response ================================
 	token, _ := auth.GenerateToken(userID)
 	ctx.JSON(http.StatusCreated, gin.H{"message": "Post created", "token": token})
@@ -193,13 +199,6 @@ func GetPostsByUserID(ctx *gin.Context) {
 		return
 	}
 
-	// ============================= Fetch posts by the user ID =================================
-	posts, err := models.FetchPostsByUserID(uint(userID))
-	if err != nil {
-		SendInternalError(ctx, err)
-		return
-	}
-
 	// ========== Get the user ID from the context (set by AuthenticationMiddleware) ============
 	val, _ := ctx.Get("userID")
 	tokenUserID := val.(string)
@@ -210,74 +209,26 @@ func GetPostsByUserID(ctx *gin.Context) {
 	}
 	token, _ := auth.GenerateToken(tokenUserID) // Generate new token for the response
 
-	// ============================= Convert posts to JSON Structs ==============================
-	jsonPosts := make([]JSONPost, 0)
-	for _, post := range *posts {
-		// Grab the post author's username
-		authorUsername := "Unknown" // Default if author not found
-		author, err := models.FindUser(strconv.Itoa(int(post.UserID)))
-		if err == nil {
-			authorUsername = author.Username
-		}
-
-		// Grab comments for the post
-		comments, err := models.FetchCommentsByPostID(post.ID)
-		if err != nil {
-			SendInternalError(ctx, err)
-			return
-		}
-		// Convert comments to JSON structs
-		jsonComments := make([]PostCommentJSON, 0)
-		for _, comment := range *comments {
-			username := "Unknown" // Default if user not found
-			user, err := models.FindUser(strconv.Itoa(int(comment.UserID)))
-			if err == nil {
-				username = user.Username
-			}
-
-			jsonComments = append(jsonComments, PostCommentJSON{
-				UserID:   comment.UserID,
-				Username: username,
-				Contents: comment.Content,
-			})
-		}
-
-		// Grab likes for the post
-		likes, err := models.FetchLikesByPostID(post.ID)
-		if err != nil {
-			SendInternalError(ctx, err)
-			return
-		}
-		numOfLikes := len(*likes)
-
-		// ============================= Check if current user has liked this post ==================
-		liked := false
-		existingLike, err := models.FindLikeByUserIDAndPostID(uint(currentUserIDUint), post.ID)
-		if err == nil && existingLike != nil {
-			liked = true
-		}
+	// ============================= Fetch a page of posts by the user ID =======================
+	page, ok := parsePage(ctx)
+	if !ok {
+		return
+	}
+	posts, pageInfo, err := models.FetchPostsByUserID(uint(userID), uint(currentUserIDUint), page)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
 
-		// ========================= Append to JSON posts for response ==============================
-		jsonPosts = append(jsonPosts, JSONPost{
-			ID:       post.ID,
-			Question: post.Question,
-			Answer:   post.Answer,
-			UserID:   post.UserID,
-			Username: authorUsername,
-			User: JSONPostUser{
-				ID:                author.ID,
-				Username:          author.Username,
-				ProfilePictureURL: author.ProfilePictureURL,
-			},
-			Comments:   jsonComments,
-			NumOfLikes: numOfLikes,
-			Liked:      liked,
-			CreatedAt:  post.CreatedAt.Format(time.RFC3339),
-		})
+	// ============================= Convert posts to JSON Structs ==============================
+	jsonPosts, err := toJSONPosts(*posts, uint(currentUserIDUint))
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
 	}
 
 	// ========================== Generate token & send response ================================
-	ctx.JSON(http.StatusOK, gin.H{"posts": jsonPosts, "token": token})
+	ctx.JSON(http.StatusOK, withPageInfo(gin.H{"posts": jsonPosts, "token": token}, pageInfo))
 }
 
 func GetLikedPostsByUserID(ctx *gin.Context) {
@@ -288,36 +239,33 @@ func GetLikedPostsByUserID(ctx *gin.Context) {
 		return
 	}
 
-	posts, err := models.FetchLikedPostsByUserID(uint(userIdUint))
+	val, _ := ctx.Get("userID")
+	userID := val.(string)
+	viewerID, err := strconv.ParseUint(userID, 10, 32)
 	if err != nil {
 		SendInternalError(ctx, err)
 		return
 	}
-
-	val, _ := ctx.Get("userID")
-	userID := val.(string)
 	token, _ := auth.GenerateToken(userID)
 
-	jsonPosts := make([]JSONPost, len(*posts))
-	for _, post := range *posts {
+	page, ok := parsePage(ctx)
+	if !ok {
+		return
+	}
+	posts, pageInfo, err := models.FetchLikedPostsByUserID(uint(userIdUint), uint(viewerID), page)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
 
-		// ========================= Append to JSON posts for response ==============================
-		jsonPosts = append(jsonPosts, JSONPost{
-			ID:       post.ID,
-			Question: post.Question,
-			Answer:   post.Answer,
-			UserID:   post.UserID,
-			Username: post.User.Username,
-			User: JSONPostUser{
-				ID:                post.User.ID,
-				Username:          post.User.Username,
-				ProfilePictureURL: post.User.ProfilePictureURL,
-			},
-			CreatedAt: post.CreatedAt.Format(time.RFC3339),
-		})
+	// ============================= Convert posts to JSON Structs ==============================
+	jsonPosts, err := toJSONPosts(*posts, uint(viewerID))
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
 	}
 
-	ctx.JSON(http.StatusOK, gin.H{"posts": jsonPosts, "token": token})
+	ctx.JSON(http.StatusOK, withPageInfo(gin.H{"posts": jsonPosts, "token": token}, pageInfo))
 }
 
 func GetCurrentUserPosts(ctx *gin.Context) {
@@ -331,80 +279,52 @@ func GetCurrentUserPosts(ctx *gin.Context) {
 	}
 	token, _ := auth.GenerateToken(userID) // Generate new token for the response
 
-	// ============================= Fetch posts by the user ID =================================
-	posts, err := models.FetchPostsByUserID(uint(parsed))
+	// ============================= Fetch a page of the user's posts ===========================
+	page, ok := parsePage(ctx)
+	if !ok {
+		return
+	}
+	posts, pageInfo, err := models.FetchPostsByUserID(uint(parsed), uint(parsed), page)
 	if err != nil {
 		SendInternalError(ctx, err)
 		return
 	}
 
 	// ============================= Convert posts to JSON Structs ==============================
-	jsonPosts := make([]JSONPost, 0)
-	for _, post := range *posts {
-		// Grab the post author's username
-		authorUsername := "Unknown" // Default if author not found
-		author, err := models.FindUser(strconv.Itoa(int(post.UserID)))
-		if err == nil {
-			authorUsername = author.Username
-		}
-
-		// Grab comments for the post
-		comments, err := models.FetchCommentsByPostID(post.ID)
-		if err != nil {
-			SendInternalError(ctx, err)
-			return
-		}
-
-		// ============================= Convert comments to JSON Structs ==========================
-		jsonComments := make([]PostCommentJSON, 0)
-		for _, comment := range *comments {
-			// Find the user who made the comment to get their username
-			username := "Unknown" // Default if user not found
-			user, err := models.FindUser(strconv.Itoa(int(comment.UserID)))
-			if err == nil {
-				username = user.Username
-			}
+	jsonPosts, err := toJSONPosts(*posts, uint(parsed))
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
 
-			// Append the comment to the JSON comments
-			jsonComments = append(jsonComments, PostCommentJSON{
-				UserID:   comment.UserID,
-				Username: username,
-				Contents: comment.Content,
-			})
-		}
+	// ============================ Send response (including token) ================================
+	ctx.JSON(http.StatusOK, withPageInfo(gin.H{"posts": jsonPosts, "token": token}, pageInfo))
+}
 
-		// ============================= Fetch likes for the post ===================================
-		likes, err := models.FetchLikesByPostID(post.ID)
-		if err != nil {
-			SendInternalError(ctx, err)
-			return
-		}
-		numOfLikes := len(*likes)
+// Returns the logged in user's posts that the staleness job has flagged as possibly outdated
+func GetCurrentUserPostsNeedingReview(ctx *gin.Context) {
+	// ========== Get the user ID from the context (set by AuthenticationMiddleware) ============
+	val, _ := ctx.Get("userID")
+	userID := val.(string)
+	parsed, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+	token, _ := auth.GenerateToken(userID) // Generate new token for the response
 
-		// ============================= Check if current user has liked this post ================
-		liked := false
-		existingLike, err := models.FindLikeByUserIDAndPostID(uint(parsed), post.ID)
-		if err == nil && existingLike != nil {
-			liked = true
-		}
+	// ============================= Fetch the flagged posts ====================================
+	posts, err := models.FetchPostsNeedingReviewByUserID(uint(parsed))
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
 
-		// ============================= Append to JSON posts for response ==========================
-		jsonPosts = append(jsonPosts, JSONPost{
-			ID:       post.ID,
-			Question: post.Question,
-			Answer:   post.Answer,
-			UserID:   post.UserID,
-			Username: authorUsername,
-			User: JSONPostUser{
-				ID:                author.ID,
-				Username:          author.Username,
-				ProfilePictureURL: author.ProfilePictureURL,
-			},
-			Comments:   jsonComments,
-			NumOfLikes: numOfLikes,
-			Liked:      liked,
-			CreatedAt:  post.CreatedAt.Format(time.RFC3339),
-		})
+	// ============================= Convert posts to JSON Structs ==============================
+	jsonPosts, err := toJSONPosts(*posts, uint(parsed))
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
 	}
 
 	// ============================ Send response (including token) ================================
@@ -420,17 +340,6 @@ func GetPostByID(ctx *gin.Context) {
 		return
 	}
 
-	// ============================= Fetch the post by ID =======================================
-	post, err := models.FetchPostByID(uint(postID))
-	if err != nil {
-		if err.Error() == "record not found" { // if there's no post with that ID
-			ctx.JSON(http.StatusNotFound, gin.H{"message": "Post not found"})
-			return
-		}
-		SendInternalError(ctx, err)
-		return
-	}
-
 	// ========== Get the user ID from the context (set by AuthenticationMiddleware) ============
 	val, _ := ctx.Get("userID")
 	userID := val.(string)
@@ -441,74 +350,42 @@ func GetPostByID(ctx *gin.Context) {
 	}
 	token, _ := auth.GenerateToken(userID) // Generate new token for the response
 
-	// ============================= Convert post to JSON Struct ===============================
-	// Grab the post author's username
-	authorUsername := "Unknown" // Default if author not found
-	author, err := models.FindUser(strconv.Itoa(int(post.UserID)))
-	if err == nil {
-		authorUsername = author.Username
-	}
-
-	// Grab comments for the post
-	comments, err := models.FetchCommentsByPostID(post.ID)
+	// ============================= Fetch the post by ID =======================================
+	// Posts by someone who has blocked the user (or who they've blocked) are treated as not found
+	post, err := models.FetchPostForViewer(uint(postID), uint(userIDUint))
 	if err != nil {
+		if err.Error() == "record not found" { // if there's no post with that ID
+			ctx.JSON(http.StatusNotFound, gin.H{"message": "Post not found"})
+			return
+		}
 		SendInternalError(ctx, err)
 		return
 	}
 
-	// ============================= Convert comments to JSON Structs ==========================
-	jsonComments := make([]PostCommentJSON, 0)
-	for _, comment := range *comments {
-		// Find the user who made the comment to get their username
-		username := "Unknown" // Default if user not found
-		user, err := models.FindUser(strconv.Itoa(int(comment.UserID)))
-		if err == nil {
-			username = user.Username
-		}
-
-		// Append the comment to the JSON comments
-		jsonComments = append(jsonComments, PostCommentJSON{
-			UserID:   comment.UserID,
-			Username: username,
-			Contents: comment.Content,
-		})
-	}
-
-	// Grab likes for the post
-	likes, err := models.FetchLikesByPostID(post.ID)
+	// ============================= Convert post to JSON Struct ===============================
+	jsonPosts, err := toJSONPosts([]models.Post{*post}, uint(userIDUint))
 	if err != nil {
 		SendInternalError(ctx, err)
 		return
 	}
-	numOfLikes := len(*likes)
+	jsonPost := jsonPosts[0]
 
-	// ============================= Check if current user has liked this post ================
-	liked := false
-	existingLike, err := models.FindLikeByUserIDAndPostID(uint(userIDUint), post.ID)
-	if err == nil && existingLike != nil {
-		liked = true
-	}
-
-	jsonPost := JSONPost{
-		ID:       post.ID,
-		Question: post.Question,
-		Answer:   post.Answer,
-		UserID:   post.UserID,
-		Username: authorUsername,
-		User: JSONPostUser{
-			ID:                author.ID,
-			Username:          author.Username,
-			ProfilePictureURL: author.ProfilePictureURL,
-		},
-		Comments:   jsonComments,
-		NumOfLikes: numOfLikes,
-		Liked:      liked,
-		CreatedAt:  post.CreatedAt.Format(time.RFC3339),
-	}
 	// ========================= Send response (including token) ==============================
 	ctx.JSON(http.StatusOK, gin.H{"post": jsonPost, "token": token})
 }
 
+// The fields an author can change with PUT /posts/:id. Anything else in the body is ignored, so
+// counters, moderation and the staleness flag are only ever changed by the code that owns them.
+var editablePostFields = map[string]bool{
+	"question":             true,
+	"answer":               true,
+	"reveal_policy":        true,
+	"reveal_after_correct": true,
+	"reveal_at":            true,
+	"valid_until":          true,
+	"review_after":         true,
+}
+
 func UpdatePost(ctx *gin.Context) {
 	// ======================= Get the post ID from the URL params ==============================
 	postIDParam := ctx.Param("id")
@@ -534,7 +411,7 @@ func UpdatePost(ctx *gin.Context) {
 	}
 
 	// ============================= Get the post from the database =======================================
-	post, err := models.FetchPostByID(uint(postID))
+	post, err := models.FetchPostForViewer(uint(postID), uint(userIDUint))
 	if err != nil {
 		if err.Error() == "record not found" {
 			ctx.JSON(http.StatusNotFound, gin.H{"message": "Post not found"})
@@ -557,6 +434,13 @@ func UpdatePost(ctx *gin.Context) {
 		return
 	}
 
+	// ============================= Only keep the fields an author is allowed to change ==============================
+	for key := range updates {
+		if !editablePostFields[key] {
+			delete(updates, key)
+		}
+	}
+
 	// ============================= Validate question and answer are not blank ==============================
 	if question, exists := updates["question"]; exists {
 		if questionStr, ok := question.(string); ok && len(strings.TrimSpace(questionStr)) == 0 {
@@ -572,13 +456,39 @@ func UpdatePost(ctx *gin.Context) {
 		}
 	}
 
+	// ============================= The answer is locked once there's a bounty on it ==============================
+	if !checkAnswerKeepsBounty(ctx, post, updates) {
+		return
+	}
+
+	// ============================= Validate any changes to the reveal policy ==============================
+	revealed, err := validateRevealPolicyUpdates(post, updates)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+	if !checkRevealPolicyKeepsBounty(ctx, revealed) {
+		return
+	}
+
+	// ============================= Validate any changes to the review dates ==============================
+	if err := validateReviewDateUpdates(updates); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": err.Error()})
+		return
+	}
+
 	// ============================= Update the post in the database ==============================
-	_, err = models.UpdatePost(uint(postID), updates)
+	updatedPost, err := models.UpdatePost(uint(postID), updates)
 	if err != nil {
 		SendInternalError(ctx, err)
 		return
 	}
 
+	// ============================= Tell anyone newly mentioned in the question ==============================
+	if _, exists := updates["question"]; exists {
+		recordMentions(updatedPost.UserID, models.OnPost(updatedPost.ID), updatedPost.ID, updatedPost.Question)
+	}
+
 	// ===================== Send a success message to the frontend (with token) ==================
 	token, _ := auth.GenerateToken(userID)
 	ctx.JSON(http.StatusOK, gin.H{"message": "Post updated successfully", "token": token})
@@ -615,7 +525,7 @@ func DeletePostByID(ctx *gin.Context) {
 	}
 
 	// ======================= Fetch the post by ID ============================================
-	post, err := models.FetchPostByID(uint(postID))
+	post, err := models.FetchPostForViewer(uint(postID), uint(userID))
 	if err != nil {
 		if err.Error() == "record not found" {
 			ctx.JSON(http.StatusNotFound, gin.H{"message": "Post not found"})
@@ -640,3 +550,221 @@ func DeletePostByID(ctx *gin.Context) {
 
 	ctx.JSON(http.StatusOK, gin.H{"message": "Post deleted successfully"})
 }
+
+// ======================== Helper functions for building post JSON ==============================
+
+// toJSONPosts converts a page of posts into JSONPosts for the viewer. Everything that goes
+// alongside the posts is loaded up front by models.LoadPostExtras, so this takes the same
+// number of queries however many posts there are. Every endpoint that sends posts uses it.
+func toJSONPosts(posts []models.Post, viewerID uint) ([]JSONPost, error) {
+	extras, err := models.LoadPostExtras(posts, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	jsonPosts := make([]JSONPost, 0, len(posts))
+	for _, post := range posts {
+		// The author should always exist, but fall back to "Unknown" rather than failing the whole page
+		author := extras.Users[post.UserID]
+		authorUsername := author.Username
+		if authorUsername == "" {
+			authorUsername = "Unknown"
+		}
+
+		jsonComments := make([]PostCommentJSON, 0, len(extras.Comments[post.ID]))
+		for _, comment := range extras.Comments[post.ID] {
+			username := extras.Users[comment.UserID].Username
+			if username == "" {
+				username = "Unknown"
+			}
+			jsonComment := PostCommentJSON{
+				ID:         comment.ID,
+				UserID:     comment.UserID,
+				Username:   username,
+				Contents:   comment.Content,
+				ParentID:   comment.ParentID,
+				Depth:      comment.Depth,
+				ReplyCount: comment.ReplyCount,
+				EditedAt:   comment.EditedAt,
+				Spoiler:    comment.Spoiler,
+				Moderated:  comment.HiddenAt != nil,
+				Mentions:   []JSONMention{},
+				Reactions:  toJSONReactions(extras.CommentReactions[comment.ID]),
+			}
+			// Deleted comments that still have replies are kept as placeholders
+			if comment.IsDeleted() {
+				jsonComment.UserID, jsonComment.Username = 0, ""
+				jsonComment.Contents, jsonComment.Deleted = models.DeletedCommentContent, true
+			} else if extras.IsSpoilerHidden(&post, &comment) {
+				jsonComment.Contents, jsonComment.Hidden = models.HiddenSpoilerContent, true
+			} else {
+				jsonComment.Mentions = toJSONMentions(comment.Content, extras.CommentMentions[comment.ID])
+			}
+			jsonComments = append(jsonComments, jsonComment)
+		}
+
+		// Only show the answer if the reveal policy allows it
+		answer, answerRevealed := "", false
+		if extras.IsAnswerVisible(&post) {
+			answer, answerRevealed = post.Answer, true
+		}
+
+		var bounty *JSONBounty
+		if postBounty, exists := extras.Bounties[post.ID]; exists {
+			bounty = toJSONBounty(&postBounty)
+		}
+
+		jsonPosts = append(jsonPosts, JSONPost{
+			ID:             post.ID,
+			Question:       post.Question,
+			Mentions:       toJSONMentions(post.Question, extras.PostMentions[post.ID]),
+			Answer:         answer,
+			AnswerRevealed: answerRevealed,
+			RevealPolicy:   revealPolicyName(&post),
+			MayBeOutdated:  post.MayBeOutdated(),
+			Moderated:      post.HiddenAt != nil,
+			Bounty:         bounty,
+			UserID:         post.UserID,
+			Username:       authorUsername,
+			User: JSONPostUser{
+				ID:                author.ID,
+				Username:          author.Username,
+				ProfilePictureURL: author.ProfilePictureURL,
+			},
+			Comments:      jsonComments,
+			NumOfLikes:    post.LikeCount,
+			NumOfComments: post.CommentCount,
+			Reactions:     toJSONReactions(extras.PostReactions[post.ID]),
+			Liked:         extras.LikedByViewer[post.ID],
+			CreatedAt:     post.CreatedAt.Format(time.RFC3339),
+		})
+	}
+	return jsonPosts, nil
+}
+
+// ======================== Helper functions for answer reveal policies ==============================
+
+// answerForViewer returns the answer the viewer is allowed to see (blank if it's still hidden)
+// and whether it has been revealed. toJSONPosts does the same check in bulk for pages of posts.
+func answerForViewer(post *models.Post, viewerID uint) (string, bool, error) {
+	visible, err := post.IsAnswerVisibleTo(viewerID)
+	if err != nil {
+		return "", false, err
+	}
+	if !visible {
+		return "", false, nil
+	}
+	return post.Answer, true, nil
+}
+
+// Posts created before reveal policies existed have a blank policy, which means "immediate"
+func revealPolicyName(post *models.Post) string {
+	if post.RevealPolicy == "" {
+		return models.RevealImmediately
+	}
+	return post.RevealPolicy
+}
+
+// The update endpoint takes a map, so we merge the requested changes over the current
+// settings and check the result is still a valid policy. It returns a copy of the post
+// with the reveal settings as they will be after the update.
+func validateRevealPolicyUpdates(post *models.Post, updates map[string]interface{}) (*models.Post, error) {
+	policy := post.RevealPolicy
+	revealAfterCorrect := post.RevealAfterCorrect
+	revealAt := post.RevealAt
+
+	if value, exists := updates["reveal_policy"]; exists {
+		policyStr, ok := value.(string)
+		if !ok {
+			return nil, errors.New("reveal_policy must be a string")
+		}
+		policy = policyStr
+	}
+
+	if value, exists := updates["reveal_after_correct"]; exists {
+		count, ok := value.(float64) // JSON numbers are decoded as float64
+		if !ok {
+			return nil, errors.New("reveal_after_correct must be a number")
+		}
+		revealAfterCorrect = int(count)
+		updates["reveal_after_correct"] = revealAfterCorrect
+	}
+
+	if _, exists := updates["reveal_at"]; exists {
+		parsed, err := parseTimeUpdate(updates, "reveal_at")
+		if err != nil {
+			return nil, err
+		}
+		revealAt = parsed
+	}
+
+	if err := models.ValidateRevealPolicy(policy, revealAfterCorrect, revealAt); err != nil {
+		return nil, err
+	}
+
+	revealed := *post
+	revealed.RevealPolicy, revealed.RevealAfterCorrect, revealed.RevealAt = policy, revealAfterCorrect, revealAt
+	return &revealed, nil
+}
+
+// ======================== Helper functions for answer staleness ==============================
+
+// Checks valid_until / review_after in an update. If the author sets new dates they've reviewed
+// the answer, so the "may be outdated" flag is cleared (the job will flag it again if they've passed).
+func validateReviewDateUpdates(updates map[string]interface{}) error {
+	reviewed := false
+	for _, key := range []string{"valid_until", "review_after"} {
+		if _, exists := updates[key]; exists {
+			if _, err := parseTimeUpdate(updates, key); err != nil {
+				return err
+			}
+			reviewed = true
+		}
+	}
+
+	if reviewed {
+		updates["outdated_flagged_at"] = nil
+	}
+	return nil
+}
+
+// parseTimeUpdate turns an RFC3339 string (or null) in the updates map into a time the
+// database understands, replacing the value in the map as it goes
+func parseTimeUpdate(updates map[string]interface{}, key string) (*time.Time, error) {
+	value := updates[key]
+	if value == nil {
+		return nil, nil
+	}
+
+	valueStr, ok := value.(string)
+	if !ok {
+		return nil, errors.New(key + " must be a date and time")
+	}
+	parsed, err := time.Parse(time.RFC3339, valueStr)
+	if err != nil {
+		return nil, errors.New(key + " must be in RFC3339 format")
+	}
+	updates[key] = parsed
+	return &parsed, nil
+}
+
+// parseFeedSort checks the sort and window query params, turning the window into the
+// earliest creation time to include (nil means all time)
+func parseFeedSort(sort string, window string, now time.Time) (string, *time.Time, bool) {
+	if sort != models.SortTrending && sort != models.SortNew && sort != models.SortTop {
+		return "", nil, false
+	}
+
+	var since time.Time
+	switch window {
+	case "day":
+		since = now.Add(-24 * time.Hour)
+	case "week":
+		since = now.Add(-7 * 24 * time.Hour)
+	case "all":
+		return sort, nil, true
+	default:
+		return "", nil, false
+	}
+	return sort, &since, true
+}
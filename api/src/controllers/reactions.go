@@ -0,0 +1,131 @@
+package controllers
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/makersacademy/go-react-acebook-template/api/src/auth"
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+	"gorm.io/gorm"
+)
+
+type JSONReactions struct {
+	Counts map[string]int `json:"counts"` // how many of each kind, e.g. {"like": 3, "mind_blown": 1}
+	Mine   []string       `json:"mine"`   // the kinds the viewer has left
+}
+
+// ===== PUT /posts/:id/reactions/:kind =====
+// Reacts to a post. Reacting again with the same kind does nothing, so it's safe to retry.
+func ReactToPost(ctx *gin.Context) {
+	setReaction(ctx, reactionOnPost, true)
+}
+
+// ===== DELETE /posts/:id/reactions/:kind =====
+func UnreactToPost(ctx *gin.Context) {
+	setReaction(ctx, reactionOnPost, false)
+}
+
+// ===== PUT /comments/:id/reactions/:kind =====
+// Reacts to a comment. Reacting again with the same kind does nothing, so it's safe to retry.
+func ReactToComment(ctx *gin.Context) {
+	setReaction(ctx, reactionOnComment, true)
+}
+
+// ===== DELETE /comments/:id/reactions/:kind =====
+func UnreactToComment(ctx *gin.Context) {
+	setReaction(ctx, reactionOnComment, false)
+}
+
+// ======================== Helper functions for reactions ==============================
+
+// reactionOnPost checks the post exists (and the user can see it) and returns it as a reaction target
+func reactionOnPost(id uint, userID uint) (models.ReactionTarget, error) {
+	_, err := models.FetchPostForViewer(id, userID)
+	return models.OnPost(id), err
+}
+
+// reactionOnComment checks the comment exists (and the user can see it) and returns it as a reaction target
+func reactionOnComment(id uint, userID uint) (models.ReactionTarget, error) {
+	_, err := models.FetchCommentForViewer(id, userID)
+	return models.OnComment(id), err
+}
+
+// setReaction adds (or takes back) the current user's reaction and sends back the updated reactions
+func setReaction(ctx *gin.Context, findTarget func(id uint, userID uint) (models.ReactionTarget, error), reacted bool) {
+	// ========== Get the post or comment ID and reaction kind from the URL ==========
+	id, err := strconv.ParseUint(ctx.Param("id"), 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid ID"})
+		return
+	}
+	kind := ctx.Param("kind")
+	if !models.IsReactionKind(kind) {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Unknown reaction", "kinds": models.ReactionKinds})
+		return
+	}
+
+	// ========== Get the user ID from the context ==========
+	val, _ := ctx.Get("userID")
+	userID := val.(string)
+	userIDUint, err := strconv.ParseUint(userID, 10, 32)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	// ========== Check what's being reacted to exists (and the user can see it) ==========
+	target, err := findTarget(uint(id), uint(userIDUint))
+	if err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"message": "Not found"})
+			return
+		}
+		SendInternalError(ctx, err)
+		return
+	}
+
+	// ========== Add or take back the reaction ==========
+	// Likes on posts still reward the author, however they're left
+	isPostLike := target.IsPost() && kind == models.ReactionLike
+	if reacted {
+		reaction, created, err := models.React(uint(userIDUint), target, kind)
+		if err != nil {
+			SendInternalError(ctx, err)
+			return
+		}
+		if created && isPostLike {
+			afterLikeCreated(reaction)
+		}
+	} else {
+		reaction, err := models.Unreact(uint(userIDUint), target, kind)
+		if err != nil {
+			SendInternalError(ctx, err)
+			return
+		}
+		if reaction != nil && isPostLike {
+			afterLikeRemoved(reaction)
+		}
+	}
+
+	// ========== Send back the updated reactions (w/ token) ==========
+	summaries, err := models.FetchReactionSummaries([]models.ReactionTarget{target}, uint(userIDUint))
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+	token, _ := auth.GenerateToken(userID)
+	ctx.JSON(http.StatusOK, gin.H{"reactions": toJSONReactions(summaries[target.ID]), "token": token})
+}
+
+func toJSONReactions(summary models.ReactionSummary) JSONReactions {
+	reactions := JSONReactions{Counts: summary.Counts, Mine: summary.Mine}
+	if reactions.Counts == nil {
+		reactions.Counts = map[string]int{}
+	}
+	if reactions.Mine == nil {
+		reactions.Mine = []string{}
+	}
+	return reactions
+}
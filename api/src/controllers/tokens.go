@@ -3,6 +3,7 @@ package controllers
 import (
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/makersacademy/go-react-acebook-template/api/src/auth"
@@ -51,6 +52,12 @@ func CreateToken(ctx *gin.Context) {
 		return
 	}
 
+	// Suspended users can't sign in until the suspension is over
+	if user.IsSuspended(time.Now()) {
+		ctx.JSON(http.StatusForbidden, gin.H{"message": "Your account is suspended", "suspended_until": user.SuspendedUntil})
+		return
+	}
+
 	token, err := auth.GenerateToken(fmt.Sprintf("%d", user.ID))
 	if err != nil {
 		SendInternalError(ctx, err)
@@ -2,6 +2,7 @@ package controllers
 
 import (
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
@@ -15,6 +16,7 @@ import (
 	"github.com/makersacademy/go-react-acebook-template/api/src/auth"
 	"github.com/makersacademy/go-react-acebook-template/api/src/models"
 	"github.com/makersacademy/go-react-acebook-template/api/src/passwordhashing"
+	"gorm.io/gorm"
 )
 
 func CreateUser(ctx *gin.Context) {
@@ -45,6 +47,13 @@ func CreateUser(ctx *gin.Context) {
 		return
 	}
 
+	// ============================ Give the new user their starting points =======================
+	err = models.AddPoints(models.Database, newUser.ID, models.StartingPoints, models.PointsStartingBalance, "user", newUser.ID)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
 	// =========================== Send a success message to the frontend =========================
 	// No need to send a token as the user is not logged in yet
 	ctx.JSON(http.StatusCreated, gin.H{"message": "OK"})
@@ -109,6 +118,10 @@ func UpdateUser(ctx *gin.Context) {
 		}
 	}
 
+	// =================== Users can't change their own role, or lift their own suspension ===================
+	delete(updates, "role")
+	delete(updates, "suspended_until")
+
 	// ============================= Update the user in the database ==============================
 	_, err = models.UpdateUser(uint(userID), updates)
 	if err != nil {
@@ -169,6 +182,13 @@ func GetCurrentUser(ctx *gin.Context) {
 	tokenUserID := val.(string)
 	token, _ := auth.GenerateToken(tokenUserID)
 
+	// Get the user's XP, level and streak
+	progress, err := userProgress(user.ID)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
 	// Create user data map
 	userData := gin.H{
 		"ID":             user.ID,
@@ -178,7 +198,9 @@ func GetCurrentUser(ctx *gin.Context) {
 		"surname":        user.Surname,
 		"bio":            user.Bio,
 		"profilePicture": profilePictureBase64,
+		"role":           user.Role,
 		"Posts":          user.Posts,
+		"progress":       progress,
 	}
 
 	// Return user data and token in the requested format
@@ -314,10 +336,38 @@ func convertImageToBase64(imagePath string) (string, error) {
 // This function gets a user's profile information from the user_id
 func GetUserByID(ctx *gin.Context) {
 	userID := ctx.Param("id")
+	if _, err := strconv.ParseUint(userID, 10, 32); err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid user ID"})
+		return
+	}
 	profile, err := models.FindUser(userID)
 	if err != nil {
-		ctx.JSON(http.StatusBadRequest, gin.H{"message": "User ID not found"})
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			ctx.JSON(http.StatusNotFound, gin.H{"message": "User not found"})
+			return
+		}
+		SendInternalError(ctx, err)
+		return
+	}
+
+	// Someone you've blocked (or who has blocked you) doesn't have a profile as far as you're concerned
+	val, _ := ctx.Get("userID")
+	viewerIDString := val.(string)
+	viewerID, err := strconv.ParseUint(viewerIDString, 10, 32)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
 	}
+	blocked, err := models.IsBlockedBetween(uint(viewerID), profile.ID)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+	if blocked {
+		ctx.JSON(http.StatusNotFound, gin.H{"message": "User not found"})
+		return
+	}
+
 	var friendProfilePictureBase64 string
 	// Convert profile picture path to base64 if it exists
 	if profile.ProfilePictureURL != "" {
@@ -328,16 +378,44 @@ func GetUserByID(ctx *gin.Context) {
 		}
 	}
 
-	val, _ := ctx.Get("userID")
-	userID = val.(string)
-	token, _ := auth.GenerateToken(userID)
+	token, _ := auth.GenerateToken(viewerIDString)
+
+	// Get the user's XP, level and streak
+	progress, err := userProgress(profile.ID)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	// Get the badges the user has earned
+	badges, err := userBadges(profile.ID)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	// Count who follows the user and who they follow (the lists are paged by GET /users/:id/followers and /following)
+	followerCount, followingCount, err := models.CountFollows(profile.ID)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+	isFollowing, err := models.IsFollowing(uint(viewerID), profile.ID)
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
 
 	profileData := gin.H{
 		"ID":             profile.ID,
 		"username":       profile.Username,
 		"bio":            profile.Bio,
 		"profilePicture": friendProfilePictureBase64,
-		"Posts":          profile.Posts,
+		"progress":       progress,
+		"badges":         badges,
+		"followerCount":  followerCount,
+		"followingCount": followingCount,
+		"isFollowing":    isFollowing,
 	}
 
 	ctx.JSON(http.StatusOK, gin.H{"user": profileData, "token": token})
@@ -0,0 +1,123 @@
+package controllers
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/makersacademy/go-react-acebook-template/api/src/auth"
+	"github.com/makersacademy/go-react-acebook-template/api/src/env"
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+)
+
+type JSONXPEntry struct {
+	ID         uint   `json:"_id"`
+	Amount     int    `json:"amount"`
+	Reason     string `json:"reason"`
+	SourceType string `json:"source_type"`
+	SourceID   uint   `json:"source_id"`
+	CreatedAt  string `json:"created_at"`
+}
+
+// Returns a user's XP ledger so they can see where their XP came from
+func GetXPHistoryByUserID(ctx *gin.Context) {
+	// ========== Get the user ID from the URL params ==========
+	userIDParam := ctx.Param("id")
+	userID, err := strconv.ParseUint(userIDParam, 10, 32)
+	if err != nil {
+		ctx.JSON(http.StatusBadRequest, gin.H{"message": "Invalid user ID"})
+		return
+	}
+
+	// ========== Fetch the ledger ==========
+	entries, err := models.FetchXPEntriesByUserID(uint(userID))
+	if err != nil {
+		SendInternalError(ctx, err)
+		return
+	}
+
+	// ========== Get the user ID from the context ==========
+	val, _ := ctx.Get("userID")
+	token, _ := auth.GenerateToken(val.(string))
+
+	// ========== Convert the entries to JSON Structs ==========
+	total := 0
+	jsonEntries := make([]JSONXPEntry, 0)
+	for _, entry := range *entries {
+		total += entry.Amount
+		jsonEntries = append(jsonEntries, JSONXPEntry{
+			ID:         entry.ID,
+			Amount:     entry.Amount,
+			Reason:     entry.Reason,
+			SourceType: entry.SourceType,
+			SourceID:   entry.SourceID,
+			CreatedAt:  entry.CreatedAt.Format(time.RFC3339),
+		})
+	}
+
+	// ========== Send the response (w/ token) ==========
+	ctx.JSON(http.StatusOK, gin.H{"xp_history": jsonEntries, "xp": total, "token": token})
+}
+
+// ======================== Helper functions for XP and levels ==============================
+
+// awardXP adds to a user's XP ledger. XP is a bonus on top of the real action, so if it
+// fails we just log the error rather than failing the whole request.
+func awardXP(userID uint, reason string, sourceType string, sourceID uint) {
+	awardXPAmount(userID, models.XPAmounts[reason], reason, sourceType, sourceID)
+}
+
+func awardXPAmount(userID uint, amount int, reason string, sourceType string, sourceID uint) {
+	if err := models.AwardXP(userID, amount, reason, sourceType, sourceID); err != nil {
+		fmt.Printf("Error awarding %s XP to user %d: %v\n", reason, userID, err)
+	}
+}
+
+// awardDailyStreakXP gives a bonus for finishing today's daily question that grows with the streak
+func awardDailyStreakXP(userID uint, dailyAttemptID uint) {
+	streak, err := models.CalculateDailyStreak(userID, time.Now())
+	if err != nil {
+		fmt.Printf("Error calculating daily streak for user %d: %v\n", userID, err)
+		return
+	}
+	amount := models.XPAmounts[models.XPDailyStreak] * min(streak, models.MaxDailyStreakMultiplier)
+	awardXPAmount(userID, amount, models.XPDailyStreak, "daily", dailyAttemptID)
+}
+
+// The level curve can be tuned with XP_LEVEL_BASE and XP_LEVEL_GROWTH in the .env file
+func levelCurve() models.LevelCurve {
+	return models.LevelCurve{
+		Base:   env.GetFloat("XP_LEVEL_BASE", 100),
+		Growth: env.GetFloat("XP_LEVEL_GROWTH", 1.5),
+	}
+}
+
+// userProgress returns the XP, level, points and daily streak shown on a user's profile
+func userProgress(userID uint) (gin.H, error) {
+	total, err := models.TotalXPForUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	streak, err := models.CalculateDailyStreak(userID, time.Now())
+	if err != nil {
+		return nil, err
+	}
+
+	points, err := models.PointsBalanceForUser(models.Database, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	level, levelProgress, levelTarget := levelCurve().LevelForXP(total)
+	return gin.H{
+		"points":        points,
+		"xp":            total,
+		"level":         level,
+		"levelProgress": levelProgress,
+		"levelTarget":   levelTarget,
+		"streak":        streak,
+	}, nil
+}
@@ -2,6 +2,9 @@ package env
 
 import (
 	"log"
+	"os"
+	"strconv"
+	"time"
 
 	"github.com/joho/godotenv"
 )
@@ -14,3 +17,33 @@ func LoadEnv(filenames ...string) {
 
 	log.Println(".env file successfully loaded")
 }
+
+// GetInt reads a whole number setting from the environment,
+// falling back to the default if it is missing or not a number
+func GetInt(name string, defaultValue int) int {
+	value, err := strconv.Atoi(os.Getenv(name))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// GetDuration reads a setting like "72h" or "15m" from the environment,
+// falling back to the default if it is missing or can't be parsed
+func GetDuration(name string, defaultValue time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(name))
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
+
+// GetFloat reads a decimal setting like "1.5" from the environment,
+// falling back to the default if it is missing or not a number
+func GetFloat(name string, defaultValue float64) float64 {
+	value, err := strconv.ParseFloat(os.Getenv(name), 64)
+	if err != nil {
+		return defaultValue
+	}
+	return value
+}
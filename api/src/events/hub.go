@@ -0,0 +1,222 @@
+// Package events is an in-process publish/subscribe hub for pushing things that happen
+// (new comments, likes, notifications) to connected clients as they happen.
+// Events are published to topics, e.g. one per user, and anyone subscribed to the topic gets them.
+package events
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// An Event is something that happened. IDs only ever go up, so a client that reconnects can
+// say which event it saw last and be sent the ones it missed.
+type Event struct {
+	ID   uint64                 `json:"id"`
+	Type string                 `json:"type"`
+	Data map[string]interface{} `json:"data"`
+}
+
+// Event types
+const (
+	CommentCreated      = "comment.created"
+	CommentDeleted      = "comment.deleted"
+	LikeCreated         = "like.created"
+	LikeDeleted         = "like.deleted"
+	MessageCreated      = "message.created"
+	NotificationCreated = "notification.created"
+	Presence            = "presence" // how many people are looking at a post
+	Resync              = "resync"   // sent when some events were missed and can't be replayed
+)
+
+// UserTopic is for things the user should hear about wherever they are
+func UserTopic(userID uint) string {
+	return fmt.Sprintf("user:%d", userID)
+}
+
+// PostTopic is for everyone looking at the post
+func PostTopic(postID uint) string {
+	return fmt.Sprintf("post:%d", postID)
+}
+
+// A Subscription receives the events published to its topic. If it falls too far behind
+// (the client isn't reading) it's dropped and Events is closed, so the client can reconnect.
+type Subscription struct {
+	Topic  string
+	UserID uint // who subscribed
+	events chan Event
+}
+
+func (subscription *Subscription) Events() <-chan Event {
+	return subscription.events
+}
+
+type publishedEvent struct {
+	Event
+	topics []string
+}
+
+// A Hub keeps track of subscriptions and the most recent events (so they can be replayed)
+type Hub struct {
+	mutex         sync.Mutex
+	nextID        uint64
+	recent        []publishedEvent // oldest first
+	recentSize    int
+	bufferSize    int
+	subscriptions map[string]map[*Subscription]bool
+}
+
+// NewHub makes a hub that remembers the last recentSize events for replaying, and lets each
+// subscription fall up to bufferSize events behind before it's dropped
+func NewHub(recentSize int, bufferSize int) *Hub {
+	return &Hub{
+		// Start from the time so IDs from before a restart are always lower than new ones
+		nextID:        uint64(time.Now().UnixMicro()),
+		recentSize:    recentSize,
+		bufferSize:    bufferSize,
+		subscriptions: map[string]map[*Subscription]bool{},
+	}
+}
+
+// Default is the hub the models publish to
+var Default = NewHub(1000, 64)
+
+// Publish sends an event to everyone subscribed to any of the topics
+func Publish(eventType string, data map[string]interface{}, topics ...string) {
+	Default.Publish(eventType, data, topics...)
+}
+
+func (hub *Hub) Publish(eventType string, data map[string]interface{}, topics ...string) Event {
+	return hub.publish(eventType, data, topics, true)
+}
+
+// PublishTransient sends an event that's only useful right now (like a presence count),
+// so it isn't kept for replaying to clients that reconnect
+func (hub *Hub) PublishTransient(eventType string, data map[string]interface{}, topics ...string) Event {
+	return hub.publish(eventType, data, topics, false)
+}
+
+func (hub *Hub) publish(eventType string, data map[string]interface{}, topics []string, keep bool) Event {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	event := Event{ID: hub.nextID, Type: eventType, Data: data}
+	hub.nextID++
+
+	if keep {
+		hub.recent = append(hub.recent, publishedEvent{Event: event, topics: topics})
+		if len(hub.recent) > hub.recentSize {
+			hub.recent = hub.recent[len(hub.recent)-hub.recentSize:]
+		}
+	}
+
+	sent := map[*Subscription]bool{}
+	for _, topic := range topics {
+		for subscription := range hub.subscriptions[topic] {
+			if sent[subscription] {
+				continue
+			}
+			sent[subscription] = true
+
+			// Never block publishing on a slow client. Drop it instead, and it can catch up by reconnecting.
+			select {
+			case subscription.events <- event:
+			default:
+				hub.remove(subscription)
+			}
+		}
+	}
+	return event
+}
+
+// Subscribe starts receiving events published to the topic. If lastEventID is set (the client is
+// reconnecting) the events it missed are returned too. complete is false if some of them are too
+// old to replay, in which case the client should reload what it's showing.
+func (hub *Hub) Subscribe(topic string, userID uint, lastEventID uint64) (subscription *Subscription, missed []Event, complete bool) {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	subscription = &Subscription{Topic: topic, UserID: userID, events: make(chan Event, hub.bufferSize)}
+	if hub.subscriptions[topic] == nil {
+		hub.subscriptions[topic] = map[*Subscription]bool{}
+	}
+	hub.subscriptions[topic][subscription] = true
+
+	if lastEventID == 0 {
+		return subscription, nil, true
+	}
+
+	oldestHeld := hub.nextID
+	if len(hub.recent) > 0 {
+		oldestHeld = hub.recent[0].ID
+	}
+	for _, event := range hub.recent {
+		if event.ID > lastEventID && hasTopic(event.topics, topic) {
+			missed = append(missed, event.Event)
+		}
+	}
+	return subscription, missed, lastEventID+1 >= oldestHeld
+}
+
+// Unsubscribe stops the subscription. It's safe to call more than once.
+func (hub *Hub) Unsubscribe(subscription *Subscription) {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+	hub.remove(subscription)
+}
+
+// SubscriberCount is how many subscriptions there are to the topic
+func (hub *Hub) SubscriberCount(topic string) int {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+	return len(hub.subscriptions[topic])
+}
+
+// SubscriberCountForUser is how many subscriptions the user has to the topic
+func (hub *Hub) SubscriberCountForUser(topic string, userID uint) int {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	count := 0
+	for subscription := range hub.subscriptions[topic] {
+		if subscription.UserID == userID {
+			count++
+		}
+	}
+	return count
+}
+
+// UserCount is how many different users are subscribed to the topic
+// (someone with the same post open in two tabs only counts once)
+func (hub *Hub) UserCount(topic string) int {
+	hub.mutex.Lock()
+	defer hub.mutex.Unlock()
+
+	users := map[uint]bool{}
+	for subscription := range hub.subscriptions[topic] {
+		users[subscription.UserID] = true
+	}
+	return len(users)
+}
+
+// remove must be called with the mutex held
+func (hub *Hub) remove(subscription *Subscription) {
+	subscriptions := hub.subscriptions[subscription.Topic]
+	if !subscriptions[subscription] {
+		return
+	}
+	delete(subscriptions, subscription)
+	if len(subscriptions) == 0 {
+		delete(hub.subscriptions, subscription.Topic)
+	}
+	close(subscription.events)
+}
+
+func hasTopic(topics []string, topic string) bool {
+	for _, eventTopic := range topics {
+		if eventTopic == topic {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,25 @@
+package jobs
+
+import (
+	"time"
+
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+)
+
+// ExpireBounties closes bounties nobody answered in time, awarding them to the author
+func ExpireBounties() error {
+	now := time.Now()
+
+	bounties, err := models.FetchExpiredOpenBounties(now)
+	if err != nil {
+		return err
+	}
+
+	for _, bounty := range *bounties {
+		if err := models.AwardExpiredBountyToAuthor(bounty.ID, now); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
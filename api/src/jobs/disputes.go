@@ -0,0 +1,48 @@
+package jobs
+
+import (
+	"errors"
+	"time"
+
+	"github.com/makersacademy/go-react-acebook-template/api/src/env"
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+)
+
+// ResolveIgnoredDisputes lets the community decide disputes that the author hasn't
+// dealt with in time. A dispute needs a minimum number of votes before it's decided;
+// a majority in favour adds the proposed answer as an alternate, otherwise it's rejected.
+func ResolveIgnoredDisputes() error {
+	authorWindow := env.GetDuration("DISPUTE_AUTHOR_WINDOW", 72*time.Hour)
+	minimumVotes := int64(env.GetInt("DISPUTE_MIN_VOTES", 3))
+
+	disputes, err := models.FetchOpenDisputesCreatedBefore(time.Now().Add(-authorWindow))
+	if err != nil {
+		return err
+	}
+
+	for _, dispute := range *disputes {
+		votesFor, votesAgainst, err := models.CountDisputeVotes(dispute.ID)
+		if err != nil {
+			return err
+		}
+
+		// Not enough people have voted yet, so leave it open
+		if votesFor+votesAgainst < minimumVotes {
+			continue
+		}
+
+		if votesFor > votesAgainst {
+			// Adding an alternate is safer than replacing the author's answer outright
+			err = models.AcceptDispute(dispute.ID, models.ResolutionAlternate, true)
+		} else {
+			err = models.RejectDispute(dispute.ID, "Rejected by community vote", true)
+		}
+
+		// Someone may have resolved it while we were counting, which is fine
+		if err != nil && !errors.Is(err, models.ErrDisputeNotOpen) {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,32 @@
+package jobs
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/makersacademy/go-react-acebook-template/api/src/env"
+)
+
+// Start kicks off the background jobs. Each job runs on its own ticker
+// for as long as the server is running.
+func Start() {
+	go runEvery(time.Hour, "resolve ignored disputes", ResolveIgnoredDisputes)
+	go runEvery(time.Hour, "flag outdated posts", FlagOutdatedPosts)
+	go runEvery(5*time.Minute, "expire bounties", ExpireBounties)
+	go runEvery(10*time.Minute, "roll over league seasons", RolloverSeasons)
+	go runEvery(env.GetDuration("TRENDING_INTERVAL", 10*time.Minute), "recompute trending scores", RecomputeTrendingScores)
+}
+
+// runEvery runs the job straight away and then again after every interval.
+// Errors are logged rather than stopping the job, so one bad run doesn't kill it.
+func runEvery(interval time.Duration, name string, job func() error) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if err := job(); err != nil {
+			fmt.Printf("Background job %q failed: %v\n", name, err)
+		}
+		<-ticker.C
+	}
+}
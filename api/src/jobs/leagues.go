@@ -0,0 +1,38 @@
+package jobs
+
+import (
+	"time"
+
+	"github.com/makersacademy/go-react-acebook-template/api/src/env"
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+)
+
+// LeagueSettings reads the division size and promotion/relegation count from the .env file
+func LeagueSettings() models.LeagueSettings {
+	return models.LeagueSettings{
+		DivisionSize: env.GetInt("LEAGUE_DIVISION_SIZE", 20),
+		MoveCount:    env.GetInt("LEAGUE_MOVE_COUNT", 3),
+	}
+}
+
+// RolloverSeasons closes any season that has ended (promoting and relegating its members into the
+// next one) and makes sure the current season exists. Closing a season is a single transaction
+// that skips seasons already closed, so the job can safely re-run after a crash.
+func RolloverSeasons() error {
+	now := time.Now()
+
+	seasons, err := models.FetchSeasonsToClose(now)
+	if err != nil {
+		return err
+	}
+
+	// Oldest first, so each season's results feed into the next one
+	for _, season := range *seasons {
+		if err := models.CloseSeason(season.ID, LeagueSettings(), now); err != nil {
+			return err
+		}
+	}
+
+	_, err = models.EnsureSeason(models.Database, now)
+	return err
+}
@@ -0,0 +1,44 @@
+package jobs
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+)
+
+// FlagOutdatedPosts finds posts whose valid_until or review_after date has passed,
+// flags them as "may be outdated" and lets the author know they need a look.
+func FlagOutdatedPosts() error {
+	now := time.Now()
+
+	posts, err := models.FetchExpiredUnflaggedPosts(now)
+	if err != nil {
+		return err
+	}
+
+	for _, post := range *posts {
+		flagged, err := models.FlagPostAsOutdated(post.ID, now)
+		if err != nil {
+			return err
+		}
+
+		// Another run got there first, so the author has already been told
+		if !flagged {
+			continue
+		}
+
+		postID := post.ID
+		notification := models.Notification{
+			UserID:  post.UserID,
+			Kind:    models.NotificationPostOutdated,
+			Message: fmt.Sprintf("Your question %q may be outdated. Please check the answer is still correct.", post.Question),
+			PostID:  &postID,
+		}
+		if _, err := notification.Save(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
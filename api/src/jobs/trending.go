@@ -0,0 +1,24 @@
+package jobs
+
+import (
+	"time"
+
+	"github.com/makersacademy/go-react-acebook-template/api/src/env"
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+)
+
+// TrendingWeights reads how much likes, comments and attempts count for, and how fast
+// scores decay with age, from the .env file
+func TrendingWeights() models.TrendingWeights {
+	return models.TrendingWeights{
+		Like:    env.GetFloat("TRENDING_LIKE_WEIGHT", 1),
+		Comment: env.GetFloat("TRENDING_COMMENT_WEIGHT", 2),
+		Attempt: env.GetFloat("TRENDING_ATTEMPT_WEIGHT", 0.5),
+		Gravity: env.GetFloat("TRENDING_GRAVITY", 1.8),
+	}
+}
+
+// RecomputeTrendingScores refreshes the precomputed score for every post
+func RecomputeTrendingScores() error {
+	return models.RecomputePostScores(TrendingWeights(), time.Now())
+}
@@ -1,11 +1,15 @@
 package middleware
 
 import (
+	"errors"
 	"fmt"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/makersacademy/go-react-acebook-template/api/src/auth"
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+	"gorm.io/gorm"
 )
 
 func AuthenticationMiddleware(ctx *gin.Context) {
@@ -14,10 +18,36 @@ func AuthenticationMiddleware(ctx *gin.Context) {
 
 	if err != nil {
 		fmt.Println(err)
-		ctx.JSON(http.StatusUnauthorized, gin.H{"message": "auth error"})
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "auth error"})
+		return
+	}
+
+	if !checkAccount(ctx, token.UserID) {
 		return
 	}
 
 	ctx.Set("userID", token.UserID)
 	ctx.Next()
 }
+
+// checkAccount makes sure the token's user still exists, and stops suspended users changing
+// anything (they can still look around with a token they already had). If the check can't be
+// made the request is refused rather than let through. It sends the error response itself.
+func checkAccount(ctx *gin.Context, userID string) bool {
+	suspendedUntil, err := models.FindSuspension(userID, time.Now())
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "auth error"})
+		return false
+	}
+	if err != nil {
+		fmt.Println(err)
+		ctx.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"err": "Something went wrong"})
+		return false
+	}
+
+	if suspendedUntil != nil && ctx.Request.Method != http.MethodGet {
+		ctx.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "Your account is suspended", "suspended_until": suspendedUntil})
+		return false
+	}
+	return true
+}
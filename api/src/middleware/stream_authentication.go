@@ -0,0 +1,34 @@
+package middleware
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/makersacademy/go-react-acebook-template/api/src/auth"
+)
+
+// StreamAuthenticationMiddleware is AuthenticationMiddleware for long lived connections (event
+// streams and websockets). Browsers can't set headers on those, so the token can also be sent
+// as ?token= in the URL.
+func StreamAuthenticationMiddleware(ctx *gin.Context) {
+	tokenString := strings.TrimPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+	tokenString = strings.TrimPrefix(tokenString, "bearer ")
+	if tokenString == "" {
+		tokenString = ctx.Query("token")
+	}
+
+	token, err := auth.DecodeToken(tokenString)
+	if err != nil {
+		fmt.Println(err)
+		ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "auth error"})
+		return
+	}
+	if !checkAccount(ctx, token.UserID) {
+		return
+	}
+
+	ctx.Set("userID", token.UserID)
+	ctx.Next()
+}
@@ -0,0 +1,173 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// An Attempt is a user's guess at the answer to a post. AnswerSeen is set if the post was
+// already showing the user its answer when they guessed, so a correct guess earns nothing.
+type Attempt struct {
+	gorm.Model
+	PostID     uint   `json:"post_id" gorm:"index"`
+	UserID     uint   `json:"user_id" gorm:"index;constraint:OnDelete:CASCADE"`
+	Guess      string `json:"guess"`
+	Correct    bool   `json:"correct"`
+	AnswerSeen bool   `json:"answer_seen" gorm:"not null;default:false"`
+	Post       Post   `json:"-"`
+	User       User   `json:"-"`
+}
+
+// An AnswerChange records a post's accepted answers changing, either because the author edited
+// the answer or a dispute was accepted. Attempts are re-scored against the new answers, and any
+// XP, points or bounty that moves as a result is booked against the change in the ledgers.
+type AnswerChange struct {
+	gorm.Model
+	PostID    uint   `json:"post_id" gorm:"index"`
+	DisputeID *uint  `json:"dispute_id"` // nil if the author edited the answer themselves
+	Answer    string `json:"answer"`     // the new answer, or the alternate answer that was added
+}
+
+func (attempt *Attempt) Save() (*Attempt, error) {
+	err := Database.Create(attempt).Error
+	if err != nil {
+		return &Attempt{}, err
+	}
+	return attempt, nil
+}
+
+func FetchAttemptsByPostID(postID uint) (*[]Attempt, error) {
+	var attempts []Attempt
+	err := Database.Where("post_id = ?", postID).Order("created_at").Find(&attempts).Error
+	if err != nil {
+		return &[]Attempt{}, err
+	}
+	return &attempts, nil
+}
+
+// Checks whether the user has made at least one attempt at the post
+func HasUserAttemptedPost(userID uint, postID uint) (bool, error) {
+	var count int64
+	err := Database.Model(&Attempt{}).Where("user_id = ? AND post_id = ?", userID, postID).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// Checks whether the user has already answered the post correctly
+func HasUserAnsweredCorrectly(userID uint, postID uint) (bool, error) {
+	var count int64
+	err := Database.Model(&Attempt{}).Where("user_id = ? AND post_id = ? AND correct = ?", userID, postID, true).Count(&count).Error
+	if err != nil {
+		return false, err
+	}
+	return count > 0, nil
+}
+
+// Counts how many different users have answered the post correctly
+func CountCorrectUsersForPost(postID uint) (int64, error) {
+	var count int64
+	err := Database.Model(&Attempt{}).Where("post_id = ? AND correct = ?", postID, true).Distinct("user_id").Count(&count).Error
+	if err != nil {
+		return 0, err
+	}
+	return count, nil
+}
+
+// RescoreAttemptsForPost re-marks every attempt at a post against its current accepted answers,
+// and records the change. Anyone who has now earned (or lost) the reward for answering gets it
+// (or gives it back), and the bounty moves to whoever should have won it.
+// It takes a transaction so it can run alongside the change that made re-scoring necessary.
+func RescoreAttemptsForPost(tx *gorm.DB, postID uint, change *AnswerChange, now time.Time) error {
+	var post Post
+	if err := tx.First(&post, postID).Error; err != nil {
+		return err
+	}
+
+	answers, err := post.AcceptedAnswers(tx)
+	if err != nil {
+		return err
+	}
+
+	change.PostID = postID
+	if err := tx.Create(change).Error; err != nil {
+		return err
+	}
+
+	var attempts []Attempt
+	if err := tx.Where("post_id = ?", postID).Order("created_at, id").Find(&attempts).Error; err != nil {
+		return err
+	}
+
+	earnedBefore := firstEarningAttempts(attempts)
+	for index := range attempts {
+		attempt := &attempts[index]
+		correct := isCorrectForAny(attempt.Guess, answers)
+		if correct == attempt.Correct {
+			continue // nothing has changed for this attempt
+		}
+		if err := tx.Model(attempt).Update("correct", correct).Error; err != nil {
+			return err
+		}
+		attempt.Correct = correct
+	}
+	earnedAfter := firstEarningAttempts(attempts)
+
+	// ========== Give or take back the reward for answering ==========
+	for userID := range earnedBefore {
+		if _, stillEarned := earnedAfter[userID]; !stillEarned {
+			if err := moveAnswerReward(tx, userID, -1, change.ID); err != nil {
+				return err
+			}
+		}
+	}
+	for userID := range earnedAfter {
+		if _, alreadyEarned := earnedBefore[userID]; !alreadyEarned {
+			if err := moveAnswerReward(tx, userID, 1, change.ID); err != nil {
+				return err
+			}
+		}
+	}
+
+	return rescoreBounty(tx, postID, attempts, change.ID, now)
+}
+
+// firstEarningAttempts finds each user's first correct attempt made without the answer on show,
+// keyed by user. attempts must be oldest first.
+func firstEarningAttempts(attempts []Attempt) map[uint]Attempt {
+	earning := map[uint]Attempt{}
+	for _, attempt := range attempts {
+		if _, exists := earning[attempt.UserID]; !exists && attempt.Correct && !attempt.AnswerSeen {
+			earning[attempt.UserID] = attempt
+		}
+	}
+	return earning
+}
+
+// moveAnswerReward gives (direction 1) or takes back (direction -1) the XP and points for
+// answering correctly, booked against the answer change
+func moveAnswerReward(tx *gorm.DB, userID uint, direction int, changeID uint) error {
+	xp := XPEntry{UserID: userID, Amount: direction * XPAmounts[XPCorrectAnswer], Reason: XPAnswerRescored, SourceType: "answer_change", SourceID: changeID}
+	if err := tx.Create(&xp).Error; err != nil {
+		return err
+	}
+	return AddPoints(tx, userID, direction*CorrectAnswerPoints, PointsAnswerRescored, "answer_change", changeID)
+}
+
+// AnswerRewardSource is what a new reward for answering the post correctly is booked against:
+// the post itself, or its latest answer change if it has one. Someone can only earn the reward
+// once per version of the answer, as re-scoring takes it back if the answer changes under them.
+func AnswerRewardSource(db *gorm.DB, postID uint) (string, uint, error) {
+	var change AnswerChange
+	err := db.Where("post_id = ?", postID).Order("id DESC").First(&change).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "post", postID, nil
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	return "answer_change", change.ID, nil
+}
@@ -0,0 +1,90 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// A UserBadge records that a user has earned an achievement. The badge details
+// (name, description, rule) live in the achievements package, keyed by BadgeCode.
+type UserBadge struct {
+	gorm.Model
+	UserID    uint      `json:"user_id" gorm:"uniqueIndex:idx_user_badges_user_badge;constraint:OnDelete:CASCADE"`
+	BadgeCode string    `json:"badge_code" gorm:"size:50;uniqueIndex:idx_user_badges_user_badge"`
+	AwardedAt time.Time `json:"awarded_at"`
+}
+
+// GrantBadge gives the user a badge. Granting a badge they already have does nothing.
+func GrantBadge(userID uint, badgeCode string, awardedAt time.Time) error {
+	badge := UserBadge{UserID: userID, BadgeCode: badgeCode, AwardedAt: awardedAt}
+	return Database.Clauses(clause.OnConflict{DoNothing: true}).Create(&badge).Error
+}
+
+func FetchBadgesByUserID(userID uint) (*[]UserBadge, error) {
+	var badges []UserBadge
+	err := Database.Where("user_id = ?", userID).Order("awarded_at").Find(&badges).Error
+	if err != nil {
+		return &[]UserBadge{}, err
+	}
+	return &badges, nil
+}
+
+// Fetches the IDs of every user, used when backfilling badges
+func FetchAllUserIDs() ([]uint, error) {
+	var ids []uint
+	err := Database.Model(&User{}).Order("id").Pluck("id", &ids).Error
+	return ids, err
+}
+
+// ======================== Stats used by achievement rules ==============================
+
+func CountPostsByUserID(userID uint) (int64, error) {
+	var count int64
+	err := Database.Model(&Post{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+func CountCommentsByUserID(userID uint) (int64, error) {
+	var count int64
+	err := Database.Model(&Comment{}).Where("user_id = ?", userID).Count(&count).Error
+	return count, err
+}
+
+// The most likes any one of the user's posts has received
+func CountMostLikesOnUserPost(userID uint) (int64, error) {
+	var count int64
+	err := Database.Model(&Post{}).Where("user_id = ?", userID).Select("COALESCE(MAX(like_count), 0)").Scan(&count).Error
+	return count, err
+}
+
+// The number of different users who have attempted the user's questions but never got one right
+func CountUsersStumpedByUser(userID uint) (int64, error) {
+	var count int64
+	err := Database.Raw(`SELECT COUNT(*) FROM (
+			SELECT attempts.user_id FROM attempts
+			JOIN posts ON posts.id = attempts.post_id AND posts.deleted_at IS NULL
+			WHERE posts.user_id = ? AND attempts.deleted_at IS NULL
+			GROUP BY attempts.user_id
+			HAVING NOT BOOL_OR(attempts.correct)
+		) AS stumped`, userID).Scan(&count).Error
+	return count, err
+}
+
+// The longest run of correct attempts the user has made without a wrong guess in between.
+// Numbering each attempt by how many wrong guesses came before it puts every run of correct
+// answers in its own group, so the database can count them without sending back every attempt.
+func CountLongestCorrectStreak(userID uint) (int64, error) {
+	var longest int64
+	err := Database.Raw(`SELECT COALESCE(MAX(streak), 0) FROM (
+			SELECT COUNT(*) AS streak FROM (
+				SELECT correct, COUNT(*) FILTER (WHERE NOT correct) OVER (ORDER BY created_at, id) AS wrong_before
+				FROM attempts
+				WHERE user_id = ? AND deleted_at IS NULL
+			) AS numbered
+			WHERE correct
+			GROUP BY wrong_before
+		) AS streaks`, userID).Scan(&longest).Error
+	return longest, err
+}
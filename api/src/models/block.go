@@ -0,0 +1,103 @@
+package models
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// A Block means BlockerID doesn't want anything to do with BlockedID. It works both ways:
+// neither of them sees the other's posts and comments, or can comment on, like or message them.
+type Block struct {
+	gorm.Model
+	BlockerID uint `json:"blocker_id" gorm:"uniqueIndex:idx_blocks_blocker_blocked;constraint:OnDelete:CASCADE"`
+	BlockedID uint `json:"blocked_id" gorm:"uniqueIndex:idx_blocks_blocker_blocked;index;constraint:OnDelete:CASCADE"`
+	Blocker   User `json:"-"`
+	Blocked   User `json:"-"`
+}
+
+// A Mute means MuterID doesn't want to see MutedID's posts and comments. Unlike a block it only
+// works one way, and nothing changes for the muted user (they aren't told).
+type Mute struct {
+	gorm.Model
+	MuterID uint `json:"muter_id" gorm:"uniqueIndex:idx_mutes_muter_muted;constraint:OnDelete:CASCADE"`
+	MutedID uint `json:"muted_id" gorm:"uniqueIndex:idx_mutes_muter_muted;index;constraint:OnDelete:CASCADE"`
+	Muter   User `json:"-"`
+	Muted   User `json:"-"`
+}
+
+// BlockUser blocks someone, and stops either of them following the other. Blocking someone
+// you've already blocked does nothing. It returns true if this created a new block.
+func BlockUser(blockerID uint, blockedID uint) (bool, error) {
+	blocked := false
+	err := Database.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&Block{BlockerID: blockerID, BlockedID: blockedID})
+		if result.Error != nil {
+			return result.Error
+		}
+		blocked = result.RowsAffected > 0
+
+		return tx.Unscoped().
+			Where("(follower_id = ? AND followee_id = ?) OR (follower_id = ? AND followee_id = ?)", blockerID, blockedID, blockedID, blockerID).
+			Delete(&Follow{}).Error
+	})
+	return blocked, err
+}
+
+// UnblockUser removes a block. The row is removed for good (rather than soft deleted)
+// so the unique index doesn't stop them blocking again later.
+func UnblockUser(blockerID uint, blockedID uint) error {
+	return Database.Unscoped().Where("blocker_id = ? AND blocked_id = ?", blockerID, blockedID).Delete(&Block{}).Error
+}
+
+// MuteUser mutes someone. Muting someone you've already muted does nothing.
+func MuteUser(muterID uint, mutedID uint) error {
+	return Database.Clauses(clause.OnConflict{DoNothing: true}).Create(&Mute{MuterID: muterID, MutedID: mutedID}).Error
+}
+
+// UnmuteUser removes a mute for good, like UnblockUser
+func UnmuteUser(muterID uint, mutedID uint) error {
+	return Database.Unscoped().Where("muter_id = ? AND muted_id = ?", muterID, mutedID).Delete(&Mute{}).Error
+}
+
+// IsBlockedBetween is true if either user has blocked the other
+func IsBlockedBetween(userID uint, otherUserID uint) (bool, error) {
+	return isBlockedWithAny(Database, userID, []uint{otherUserID})
+}
+
+// isBlockedWithAny is true if the user has blocked, or been blocked by, any of the others
+func isBlockedWithAny(db *gorm.DB, userID uint, otherUserIDs []uint) (bool, error) {
+	if len(otherUserIDs) == 0 {
+		return false, nil
+	}
+	var count int64
+	err := db.Model(&Block{}).
+		Where("(blocker_id = ? AND blocked_id IN ?) OR (blocked_id = ? AND blocker_id IN ?)", userID, otherUserIDs, userID, otherUserIDs).
+		Count(&count).Error
+	return count > 0, err
+}
+
+// Fetches the users the given user has blocked, most recent first
+func FetchBlockedUsers(userID uint) (*[]User, error) {
+	var users []User
+	err := Database.Joins("JOIN blocks ON blocks.blocked_id = users.id AND blocks.deleted_at IS NULL").
+		Where("blocks.blocker_id = ?", userID).
+		Order("blocks.created_at DESC").
+		Find(&users).Error
+	if err != nil {
+		return &[]User{}, err
+	}
+	return &users, nil
+}
+
+// Fetches the users the given user has muted, most recent first
+func FetchMutedUsers(userID uint) (*[]User, error) {
+	var users []User
+	err := Database.Joins("JOIN mutes ON mutes.muted_id = users.id AND mutes.deleted_at IS NULL").
+		Where("mutes.muter_id = ?", userID).
+		Order("mutes.created_at DESC").
+		Find(&users).Error
+	if err != nil {
+		return &[]User{}, err
+	}
+	return &users, nil
+}
@@ -0,0 +1,315 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Bounty statuses
+const (
+	BountyOpen      = "open"       // nobody has answered correctly yet and there's still time
+	BountyAnswered  = "answered"   // the first correct answerer won the bounty
+	BountyAuthorWon = "author_won" // nobody answered correctly in time, so the author won
+	BountyCancelled = "cancelled"  // the post was deleted or hidden while it was open, so the author got their stake back
+)
+
+// A Bounty is a points prize an author puts on their own question.
+// If someone answers correctly before it expires, the first of them wins the author's stake.
+// If nobody does, the author gets their stake back and wins the same amount again.
+type Bounty struct {
+	gorm.Model
+	PostID     uint       `json:"post_id" gorm:"uniqueIndex"`
+	UserID     uint       `json:"user_id" gorm:"constraint:OnDelete:CASCADE"`
+	Amount     int        `json:"amount"`
+	ExpiresAt  time.Time  `json:"expires_at" gorm:"index"`
+	Status     string     `json:"status" gorm:"size:20;default:open;index"`
+	WinnerID   *uint      `json:"winner_id"`
+	ResolvedAt *time.Time `json:"resolved_at"`
+}
+
+var (
+	ErrNotEnoughPoints     = errors.New("not enough points")
+	ErrBountyAlreadyExists = errors.New("post already has a bounty")
+	ErrAlreadyAnswered     = errors.New("post has already been answered correctly")
+)
+
+// CreateBounty puts a bounty on a post, taking the stake from the author's points in the same transaction
+func CreateBounty(postID uint, authorID uint, amount int, expiresAt time.Time) (*Bounty, error) {
+	var bounty Bounty
+
+	err := Database.Transaction(func(tx *gorm.DB) error {
+		// Lock the author so two bounties can't spend the same points
+		var author User
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&author, authorID).Error; err != nil {
+			return err
+		}
+
+		balance, err := PointsBalanceForUser(tx, authorID)
+		if err != nil {
+			return err
+		}
+		if balance < amount {
+			return ErrNotEnoughPoints
+		}
+
+		// A bounty on a question someone has already got right would be won straight away
+		var correctAttempts int64
+		if err := tx.Model(&Attempt{}).Where("post_id = ? AND correct = ?", postID, true).Count(&correctAttempts).Error; err != nil {
+			return err
+		}
+		if correctAttempts > 0 {
+			return ErrAlreadyAnswered
+		}
+
+		var existing int64
+		if err := tx.Unscoped().Model(&Bounty{}).Where("post_id = ?", postID).Count(&existing).Error; err != nil {
+			return err
+		}
+		if existing > 0 {
+			return ErrBountyAlreadyExists
+		}
+
+		bounty = Bounty{PostID: postID, UserID: authorID, Amount: amount, ExpiresAt: expiresAt, Status: BountyOpen}
+		if err := tx.Create(&bounty).Error; err != nil {
+			return err
+		}
+
+		return AddPoints(tx, authorID, -amount, PointsBountyEscrow, "bounty", bounty.ID)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return &bounty, nil
+}
+
+func FetchBountyByPostID(postID uint) (*Bounty, error) {
+	var bounty Bounty
+	err := Database.Where("post_id = ?", postID).First(&bounty).Error
+	if err != nil {
+		return nil, err
+	}
+	return &bounty, nil
+}
+
+// ClaimBounty pays an open, unexpired bounty to the user who just answered correctly.
+// The bounty row is locked, so only the first correct answerer can win it.
+// It returns the bounty if this user won it, or nil if there was nothing to win.
+func ClaimBounty(postID uint, userID uint, now time.Time) (*Bounty, error) {
+	var bounty Bounty
+	won := false
+
+	err := Database.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("post_id = ? AND status = ? AND expires_at > ?", postID, BountyOpen, now).
+			First(&bounty).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil // no open bounty on this post
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&bounty).Updates(map[string]interface{}{
+			"status":      BountyAnswered,
+			"winner_id":   userID,
+			"resolved_at": now,
+		}).Error; err != nil {
+			return err
+		}
+
+		won = true
+		sourceType, sourceID, err := bountyPaymentSource(tx, &bounty)
+		if err != nil {
+			return err
+		}
+		return AddPoints(tx, userID, bounty.Amount, PointsBountyWon, sourceType, sourceID)
+	})
+	if err != nil || !won {
+		return nil, err
+	}
+	return &bounty, nil
+}
+
+// Fetches open bounties whose window has closed. Bounties on deleted or hidden posts are left
+// out, as those are cancelled rather than won.
+func FetchExpiredOpenBounties(now time.Time) (*[]Bounty, error) {
+	var bounties []Bounty
+	err := Database.Joins("JOIN posts ON posts.id = bounties.post_id AND posts.deleted_at IS NULL AND posts.hidden_at IS NULL").
+		Where("bounties.status = ? AND bounties.expires_at <= ?", BountyOpen, now).
+		Find(&bounties).Error
+	if err != nil {
+		return &[]Bounty{}, err
+	}
+	return &bounties, nil
+}
+
+// AwardExpiredBountyToAuthor closes a bounty nobody won in time: the author gets their stake
+// back and wins the same amount. It does nothing if the bounty has already been resolved.
+func AwardExpiredBountyToAuthor(id uint, now time.Time) error {
+	return Database.Transaction(func(tx *gorm.DB) error {
+		var bounty Bounty
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("id = ? AND status = ? AND expires_at <= ?", id, BountyOpen, now).
+			First(&bounty).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil // someone won it, or another run already closed it
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Model(&bounty).Updates(map[string]interface{}{
+			"status":      BountyAuthorWon,
+			"winner_id":   bounty.UserID,
+			"resolved_at": now,
+		}).Error; err != nil {
+			return err
+		}
+
+		sourceType, sourceID, err := bountyPaymentSource(tx, &bounty)
+		if err != nil {
+			return err
+		}
+		if err := AddPoints(tx, bounty.UserID, bounty.Amount, PointsBountyRefund, sourceType, sourceID); err != nil {
+			return err
+		}
+		return AddPoints(tx, bounty.UserID, bounty.Amount, PointsBountyWon, sourceType, sourceID)
+	})
+}
+
+// cancelBounty gives the author their stake back if the post has an open bounty, without anyone
+// winning it. It's called when the post is deleted or hidden, so nobody can still answer it.
+func cancelBounty(tx *gorm.DB, postID uint, now time.Time) error {
+	var bounty Bounty
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("post_id = ? AND status = ?", postID, BountyOpen).
+		First(&bounty).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil // no open bounty on this post
+	}
+	if err != nil {
+		return err
+	}
+
+	if err := tx.Model(&bounty).Updates(map[string]interface{}{
+		"status":      BountyCancelled,
+		"resolved_at": now,
+	}).Error; err != nil {
+		return err
+	}
+
+	sourceType, sourceID, err := bountyPaymentSource(tx, &bounty)
+	if err != nil {
+		return err
+	}
+	return AddPoints(tx, bounty.UserID, bounty.Amount, PointsBountyRefund, sourceType, sourceID)
+}
+
+// bountyPaymentSource is what paying out the bounty is booked against: the bounty itself, or the
+// latest answer change since it was put up. Re-scoring can reopen a bounty after it was paid, so
+// this lets it be paid again (to whoever wins it next) without clashing with the first payment.
+func bountyPaymentSource(tx *gorm.DB, bounty *Bounty) (string, uint, error) {
+	var change AnswerChange
+	err := tx.Where("post_id = ? AND created_at >= ?", bounty.PostID, bounty.CreatedAt).Order("id DESC").First(&change).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "bounty", bounty.ID, nil
+	}
+	if err != nil {
+		return "", 0, err
+	}
+	return "answer_change", change.ID, nil
+}
+
+// rescoreBounty moves the post's bounty to whoever should have won it now attempts have been
+// re-scored: the first person to work out the answer after it was put up and before it expired.
+// If nobody did, it's reopened, or goes to the author if it has already expired. Any points that
+// move are booked against the answer change. attempts must be oldest first.
+func rescoreBounty(tx *gorm.DB, postID uint, attempts []Attempt, changeID uint, now time.Time) error {
+	var bounty Bounty
+	err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("post_id = ? AND status <> ?", postID, BountyCancelled).First(&bounty).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil // no bounty, or it was cancelled and the stake has already gone back
+	}
+	if err != nil {
+		return err
+	}
+
+	// ========== Work out who should have it now ==========
+	var winnerID *uint
+	for _, attempt := range attempts {
+		if attempt.Correct && !attempt.AnswerSeen && !attempt.CreatedAt.Before(bounty.CreatedAt) && attempt.CreatedAt.Before(bounty.ExpiresAt) {
+			winnerID = &attempt.UserID
+			break
+		}
+	}
+
+	status, resolvedAt := BountyAnswered, &now
+	switch {
+	case winnerID != nil:
+	case bounty.Status == BountyOpen:
+		return nil // nobody has won it yet, and still nobody has
+	case now.Before(bounty.ExpiresAt):
+		status, resolvedAt = BountyOpen, nil
+	default:
+		status, winnerID = BountyAuthorWon, &bounty.UserID
+	}
+	if status == bounty.Status && sameUser(winnerID, bounty.WinnerID) {
+		return nil
+	}
+
+	// ========== Take the prize back from whoever had it, and give it to whoever should ==========
+	prizes, refunds := map[uint]int{}, map[uint]int{}
+	if bounty.Status != BountyOpen && bounty.WinnerID != nil {
+		prizes[*bounty.WinnerID] -= bounty.Amount
+		if bounty.Status == BountyAuthorWon {
+			refunds[bounty.UserID] -= bounty.Amount
+		}
+	}
+	if status != BountyOpen {
+		prizes[*winnerID] += bounty.Amount
+		if status == BountyAuthorWon {
+			refunds[bounty.UserID] += bounty.Amount
+		}
+	}
+	for userID, amount := range prizes {
+		if amount == 0 {
+			continue
+		}
+		if err := AddPoints(tx, userID, amount, PointsBountyRescored, "answer_change", changeID); err != nil {
+			return err
+		}
+	}
+	for userID, amount := range refunds {
+		if amount == 0 {
+			continue
+		}
+		if err := AddPoints(tx, userID, amount, PointsRefundRescored, "answer_change", changeID); err != nil {
+			return err
+		}
+	}
+
+	return tx.Model(&bounty).Updates(map[string]interface{}{
+		"status":      status,
+		"winner_id":   winnerID,
+		"resolved_at": resolvedAt,
+	}).Error
+}
+
+func sameUser(a *uint, b *uint) bool {
+	return (a == nil && b == nil) || (a != nil && b != nil && *a == *b)
+}
+
+// Fetches a page of posts that have an open bounty, newest post first
+func FetchPostsWithOpenBounty(now time.Time, viewerID uint, page Page) (*[]Post, PageInfo, error) {
+	query := Database.Model(&Post{}).Scopes(listedFor(viewerID, "posts")).
+		Joins("JOIN bounties ON bounties.post_id = posts.id AND bounties.deleted_at IS NULL").
+		Where("bounties.status = ? AND bounties.expires_at > ?", BountyOpen, now)
+	posts, info, err := fetchPage(query, page, keyset{table: "posts"}, postCursor)
+	if err != nil {
+		return &[]Post{}, PageInfo{}, err
+	}
+	return &posts, info, nil
+}
@@ -1,23 +1,113 @@
 package models
 
 import (
+	"time"
+
+	"github.com/makersacademy/go-react-acebook-template/api/src/answercheck"
+	"github.com/makersacademy/go-react-acebook-template/api/src/events"
 	"gorm.io/gorm"
 )
 
+// A Comment is either on a post (ParentID is nil) or a reply to another comment on the same post.
+// Depth is 0 for comments on the post, 1 for replies to those, and so on.
+// ReplyCount is how many replies are still in the thread. A deleted comment that still has
+// replies stays in the thread as a "[deleted]" placeholder, so its replies make sense.
 type Comment struct {
 	gorm.Model
-	Content string `json:"content"`
-	PostID  uint   `json:"post_id"`
-	UserID  uint   `json:"user_id" gorm:"constraint:OnDelete:CASCADE"`
-	Post    Post   `json:"-"`
-	User    User   `json:"-"`
+	Content    string     `json:"content"`
+	PostID     uint       `json:"post_id"`
+	UserID     uint       `json:"user_id" gorm:"constraint:OnDelete:CASCADE"`
+	ParentID   *uint      `json:"parent_id" gorm:"index"`
+	Depth      int        `json:"depth" gorm:"not null;default:0"`
+	ReplyCount int        `json:"reply_count" gorm:"not null;default:0"`
+	EditedAt   *time.Time `json:"edited_at"`                             // set when the author last edited the comment
+	Spoiler    bool       `json:"spoiler" gorm:"not null;default:false"` // tagged as a spoiler by the author
+	HiddenAt   *time.Time `json:"hidden_at"`                             // set when a moderator hides the comment. Only its author can still see it
+	Post       Post       `json:"-"`
+	User       User       `json:"-"`
+}
+
+// Placeholder shown instead of the content of a deleted comment that still has replies
+const DeletedCommentContent = "[deleted]"
+
+func (comment *Comment) IsDeleted() bool {
+	return comment.DeletedAt.Valid
+}
+
+// Shown instead of the content of a spoiler to people who haven't seen the answer yet
+const HiddenSpoilerContent = "[spoiler hidden until you've seen the answer]"
+
+// GivesAwayAnswer is true if the author tagged the comment as a spoiler,
+// or it contains the whole of one of the post's accepted answers (allowing for typos)
+func (comment *Comment) GivesAwayAnswer(answers []string) bool {
+	if comment.Spoiler {
+		return true
+	}
+	for _, answer := range answers {
+		if answercheck.Contains(comment.Content, answer) {
+			return true
+		}
+	}
+	return false
+}
+
+// isSpoilerHidden decides whether a comment should be hidden from a viewer. People who can see
+// the answer see everything, and the comment's author always sees their own comment.
+func (comment *Comment) isSpoilerHidden(viewerID uint, answerVisible bool, answers []string) bool {
+	return !answerVisible && comment.UserID != viewerID && comment.GivesAwayAnswer(answers)
+}
+
+// FindHiddenSpoilers returns the IDs of the comments on a post that give away the answer
+// to a viewer who hasn't seen it yet
+func FindHiddenSpoilers(postID uint, comments []Comment, viewerID uint) (map[uint]bool, error) {
+	hidden := map[uint]bool{}
+	if len(comments) == 0 {
+		return hidden, nil
+	}
+
+	post, err := FetchPostByID(postID)
+	if err != nil {
+		return nil, err
+	}
+	answerVisible, err := post.IsAnswerVisibleTo(viewerID)
+	if err != nil || answerVisible {
+		return hidden, err
+	}
+	answers, err := post.AcceptedAnswers(Database)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, comment := range comments {
+		if comment.isSpoilerHidden(viewerID, answerVisible, answers) {
+			hidden[comment.ID] = true
+		}
+	}
+	return hidden, nil
+}
+
+// visibleComments includes deleted comments that still have replies, so threads stay in one piece
+func visibleComments(db *gorm.DB) *gorm.DB {
+	return db.Unscoped().Where("comments.deleted_at IS NULL OR comments.reply_count > 0")
 }
 
 func (comment *Comment) Save() (*Comment, error) {
-	err := Database.Create(comment).Error
+	err := Database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(comment).Error; err != nil {
+			return err
+		}
+		if comment.ParentID != nil {
+			if err := adjustReplyCount(tx, *comment.ParentID, 1); err != nil {
+				return err
+			}
+		}
+		return adjustPostCounter(tx, comment.PostID, "comment_count", 1)
+	})
 	if err != nil {
 		return &Comment{}, err
 	}
+
+	publishCommentEvent(events.CommentCreated, comment)
 	return comment, nil
 }
 
@@ -30,6 +120,22 @@ func FetchCommentsByPostID(postID uint) (*[]Comment, error) {
 	return &comments, nil
 }
 
+// Fetches one page of a post's comments and replies as a flat list, oldest first. Replies are
+// always newer than what they reply to, so a reply never comes on an earlier page than its parent.
+// If parentID is set, only the direct replies to that comment are fetched. Comments the viewer
+// shouldn't see (see listedFor) are left out.
+func FetchCommentsPageByPostID(postID uint, parentID *uint, viewerID uint, page Page) (*[]Comment, PageInfo, error) {
+	query := visibleComments(Database.Model(&Comment{})).Scopes(listedFor(viewerID, "comments")).Where("comments.post_id = ?", postID)
+	if parentID != nil {
+		query = query.Where("comments.parent_id = ?", *parentID)
+	}
+	comments, info, err := fetchPage(query, page, keyset{table: "comments", ascending: true}, commentCursor)
+	if err != nil {
+		return &[]Comment{}, PageInfo{}, err
+	}
+	return &comments, info, nil
+}
+
 func FetchCommentByID(id uint) (*Comment, error) {
 	var comment Comment
 	err := Database.First(&comment, id).Error
@@ -41,16 +147,62 @@ func FetchCommentByID(id uint) (*Comment, error) {
 
 func DeleteCommentByID(id uint) error {
 	var comment Comment
+	deleted := false
+
+	err := Database.Transaction(func(tx *gorm.DB) error {
+		// Find the comment
+		if err := tx.First(&comment, id).Error; err != nil {
+			return err
+		}
 
-	// Find the comment
-	if err := Database.First(&comment, id).Error; err != nil {
+		// Delete the comment record from the database
+		result := tx.Delete(&comment)
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		deleted = true
+
+		// If it has replies it stays in the thread as a placeholder. If not, it's gone, so take it
+		// off its parent's replies, and take away any deleted ancestors left with no replies too.
+		if comment.ReplyCount == 0 {
+			if err := removeFromThread(tx, comment.ParentID); err != nil {
+				return err
+			}
+		}
+
+		return adjustPostCounter(tx, comment.PostID, "comment_count", -1)
+	})
+	if err != nil {
 		return err
 	}
 
-	// Delete the comment record from the database
-	if err := Database.Delete(&comment).Error; err != nil {
-		return err
+	if deleted {
+		publishCommentEvent(events.CommentDeleted, &comment)
 	}
+	return nil
+}
+
+func adjustReplyCount(tx *gorm.DB, commentID uint, change int) error {
+	return tx.Unscoped().Model(&Comment{}).Where("id = ?", commentID).
+		UpdateColumn("reply_count", gorm.Expr("reply_count + ?", change)).Error
+}
 
+// removeFromThread takes a reply that has left the thread off its parent's reply count, working
+// up the thread while the parents are deleted placeholders that no longer have any replies
+func removeFromThread(tx *gorm.DB, parentID *uint) error {
+	for parentID != nil {
+		if err := adjustReplyCount(tx, *parentID, -1); err != nil {
+			return err
+		}
+
+		var parent Comment
+		if err := tx.Unscoped().First(&parent, *parentID).Error; err != nil {
+			return err
+		}
+		if !parent.IsDeleted() || parent.ReplyCount > 0 {
+			return nil
+		}
+		parentID = parent.ParentID
+	}
 	return nil
 }
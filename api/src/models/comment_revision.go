@@ -0,0 +1,53 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// A CommentRevision keeps what a comment said before it was edited.
+// CreatedAt is when that version was replaced.
+type CommentRevision struct {
+	gorm.Model
+	CommentID uint   `json:"comment_id" gorm:"index;constraint:OnDelete:CASCADE"`
+	Content   string `json:"content"`
+}
+
+// EditComment replaces the comment's content, keeping the old content as a revision
+func EditComment(id uint, content string, now time.Time) (*Comment, error) {
+	var comment Comment
+	err := Database.Transaction(func(tx *gorm.DB) error {
+		// Lock the comment so two edits at once can't both save the same old version
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&comment, id).Error; err != nil {
+			return err
+		}
+		if comment.Content == content {
+			return nil
+		}
+
+		revision := CommentRevision{CommentID: comment.ID, Content: comment.Content}
+		if err := tx.Create(&revision).Error; err != nil {
+			return err
+		}
+
+		comment.Content = content
+		comment.EditedAt = &now
+		return tx.Model(&comment).Updates(map[string]interface{}{"content": content, "edited_at": now}).Error
+	})
+	if err != nil {
+		return &Comment{}, err
+	}
+	return &comment, nil
+}
+
+// Fetches the earlier versions of a comment, oldest first
+func FetchCommentRevisions(commentID uint) (*[]CommentRevision, error) {
+	var revisions []CommentRevision
+	err := Database.Where("comment_id = ?", commentID).Order("created_at, id").Find(&revisions).Error
+	if err != nil {
+		return &[]CommentRevision{}, err
+	}
+	return &revisions, nil
+}
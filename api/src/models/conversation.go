@@ -0,0 +1,271 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var (
+	ErrBlocked        = errors.New("blocked")
+	ErrMemberNotFound = errors.New("user not found")
+	ErrNotAMember     = errors.New("not a member of the conversation")
+)
+
+// A Conversation is a private chat between two people, or a small group. There's only ever one
+// chat between the same two people: DirectKey is "smallerID:largerID" for those, and nil for groups.
+// UpdatedAt is bumped by every message, and the conversation list is ordered by it.
+type Conversation struct {
+	gorm.Model
+	CreatedByID uint                 `json:"created_by_id"`
+	Title       string               `json:"title" gorm:"size:100"`
+	DirectKey   *string              `json:"-" gorm:"size:30;uniqueIndex"`
+	Members     []ConversationMember `json:"-"`
+	CreatedBy   User                 `json:"-"`
+}
+
+// A ConversationMember is someone in a conversation. LastReadMessageID is the newest message
+// they've read, so anything after it (that they didn't send) is unread.
+type ConversationMember struct {
+	ID                uint       `gorm:"primarykey"`
+	CreatedAt         time.Time  `json:"created_at"`
+	ConversationID    uint       `json:"conversation_id" gorm:"uniqueIndex:idx_conversation_members_conversation_user;constraint:OnDelete:CASCADE"`
+	UserID            uint       `json:"user_id" gorm:"uniqueIndex:idx_conversation_members_conversation_user;index;constraint:OnDelete:CASCADE"`
+	LastReadMessageID uint       `json:"last_read_message_id" gorm:"not null;default:0"`
+	LastReadAt        *time.Time `json:"last_read_at"`
+	User              User       `json:"-"`
+}
+
+type Message struct {
+	gorm.Model
+	ConversationID uint         `json:"conversation_id" gorm:"index;constraint:OnDelete:CASCADE"`
+	SenderID       uint         `json:"sender_id"`
+	Content        string       `json:"content"`
+	Conversation   Conversation `json:"-"`
+	Sender         User         `json:"-"`
+}
+
+func directKey(userID uint, otherUserID uint) string {
+	if userID > otherUserID {
+		userID, otherUserID = otherUserID, userID
+	}
+	return fmt.Sprintf("%d:%d", userID, otherUserID)
+}
+
+// StartConversation starts a conversation between the creator and the other members (who
+// shouldn't include the creator, or anyone twice). With only one other member it's a direct
+// conversation, and if the two of them already have one that's returned instead, with created false.
+// It fails with ErrMemberNotFound if one of the members doesn't exist, and ErrBlocked if the
+// creator has blocked (or been blocked by) any of them.
+func StartConversation(creatorID uint, memberIDs []uint, title string) (*Conversation, bool, error) {
+	var count int64
+	if err := Database.Model(&User{}).Where("id IN ?", memberIDs).Count(&count).Error; err != nil {
+		return nil, false, err
+	}
+	if int(count) != len(memberIDs) {
+		return nil, false, ErrMemberNotFound
+	}
+
+	blocked, err := isBlockedWithAny(Database, creatorID, memberIDs)
+	if err != nil {
+		return nil, false, err
+	}
+	if blocked {
+		return nil, false, ErrBlocked
+	}
+
+	conversation := Conversation{CreatedByID: creatorID, Title: title}
+	if len(memberIDs) == 1 {
+		key := directKey(creatorID, memberIDs[0])
+		conversation.DirectKey, conversation.Title = &key, ""
+	}
+
+	created := false
+	err = Database.Transaction(func(tx *gorm.DB) error {
+		// Two people starting a chat with each other at the same time get the same one
+		result := tx.Clauses(clause.OnConflict{Columns: []clause.Column{{Name: "direct_key"}}, DoNothing: true}).Create(&conversation)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return tx.Where("direct_key = ?", *conversation.DirectKey).First(&conversation).Error
+		}
+
+		created = true
+		members := []ConversationMember{{ConversationID: conversation.ID, UserID: creatorID}}
+		for _, memberID := range memberIDs {
+			members = append(members, ConversationMember{ConversationID: conversation.ID, UserID: memberID})
+		}
+		return tx.Create(&members).Error
+	})
+	if err != nil {
+		return nil, false, err
+	}
+	return &conversation, created, nil
+}
+
+// FetchConversationForMember fetches the conversation, with its members, if the user is in it.
+// If they aren't it fails with gorm.ErrRecordNotFound, the same as if it didn't exist.
+func FetchConversationForMember(conversationID uint, userID uint) (*Conversation, error) {
+	var conversation Conversation
+	err := Database.Preload("Members.User").
+		Where("id = ? AND id IN (?)", conversationID, Database.Model(&ConversationMember{}).Select("conversation_id").Where("user_id = ?", userID)).
+		First(&conversation).Error
+	if err != nil {
+		return &Conversation{}, err
+	}
+	return &conversation, nil
+}
+
+// OtherMemberIDs is everyone in the conversation except the user
+func (conversation *Conversation) OtherMemberIDs(userID uint) []uint {
+	ids := []uint{}
+	for _, member := range conversation.Members {
+		if member.UserID != userID {
+			ids = append(ids, member.UserID)
+		}
+	}
+	return ids
+}
+
+// Member is the user's membership of the conversation, or nil if they aren't in it
+func (conversation *Conversation) Member(userID uint) *ConversationMember {
+	for index := range conversation.Members {
+		if conversation.Members[index].UserID == userID {
+			return &conversation.Members[index]
+		}
+	}
+	return nil
+}
+
+// SendMessage adds a message to the conversation, which counts as the sender having read it.
+// It fails with ErrNotAMember if the sender isn't in the conversation, and ErrBlocked if
+// they've blocked (or been blocked by) anyone else in it.
+func SendMessage(conversation *Conversation, senderID uint, content string) (*Message, error) {
+	if conversation.Member(senderID) == nil {
+		return nil, ErrNotAMember
+	}
+
+	message := Message{ConversationID: conversation.ID, SenderID: senderID, Content: content}
+	err := Database.Transaction(func(tx *gorm.DB) error {
+		// Checked in the transaction so a block that's just been made is seen
+		blocked, err := isBlockedWithAny(tx, senderID, conversation.OtherMemberIDs(senderID))
+		if err != nil {
+			return err
+		}
+		if blocked {
+			return ErrBlocked
+		}
+
+		if err := tx.Create(&message).Error; err != nil {
+			return err
+		}
+		// Moves the conversation to the top of everyone's list
+		if err := tx.Model(&Conversation{}).Where("id = ?", conversation.ID).UpdateColumn("updated_at", message.CreatedAt).Error; err != nil {
+			return err
+		}
+		return tx.Model(&ConversationMember{}).
+			Where("conversation_id = ? AND user_id = ?", conversation.ID, senderID).
+			Updates(map[string]interface{}{"last_read_message_id": message.ID, "last_read_at": message.CreatedAt}).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	message.Conversation = *conversation
+	publishMessageEvent(&message)
+	return &message, nil
+}
+
+// Conversations are listed by when they last had a message (or were started). updated_at is
+// ranked on as a whole number of microseconds, which the cursor can store exactly.
+var conversationKeys = keyset{table: "conversations", rank: "(EXTRACT(EPOCH FROM conversations.updated_at) * 1000000)::bigint"}
+
+func conversationCursor(conversation *Conversation) (Cursor, error) {
+	rank := float64(conversation.UpdatedAt.UnixMicro())
+	return Cursor{CreatedAt: conversation.CreatedAt, ID: conversation.ID, Rank: &rank}, nil
+}
+
+func messageCursor(message *Message) (Cursor, error) {
+	return Cursor{CreatedAt: message.CreatedAt, ID: message.ID}, nil
+}
+
+// Fetches a page of the user's conversations, with their members, most recently active first
+func FetchConversationsPage(userID uint, page Page) (*[]Conversation, PageInfo, error) {
+	query := Database.Model(&Conversation{}).Preload("Members.User").
+		Where("conversations.id IN (?)", Database.Model(&ConversationMember{}).Select("conversation_id").Where("user_id = ?", userID))
+	conversations, info, err := fetchPage(query, page, conversationKeys, conversationCursor)
+	if err != nil {
+		return &[]Conversation{}, PageInfo{}, err
+	}
+	return &conversations, info, nil
+}
+
+// Fetches a page of the conversation's messages, newest first
+func FetchMessagesPage(conversationID uint, page Page) (*[]Message, PageInfo, error) {
+	query := Database.Model(&Message{}).Preload("Sender").Where("messages.conversation_id = ?", conversationID)
+	messages, info, err := fetchPage(query, page, keyset{table: "messages"}, messageCursor)
+	if err != nil {
+		return &[]Message{}, PageInfo{}, err
+	}
+	return &messages, info, nil
+}
+
+// FetchLastMessages loads the newest message in each of the conversations, keyed by conversation ID
+func FetchLastMessages(conversationIDs []uint) (map[uint]Message, error) {
+	lastMessages := map[uint]Message{}
+	if len(conversationIDs) == 0 {
+		return lastMessages, nil
+	}
+
+	var messages []Message
+	err := Database.Preload("Sender").
+		Where("id IN (?)", Database.Model(&Message{}).Select("MAX(id)").Where("conversation_id IN ?", conversationIDs).Group("conversation_id")).
+		Find(&messages).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, message := range messages {
+		lastMessages[message.ConversationID] = message
+	}
+	return lastMessages, nil
+}
+
+// CountUnreadMessages counts the messages the user hasn't read in each of the conversations,
+// keyed by conversation ID. Their own messages don't count.
+func CountUnreadMessages(userID uint, conversationIDs []uint) (map[uint]int64, error) {
+	counts := map[uint]int64{}
+	if len(conversationIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		ConversationID uint
+		Unread         int64
+	}
+	err := Database.Model(&ConversationMember{}).
+		Select("conversation_members.conversation_id, COUNT(messages.id) AS unread").
+		Joins("JOIN messages ON messages.conversation_id = conversation_members.conversation_id AND messages.deleted_at IS NULL "+
+			"AND messages.id > conversation_members.last_read_message_id AND messages.sender_id <> conversation_members.user_id").
+		Where("conversation_members.user_id = ? AND conversation_members.conversation_id IN ?", userID, conversationIDs).
+		Group("conversation_members.conversation_id").
+		Scan(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		counts[row.ConversationID] = row.Unread
+	}
+	return counts, nil
+}
+
+// MarkConversationRead marks every message in the conversation as read by the user
+func MarkConversationRead(conversationID uint, userID uint, now time.Time) error {
+	latest := Database.Model(&Message{}).Select("COALESCE(MAX(id), 0)").Where("conversation_id = ?", conversationID)
+	return Database.Model(&ConversationMember{}).
+		Where("conversation_id = ? AND user_id = ?", conversationID, userID).
+		Updates(map[string]interface{}{"last_read_message_id": latest, "last_read_at": now}).Error
+}
@@ -0,0 +1,74 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// adjustPostCounter adds to (or takes away from) one of a post's counters. It's done in SQL
+// rather than read, add and save, so two likes at the same moment can't overwrite each other.
+func adjustPostCounter(tx *gorm.DB, postID uint, column string, change int) error {
+	return tx.Model(&Post{}).Where("id = ?", postID).
+		UpdateColumn(column, gorm.Expr(column+" + ?", change)).Error
+}
+
+// A CounterDrift is a post whose stored counters didn't match the likes and comments it really has
+type CounterDrift struct {
+	PostID         uint
+	LikeCount      int
+	ActualLikes    int
+	CommentCount   int
+	ActualComments int
+}
+
+// The SQL that recounts a post's likes and comments from the reactions and comments tables
+const (
+	actualLikesSQL    = "(SELECT COUNT(*) FROM reactions WHERE reactions.post_id = posts.id AND reactions.kind = 'like' AND reactions.deleted_at IS NULL)"
+	actualCommentsSQL = "(SELECT COUNT(*) FROM comments WHERE comments.post_id = posts.id AND comments.deleted_at IS NULL)"
+)
+
+// recountPostCounters sets the counters on the given posts straight from the source tables
+func recountPostCounters(tx *gorm.DB, postIDs interface{}) error {
+	return tx.Exec(`UPDATE posts SET like_count = `+actualLikesSQL+`, comment_count = `+actualCommentsSQL+`
+		WHERE id IN (?)`, postIDs).Error
+}
+
+// ReconcilePostCounters recounts every post's likes and comments from the reactions and comments
+// tables, fixes any counters that have drifted, and returns the posts that needed fixing
+func ReconcilePostCounters() ([]CounterDrift, error) {
+	var drifts []CounterDrift
+
+	err := Database.Transaction(func(tx *gorm.DB) error {
+		err := tx.Raw(`SELECT * FROM (
+				SELECT posts.id AS post_id, posts.like_count, posts.comment_count,
+					` + actualLikesSQL + ` AS actual_likes,
+					` + actualCommentsSQL + ` AS actual_comments
+				FROM posts WHERE posts.deleted_at IS NULL
+			) AS counts
+			WHERE like_count <> actual_likes OR comment_count <> actual_comments
+			ORDER BY post_id`).Scan(&drifts).Error
+		if err != nil {
+			return err
+		}
+
+		// Recount as part of the update, so a like that lands in the meantime isn't lost
+		if len(drifts) == 0 {
+			return nil
+		}
+		postIDs := make([]uint, 0, len(drifts))
+		for _, drift := range drifts {
+			postIDs = append(postIDs, drift.PostID)
+		}
+		return recountPostCounters(tx, postIDs)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return drifts, nil
+}
+
+// postsInteractedWithByUser is a subquery of every post the user has reacted to or commented on,
+// including reactions and comments that have since been deleted
+func postsInteractedWithByUser(tx *gorm.DB, userID uint) *gorm.DB {
+	return tx.Raw(`SELECT post_id FROM reactions WHERE user_id = ? AND post_id IS NOT NULL
+		UNION SELECT post_id FROM comments WHERE user_id = ?`, userID, userID)
+}
@@ -0,0 +1,286 @@
+package models
+
+import (
+	"errors"
+	"hash/fnv"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// The date format used for daily questions, e.g. "2025-04-11". Days are in UTC.
+const DailyDateFormat = "2006-01-02"
+
+// A DailyQuestion is the post everyone plays on a given day.
+// Curated rows are scheduled ahead of time; the rest are picked automatically.
+type DailyQuestion struct {
+	gorm.Model
+	Date    string `json:"date" gorm:"uniqueIndex;size:10"`
+	PostID  uint   `json:"post_id"`
+	Curated bool   `json:"curated"`
+	Post    Post   `json:"-"`
+}
+
+// A DailyAttempt is one user's single play of a day's question.
+// It can include several guesses and hints, but each user only gets one per day.
+type DailyAttempt struct {
+	gorm.Model
+	UserID     uint       `json:"user_id" gorm:"uniqueIndex:idx_daily_attempts_user_date;constraint:OnDelete:CASCADE"`
+	Date       string     `json:"date" gorm:"uniqueIndex:idx_daily_attempts_user_date;size:10"`
+	PostID     uint       `json:"post_id"`
+	Guesses    int        `json:"guesses"`
+	Results    string     `json:"results"` // one character per guess, "Y" for right and "N" for wrong
+	HintsUsed  int        `json:"hints_used"`
+	Correct    bool       `json:"correct"`
+	StartedAt  time.Time  `json:"started_at"`
+	FinishedAt *time.Time `json:"finished_at"`
+}
+
+// Today's date in the daily question format
+func DailyDate(now time.Time) string {
+	return now.UTC().Format(DailyDateFormat)
+}
+
+// ScheduleDailyQuestion adds a curated question for a date (replacing any earlier pick for a future date)
+func ScheduleDailyQuestion(date string, postID uint) error {
+	dailyQuestion := DailyQuestion{Date: date, PostID: postID, Curated: true}
+	return Database.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "date"}},
+		DoUpdates: clause.AssignmentColumns([]string{"post_id", "curated"}),
+	}).Create(&dailyQuestion).Error
+}
+
+// FetchOrChooseDailyQuestion returns the question for the given date. If nothing has been
+// curated for that day, it picks one deterministically from the well-liked posts that haven't
+// been a daily question before, and stores the pick so it never changes.
+// Only posts a moderator hasn't hidden, whose answer stays hidden all day, can be picked, and if
+// a moderator hides the day's post after it was picked (or curated), another one is picked instead.
+func FetchOrChooseDailyQuestion(date string, minimumLikes int) (*DailyQuestion, error) {
+	var dailyQuestion DailyQuestion
+	err := Database.Where("date = ?", date).First(&dailyQuestion).Error
+	if err != nil && !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+	replacing := err == nil
+	if replacing {
+		var hidden int64
+		err := Database.Model(&Post{}).Where("id = ? AND hidden_at IS NOT NULL", dailyQuestion.PostID).Count(&hidden).Error
+		if err != nil {
+			return nil, err
+		}
+		if hidden == 0 {
+			return &dailyQuestion, nil
+		}
+	}
+
+	day, err := time.Parse(DailyDateFormat, date)
+	if err != nil {
+		return nil, err
+	}
+	playable := func(db *gorm.DB) *gorm.DB {
+		return db.Where("posts.hidden_at IS NULL").Scopes(answerHiddenUntil(day.AddDate(0, 0, 1)))
+	}
+
+	// Candidates are posts with enough likes that haven't already been used, in a stable order
+	var candidateIDs []uint
+	err = Database.Model(&Post{}).Scopes(playable).
+		Where("posts.like_count >= ?", minimumLikes).
+		Where("posts.id NOT IN (?)", Database.Model(&DailyQuestion{}).Select("post_id")).
+		Order("posts.id").
+		Pluck("posts.id", &candidateIDs).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// If every well-liked post has been used, fall back to any playable post at all
+	if len(candidateIDs) == 0 {
+		if err := Database.Model(&Post{}).Scopes(playable).Order("posts.id").Pluck("posts.id", &candidateIDs).Error; err != nil {
+			return nil, err
+		}
+	}
+	if len(candidateIDs) == 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	// Hash the date so every server picks the same post for the same day
+	hash := fnv.New32a()
+	hash.Write([]byte(date))
+	chosen := candidateIDs[int(hash.Sum32()%uint32(len(candidateIDs)))]
+
+	// Two requests might choose at the same time, so let the first one win
+	onConflict := clause.OnConflict{DoNothing: true}
+	if replacing {
+		onConflict = clause.OnConflict{
+			Columns:   []clause.Column{{Name: "date"}},
+			DoUpdates: clause.Assignments(map[string]interface{}{"post_id": chosen, "curated": false}),
+			Where:     clause.Where{Exprs: []clause.Expression{clause.Eq{Column: "daily_questions.post_id", Value: dailyQuestion.PostID}}},
+		}
+	}
+	dailyQuestion = DailyQuestion{Date: date, PostID: chosen}
+	if err := Database.Clauses(onConflict).Create(&dailyQuestion).Error; err != nil {
+		return nil, err
+	}
+	if err := Database.Where("date = ?", date).First(&dailyQuestion).Error; err != nil {
+		return nil, err
+	}
+	return &dailyQuestion, nil
+}
+
+// Daily questions are listed by date rather than when they were picked, as curated ones are picked in advance
+var dailyQuestionKeys = keyset{table: "daily_questions", rank: "EXTRACT(EPOCH FROM daily_questions.date::date)"}
+
+func dailyQuestionCursor(dailyQuestion *DailyQuestion) (Cursor, error) {
+	day, err := time.Parse(DailyDateFormat, dailyQuestion.Date)
+	if err != nil {
+		return Cursor{}, err
+	}
+	rank := float64(day.Unix())
+	return Cursor{CreatedAt: dailyQuestion.CreatedAt, ID: dailyQuestion.ID, Rank: &rank}, nil
+}
+
+// Fetches a page of the daily questions from before the given date (with their posts), newest
+// first. Questions whose post has gone, or that the viewer isn't allowed to see, are left out.
+func FetchDailyQuestionsBefore(date string, viewerID uint, page Page) (*[]DailyQuestion, PageInfo, error) {
+	query := Database.Model(&DailyQuestion{}).Preload("Post").
+		Joins("JOIN posts ON posts.id = daily_questions.post_id AND posts.deleted_at IS NULL").
+		Scopes(accessibleTo(viewerID, "posts")).
+		Where("daily_questions.date < ?", date)
+	dailyQuestions, info, err := fetchPage(query, page, dailyQuestionKeys, dailyQuestionCursor)
+	if err != nil {
+		return &[]DailyQuestion{}, PageInfo{}, err
+	}
+	return &dailyQuestions, info, nil
+}
+
+// FetchOrStartDailyAttempt returns the user's play for the day, starting the clock if it's their first look
+func FetchOrStartDailyAttempt(userID uint, date string, postID uint) (*DailyAttempt, error) {
+	dailyAttempt := DailyAttempt{UserID: userID, Date: date, PostID: postID, StartedAt: time.Now()}
+	err := Database.Clauses(clause.OnConflict{DoNothing: true}).Create(&dailyAttempt).Error
+	if err != nil {
+		return nil, err
+	}
+
+	// Re-read it in case it already existed (in which case nothing was created above)
+	if err := Database.Where("user_id = ? AND date = ?", userID, date).First(&dailyAttempt).Error; err != nil {
+		return nil, err
+	}
+	return &dailyAttempt, nil
+}
+
+func FetchDailyAttempt(userID uint, date string) (*DailyAttempt, error) {
+	var dailyAttempt DailyAttempt
+	err := Database.Where("user_id = ? AND date = ?", userID, date).First(&dailyAttempt).Error
+	if err != nil {
+		return nil, err
+	}
+	return &dailyAttempt, nil
+}
+
+// Fetches all of a user's daily attempts keyed by date, used to show history
+// Fetches the user's plays on the given dates, keyed by date
+func FetchDailyAttemptsByUserID(userID uint, dates []string) (map[string]DailyAttempt, error) {
+	var dailyAttempts []DailyAttempt
+	if err := Database.Where("user_id = ? AND date IN ?", userID, dates).Find(&dailyAttempts).Error; err != nil {
+		return nil, err
+	}
+
+	byDate := make(map[string]DailyAttempt, len(dailyAttempts))
+	for _, dailyAttempt := range dailyAttempts {
+		byDate[dailyAttempt.Date] = dailyAttempt
+	}
+	return byDate, nil
+}
+
+func (dailyAttempt *DailyAttempt) IsFinished() bool {
+	return dailyAttempt.FinishedAt != nil
+}
+
+var (
+	ErrDailyFinished = errors.New("daily question already played")
+	ErrNoDailyHints  = errors.New("no daily hints left")
+)
+
+// lockDailyAttempt re-reads the user's play inside tx, locking it until tx ends
+func lockDailyAttempt(tx *gorm.DB, dailyAttempt *DailyAttempt) error {
+	return tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+		Where("user_id = ? AND date = ?", dailyAttempt.UserID, dailyAttempt.Date).
+		First(dailyAttempt).Error
+}
+
+// RecordDailyHint uses up one more hint on the user's play of the day, failing with
+// ErrNoDailyHints once they've had maxHints. dailyAttempt is refreshed with what was saved.
+func RecordDailyHint(dailyAttempt *DailyAttempt, maxHints int) error {
+	return Database.Transaction(func(tx *gorm.DB) error {
+		if err := lockDailyAttempt(tx, dailyAttempt); err != nil {
+			return err
+		}
+		if dailyAttempt.IsFinished() {
+			return ErrDailyFinished
+		}
+		if dailyAttempt.HintsUsed >= maxHints {
+			return ErrNoDailyHints
+		}
+
+		dailyAttempt.HintsUsed++
+		return tx.Model(dailyAttempt).Update("hints_used", dailyAttempt.HintsUsed).Error
+	})
+}
+
+// RecordDailyGuess adds a guess to the user's play of the day, finishing it once they get it right
+// or run out of guesses, and fails with ErrDailyFinished if it's already over. The play is locked
+// while it's updated, so guesses sent at the same time can't go over the limit between them.
+// dailyAttempt is refreshed with what was saved.
+func RecordDailyGuess(dailyAttempt *DailyAttempt, correct bool, maxGuesses int, now time.Time) error {
+	return Database.Transaction(func(tx *gorm.DB) error {
+		if err := lockDailyAttempt(tx, dailyAttempt); err != nil {
+			return err
+		}
+		if dailyAttempt.IsFinished() || dailyAttempt.Guesses >= maxGuesses {
+			return ErrDailyFinished
+		}
+
+		dailyAttempt.Guesses++
+		if correct {
+			dailyAttempt.Results += "Y"
+		} else {
+			dailyAttempt.Results += "N"
+		}
+		if correct || dailyAttempt.Guesses >= maxGuesses {
+			dailyAttempt.Correct = correct
+			dailyAttempt.FinishedAt = &now
+		}
+
+		return tx.Model(dailyAttempt).Select("guesses", "results", "correct", "finished_at").Updates(dailyAttempt).Error
+	})
+}
+
+// CalculateDailyStreak counts how many days in a row the user has answered the daily question
+// correctly, ending today (or yesterday, so the streak isn't lost before they've played today)
+func CalculateDailyStreak(userID uint, now time.Time) (int, error) {
+	var dates []string
+	err := Database.Model(&DailyAttempt{}).
+		Where("user_id = ? AND correct = ?", userID, true).
+		Order("date DESC").
+		Pluck("date", &dates).Error
+	if err != nil {
+		return 0, err
+	}
+
+	today := now.UTC()
+	expected := DailyDate(today)
+	if len(dates) > 0 && dates[0] != expected {
+		expected = DailyDate(today.AddDate(0, 0, -1))
+	}
+
+	streak := 0
+	for _, date := range dates {
+		if date != expected {
+			break
+		}
+		streak++
+		day, _ := time.Parse(DailyDateFormat, date)
+		expected = DailyDate(day.AddDate(0, 0, -1))
+	}
+	return streak, nil
+}
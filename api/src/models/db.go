@@ -28,5 +28,37 @@ func AutoMigrateModels() {
 	Database.AutoMigrate(&User{})
 	Database.AutoMigrate(&Post{})
 	Database.AutoMigrate(&Comment{})
-	Database.AutoMigrate(&Like{})
+	Database.AutoMigrate(&CommentRevision{})
+	Database.AutoMigrate(&Reaction{})
+	if Database.Migrator().HasTable("likes") {
+		fmt.Println("The old likes table is still here: run \"./api migrate-likes\" to move it into reactions")
+	}
+	Database.AutoMigrate(&Attempt{})
+	Database.AutoMigrate(&AlternateAnswer{})
+	Database.AutoMigrate(&AnswerChange{})
+	Database.AutoMigrate(&Dispute{})
+	Database.AutoMigrate(&DisputeVote{})
+	if err := readDuplicateUnreadGroups(); err != nil {
+		fmt.Println("Couldn't tidy up duplicate unread notifications:", err)
+	}
+	Database.AutoMigrate(&Notification{})
+	Database.AutoMigrate(&NotificationActor{})
+	Database.AutoMigrate(&DailyQuestion{})
+	Database.AutoMigrate(&DailyAttempt{})
+	Database.AutoMigrate(&XPEntry{})
+	Database.AutoMigrate(&UserBadge{})
+	Database.AutoMigrate(&PointsEntry{})
+	Database.AutoMigrate(&Bounty{})
+	Database.AutoMigrate(&Season{})
+	Database.AutoMigrate(&LeagueMembership{})
+	Database.AutoMigrate(&Follow{})
+	Database.AutoMigrate(&PostScore{})
+	Database.AutoMigrate(&Mention{})
+	Database.AutoMigrate(&Block{})
+	Database.AutoMigrate(&Mute{})
+	Database.AutoMigrate(&Conversation{})
+	Database.AutoMigrate(&ConversationMember{})
+	Database.AutoMigrate(&Message{})
+	Database.AutoMigrate(&Report{})
+	Database.AutoMigrate(&ModerationLogEntry{})
 }
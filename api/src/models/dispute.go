@@ -0,0 +1,189 @@
+package models
+
+import (
+	"errors"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Dispute statuses
+const (
+	DisputeOpen     = "open"
+	DisputeAccepted = "accepted"
+	DisputeRejected = "rejected"
+)
+
+// How an accepted dispute changes the post
+const (
+	ResolutionReplace   = "replace"   // the proposed answer replaces the existing answer
+	ResolutionAlternate = "alternate" // the proposed answer is added as another correct answer
+)
+
+// A Dispute is a user saying a post's answer is wrong (or out of date) and proposing a better one
+type Dispute struct {
+	gorm.Model
+	PostID          uint          `json:"post_id" gorm:"index"`
+	UserID          uint          `json:"user_id" gorm:"constraint:OnDelete:CASCADE"`
+	ProposedAnswer  string        `json:"proposed_answer"`
+	Justification   string        `json:"justification"`
+	Status          string        `json:"status" gorm:"size:20;default:open;index"`
+	Resolution      string        `json:"resolution" gorm:"size:20"`
+	RejectionReason string        `json:"rejection_reason"`
+	ResolvedAt      *time.Time    `json:"resolved_at"`
+	ResolvedByVote  bool          `json:"resolved_by_vote"`
+	Post            Post          `json:"-"`
+	User            User          `json:"-"`
+	Votes           []DisputeVote `json:"-"`
+}
+
+// A DisputeVote is a community member's opinion on a dispute the author hasn't dealt with
+type DisputeVote struct {
+	gorm.Model
+	DisputeID uint `json:"dispute_id" gorm:"uniqueIndex:idx_dispute_votes_dispute_user"`
+	UserID    uint `json:"user_id" gorm:"uniqueIndex:idx_dispute_votes_dispute_user;constraint:OnDelete:CASCADE"`
+	Support   bool `json:"support"`
+}
+
+var (
+	ErrDisputeNotOpen   = errors.New("dispute has already been resolved")
+	ErrOwnDisputeVote   = errors.New("can't vote on your own dispute")
+	ErrAnswerNotVisible = errors.New("the answer hasn't been revealed to this user")
+)
+
+func (dispute *Dispute) Save() (*Dispute, error) {
+	err := Database.Create(dispute).Error
+	if err != nil {
+		return &Dispute{}, err
+	}
+	return dispute, nil
+}
+
+func FetchDisputeByID(id uint) (*Dispute, error) {
+	var dispute Dispute
+	err := Database.First(&dispute, id).Error
+	if err != nil {
+		return &Dispute{}, err
+	}
+	return &dispute, nil
+}
+
+func FetchDisputesByPostID(postID uint) (*[]Dispute, error) {
+	var disputes []Dispute
+	err := Database.Where("post_id = ?", postID).Order("created_at").Find(&disputes).Error
+	if err != nil {
+		return &[]Dispute{}, err
+	}
+	return &disputes, nil
+}
+
+// Fetches disputes that are still open and were created before the given time
+func FetchOpenDisputesCreatedBefore(cutoff time.Time) (*[]Dispute, error) {
+	var disputes []Dispute
+	err := Database.Where("status = ? AND created_at < ?", DisputeOpen, cutoff).Find(&disputes).Error
+	if err != nil {
+		return &[]Dispute{}, err
+	}
+	return &disputes, nil
+}
+
+// AcceptDispute applies the proposed answer to the post (either replacing the answer
+// or adding it as an alternate) and re-scores every attempt at the post, all in one transaction
+func AcceptDispute(id uint, resolution string, byVote bool) error {
+	return Database.Transaction(func(tx *gorm.DB) error {
+		// Lock the dispute so two people can't resolve it at the same time
+		var dispute Dispute
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&dispute, id).Error; err != nil {
+			return err
+		}
+		if dispute.Status != DisputeOpen {
+			return ErrDisputeNotOpen
+		}
+
+		// Update the post's answers
+		if resolution == ResolutionReplace {
+			if err := tx.Model(&Post{}).Where("id = ?", dispute.PostID).Update("answer", dispute.ProposedAnswer).Error; err != nil {
+				return err
+			}
+		} else {
+			alternate := AlternateAnswer{PostID: dispute.PostID, Answer: dispute.ProposedAnswer}
+			if err := tx.Create(&alternate).Error; err != nil {
+				return err
+			}
+		}
+
+		// Mark the dispute as accepted
+		now := time.Now()
+		if err := tx.Model(&dispute).Updates(map[string]interface{}{
+			"status":           DisputeAccepted,
+			"resolution":       resolution,
+			"resolved_at":      &now,
+			"resolved_by_vote": byVote,
+		}).Error; err != nil {
+			return err
+		}
+
+		// What counts as correct has changed, so re-score past attempts
+		change := AnswerChange{DisputeID: &dispute.ID, Answer: dispute.ProposedAnswer}
+		return RescoreAttemptsForPost(tx, dispute.PostID, &change, now)
+	})
+}
+
+// RejectDispute closes the dispute without changing the post
+func RejectDispute(id uint, reason string, byVote bool) error {
+	now := time.Now()
+	result := Database.Model(&Dispute{}).Where("id = ? AND status = ?", id, DisputeOpen).Updates(map[string]interface{}{
+		"status":           DisputeRejected,
+		"rejection_reason": reason,
+		"resolved_at":      &now,
+		"resolved_by_vote": byVote,
+	})
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected == 0 {
+		return ErrDisputeNotOpen
+	}
+	return nil
+}
+
+// SaveDisputeVote records a vote, replacing the user's previous vote on the same dispute.
+// Whoever opened the dispute can't vote on it (ErrOwnDisputeVote), and nor can anyone the post
+// hasn't shown its answer to yet (ErrAnswerNotVisible), so a dispute can't be voted through
+// by people who don't know what the answer is.
+func SaveDisputeVote(dispute *Dispute, post *Post, userID uint, support bool) error {
+	if dispute.UserID == userID {
+		return ErrOwnDisputeVote
+	}
+	visible, err := post.IsAnswerVisibleTo(userID)
+	if err != nil {
+		return err
+	}
+	if !visible {
+		return ErrAnswerNotVisible
+	}
+
+	var vote DisputeVote
+	err = Database.Where("dispute_id = ? AND user_id = ?", dispute.ID, userID).First(&vote).Error
+	if err != nil {
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+		vote = DisputeVote{DisputeID: dispute.ID, UserID: userID, Support: support}
+		return Database.Create(&vote).Error
+	}
+	return Database.Model(&vote).Update("support", support).Error
+}
+
+// Counts the votes for and against a dispute
+func CountDisputeVotes(disputeID uint) (int64, int64, error) {
+	var support, oppose int64
+	if err := Database.Model(&DisputeVote{}).Where("dispute_id = ? AND support = ?", disputeID, true).Count(&support).Error; err != nil {
+		return 0, 0, err
+	}
+	if err := Database.Model(&DisputeVote{}).Where("dispute_id = ? AND support = ?", disputeID, false).Count(&oppose).Error; err != nil {
+		return 0, 0, err
+	}
+	return support, oppose, nil
+}
@@ -0,0 +1,68 @@
+package models
+
+import (
+	"fmt"
+
+	"github.com/makersacademy/go-react-acebook-template/api/src/events"
+)
+
+// These publish what's happened to the events hub, so it can be pushed to anyone connected who
+// cares about it. They're called once the change has been committed, and never fail the change.
+
+// publishCommentEvent tells the post's author, and everyone looking at the post, about a comment
+// being added or removed
+func publishCommentEvent(eventType string, comment *Comment) {
+	var post Post
+	if err := Database.Select("id", "user_id").First(&post, comment.PostID).Error; err != nil {
+		fmt.Printf("Error publishing %s for comment %d: %v\n", eventType, comment.ID, err)
+		return
+	}
+
+	events.Publish(eventType, map[string]interface{}{
+		"post_id":    comment.PostID,
+		"comment_id": comment.ID,
+		"parent_id":  comment.ParentID,
+		"user_id":    comment.UserID,
+	}, events.UserTopic(post.UserID), events.PostTopic(post.ID))
+}
+
+// publishLikeEvent tells the post's author about a like being added or taken back
+func publishLikeEvent(eventType string, like *Reaction) {
+	var post Post
+	if err := Database.Select("id", "user_id", "like_count").First(&post, *like.PostID).Error; err != nil {
+		fmt.Printf("Error publishing %s for like %d: %v\n", eventType, like.ID, err)
+		return
+	}
+
+	events.Publish(eventType, map[string]interface{}{
+		"post_id":    post.ID,
+		"user_id":    like.UserID,
+		"numOfLikes": post.LikeCount,
+	}, events.UserTopic(post.UserID))
+}
+
+// publishNotification sends a new (or newly grouped) notification to the user it's for
+func publishNotification(notification *Notification) {
+	events.Publish(events.NotificationCreated, map[string]interface{}{
+		"_id":        notification.ID,
+		"kind":       notification.Kind,
+		"message":    notification.Message,
+		"post_id":    notification.PostID,
+		"actorCount": notification.ActorCount,
+	}, events.UserTopic(notification.UserID))
+}
+
+// publishMessageEvent tells everyone in the conversation about a new message, including the
+// sender, so it shows up in any other tabs they have open
+func publishMessageEvent(message *Message) {
+	topics := make([]string, 0, len(message.Conversation.Members))
+	for _, member := range message.Conversation.Members {
+		topics = append(topics, events.UserTopic(member.UserID))
+	}
+
+	events.Publish(events.MessageCreated, map[string]interface{}{
+		"conversation_id": message.ConversationID,
+		"message_id":      message.ID,
+		"sender_id":       message.SenderID,
+	}, topics...)
+}
@@ -0,0 +1,88 @@
+package models
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// A Follow means FollowerID wants to see FolloweeID's posts in their "following" feed
+type Follow struct {
+	gorm.Model
+	FollowerID uint `json:"follower_id" gorm:"uniqueIndex:idx_follows_follower_followee;constraint:OnDelete:CASCADE"`
+	FolloweeID uint `json:"followee_id" gorm:"uniqueIndex:idx_follows_follower_followee;index;constraint:OnDelete:CASCADE"`
+	Follower   User `json:"-"`
+	Followee   User `json:"-"`
+}
+
+// FollowUser starts following someone. Following someone you already follow does nothing.
+// It returns true if this created a new follow.
+func FollowUser(followerID uint, followeeID uint) (bool, error) {
+	follow := Follow{FollowerID: followerID, FolloweeID: followeeID}
+	result := Database.Clauses(clause.OnConflict{DoNothing: true}).Create(&follow)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// UnfollowUser stops following someone. The row is removed for good (rather than soft deleted)
+// so the unique index doesn't stop them following again later.
+func UnfollowUser(followerID uint, followeeID uint) error {
+	return Database.Unscoped().Where("follower_id = ? AND followee_id = ?", followerID, followeeID).Delete(&Follow{}).Error
+}
+
+func IsFollowing(followerID uint, followeeID uint) (bool, error) {
+	var count int64
+	err := Database.Model(&Follow{}).Where("follower_id = ? AND followee_id = ?", followerID, followeeID).Count(&count).Error
+	return count > 0, err
+}
+
+// Fetches one page of the follows of the given user (with Follower loaded), newest first
+func FetchFollowersPage(userID uint, page Page) (*[]Follow, PageInfo, error) {
+	query := Database.Model(&Follow{}).Preload("Follower").
+		Joins("JOIN users ON users.id = follows.follower_id AND users.deleted_at IS NULL").
+		Where("follows.followee_id = ?", userID)
+	return fetchFollowPage(query, page)
+}
+
+// Fetches one page of the follows by the given user (with Followee loaded), newest first
+func FetchFollowingPage(userID uint, page Page) (*[]Follow, PageInfo, error) {
+	query := Database.Model(&Follow{}).Preload("Followee").
+		Joins("JOIN users ON users.id = follows.followee_id AND users.deleted_at IS NULL").
+		Where("follows.follower_id = ?", userID)
+	return fetchFollowPage(query, page)
+}
+
+func fetchFollowPage(query *gorm.DB, page Page) (*[]Follow, PageInfo, error) {
+	follows, info, err := fetchPage(query, page, keyset{table: "follows"}, followCursor)
+	if err != nil {
+		return &[]Follow{}, PageInfo{}, err
+	}
+	return &follows, info, nil
+}
+
+// Counts the users who follow the given user and the users they follow
+func CountFollows(userID uint) (int64, int64, error) {
+	var followers, following int64
+	err := Database.Model(&Follow{}).
+		Joins("JOIN users ON users.id = follows.follower_id AND users.deleted_at IS NULL").
+		Where("follows.followee_id = ?", userID).Count(&followers).Error
+	if err != nil {
+		return 0, 0, err
+	}
+	err = Database.Model(&Follow{}).
+		Joins("JOIN users ON users.id = follows.followee_id AND users.deleted_at IS NULL").
+		Where("follows.follower_id = ?", userID).Count(&following).Error
+	return followers, following, err
+}
+
+// Fetches a page of posts written by anyone the given user follows
+func FetchPostsFromFollowedUsers(userID uint, page Page) (*[]Post, PageInfo, error) {
+	query := Database.Model(&Post{}).Scopes(listedFor(userID, "posts")).
+		Where("posts.user_id IN (?)", Database.Model(&Follow{}).Select("followee_id").Where("follower_id = ?", userID))
+	posts, info, err := fetchPage(query, page, keyset{table: "posts"}, postCursor)
+	if err != nil {
+		return &[]Post{}, PageInfo{}, err
+	}
+	return &posts, info, nil
+}
@@ -0,0 +1,316 @@
+package models
+
+import (
+	"errors"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Season statuses
+const (
+	SeasonActive = "active"
+	SeasonClosed = "closed"
+)
+
+// What happened to a member when their season closed
+const (
+	LeaguePromoted  = "promoted"
+	LeagueRelegated = "relegated"
+	LeagueStayed    = "stayed"
+)
+
+// The ledger reasons that count as "quiz points" in a league. Starting balances,
+// stakes and refunds move points around but aren't earned by playing, so they don't count.
+var leaguePointReasons = []string{PointsCorrectAnswer, PointsBountyWon, PointsAnswerRescored, PointsBountyRescored}
+
+// A Season is one round of the leagues (a calendar month, e.g. "2025-04")
+type Season struct {
+	gorm.Model
+	Key      string     `json:"key" gorm:"uniqueIndex;size:7"`
+	StartsAt time.Time  `json:"starts_at"`
+	EndsAt   time.Time  `json:"ends_at"`
+	Status   string     `json:"status" gorm:"size:20;default:active;index"`
+	ClosedAt *time.Time `json:"closed_at"`
+}
+
+// A LeagueMembership puts a user in a division for a season. Division 1 is the top division.
+// The final fields are filled in when the season closes, which keeps a history per user.
+type LeagueMembership struct {
+	gorm.Model
+	SeasonID    uint   `json:"season_id" gorm:"uniqueIndex:idx_league_memberships_season_user;index:idx_league_memberships_season_division"`
+	UserID      uint   `json:"user_id" gorm:"uniqueIndex:idx_league_memberships_season_user;constraint:OnDelete:CASCADE"`
+	Division    int    `json:"division" gorm:"index:idx_league_memberships_season_division"`
+	FinalPoints int    `json:"final_points"`
+	FinalRank   int    `json:"final_rank"`
+	Outcome     string `json:"outcome" gorm:"size:20"`
+	Season      Season `json:"-"`
+	User        User   `json:"-"`
+}
+
+// A LeagueStanding is one row of a division table
+type LeagueStanding struct {
+	UserID   uint
+	Username string
+	Points   int
+	Rank     int
+}
+
+// LeagueSettings control division sizes and how many people move up and down each season
+type LeagueSettings struct {
+	DivisionSize int
+	MoveCount    int
+}
+
+// Works out the season key and dates for the month containing the given time (in UTC)
+func SeasonBounds(now time.Time) (string, time.Time, time.Time) {
+	now = now.UTC()
+	startsAt := time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return startsAt.Format("2006-01"), startsAt, startsAt.AddDate(0, 1, 0)
+}
+
+// EnsureSeason creates the season for the month containing the given time if it doesn't exist yet
+func EnsureSeason(db *gorm.DB, now time.Time) (*Season, error) {
+	key, startsAt, endsAt := SeasonBounds(now)
+
+	season := Season{Key: key, StartsAt: startsAt, EndsAt: endsAt, Status: SeasonActive}
+	if err := db.Clauses(clause.OnConflict{DoNothing: true}).Create(&season).Error; err != nil {
+		return nil, err
+	}
+	if err := db.Where("key = ?", key).First(&season).Error; err != nil {
+		return nil, err
+	}
+	return &season, nil
+}
+
+// Fetches active seasons that have already ended and need closing
+func FetchSeasonsToClose(now time.Time) (*[]Season, error) {
+	var seasons []Season
+	err := Database.Where("status = ? AND ends_at <= ?", SeasonActive, now).Order("starts_at").Find(&seasons).Error
+	if err != nil {
+		return &[]Season{}, err
+	}
+	return &seasons, nil
+}
+
+// CloseSeason ranks every division, records each member's result, and moves everyone into the
+// next season's divisions (promoting the top and relegating the bottom). It all happens in one
+// transaction with the season row locked, so if the server crashes part way through nothing is
+// saved, and running it again on a season that's already closed does nothing.
+func CloseSeason(seasonID uint, settings LeagueSettings, now time.Time) error {
+	return Database.Transaction(func(tx *gorm.DB) error {
+		var season Season
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&season, seasonID).Error; err != nil {
+			return err
+		}
+		if season.Status == SeasonClosed {
+			return nil // an earlier run already closed it
+		}
+
+		var memberships []LeagueMembership
+		if err := tx.Where("season_id = ?", season.ID).Find(&memberships).Error; err != nil {
+			return err
+		}
+
+		points, err := leaguePointsForSeason(tx, &season)
+		if err != nil {
+			return err
+		}
+
+		// Group members by division so each division can be ranked on its own
+		divisions := map[int][]LeagueMembership{}
+		bottomDivision := 1
+		for _, membership := range memberships {
+			divisions[membership.Division] = append(divisions[membership.Division], membership)
+			bottomDivision = max(bottomDivision, membership.Division)
+		}
+
+		nextDivisions := map[uint]int{}
+		for division, members := range divisions {
+			rankMembers(members, points)
+			promote, relegate := movesForDivision(division, bottomDivision, len(members), settings)
+
+			for index, membership := range members {
+				rank := index + 1
+				outcome, nextDivision := LeagueStayed, division
+				if rank <= promote {
+					outcome, nextDivision = LeaguePromoted, division-1
+				} else if rank > len(members)-relegate {
+					outcome, nextDivision = LeagueRelegated, division+1
+				}
+
+				if err := tx.Model(&LeagueMembership{}).Where("id = ?", membership.ID).Updates(map[string]interface{}{
+					"final_points": points[membership.UserID],
+					"final_rank":   rank,
+					"outcome":      outcome,
+				}).Error; err != nil {
+					return err
+				}
+				nextDivisions[membership.UserID] = nextDivision
+			}
+		}
+
+		// Move everyone into next season
+		nextSeason, err := EnsureSeason(tx, season.EndsAt)
+		if err != nil {
+			return err
+		}
+		for userID, division := range nextDivisions {
+			next := LeagueMembership{SeasonID: nextSeason.ID, UserID: userID, Division: division}
+			if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&next).Error; err != nil {
+				return err
+			}
+		}
+
+		// Finally mark the season as closed
+		return tx.Model(&season).Updates(map[string]interface{}{"status": SeasonClosed, "closed_at": now}).Error
+	})
+}
+
+// EnsureLeagueMembership makes sure the user is in a division for the season, placing newcomers
+// in the bottom division (or a new one if it's full). Users aren't placed while an earlier season
+// is still waiting to close, because the rollover will place them with the right promotion.
+func EnsureLeagueMembership(userID uint, season *Season, settings LeagueSettings) (*LeagueMembership, error) {
+	var membership LeagueMembership
+	err := Database.Where("season_id = ? AND user_id = ?", season.ID, userID).First(&membership).Error
+	if err == nil {
+		return &membership, nil
+	}
+	if !errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, err
+	}
+
+	var waiting int64
+	if err := Database.Model(&Season{}).Where("status = ? AND starts_at < ?", SeasonActive, season.StartsAt).Count(&waiting).Error; err != nil {
+		return nil, err
+	}
+	if waiting > 0 {
+		return nil, gorm.ErrRecordNotFound
+	}
+
+	err = Database.Transaction(func(tx *gorm.DB) error {
+		// Lock the season so two newcomers don't both squeeze into the last space
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&Season{}, season.ID).Error; err != nil {
+			return err
+		}
+
+		var bottom struct {
+			Division int
+			Members  int
+		}
+		if err := tx.Model(&LeagueMembership{}).
+			Select("division, COUNT(*) AS members").
+			Where("season_id = ?", season.ID).
+			Group("division").Order("division DESC").Limit(1).
+			Scan(&bottom).Error; err != nil {
+			return err
+		}
+
+		division := max(bottom.Division, 1)
+		if bottom.Members >= settings.DivisionSize {
+			division++
+		}
+
+		membership = LeagueMembership{SeasonID: season.ID, UserID: userID, Division: division}
+		return tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&membership).Error
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	err = Database.Where("season_id = ? AND user_id = ?", season.ID, userID).First(&membership).Error
+	if err != nil {
+		return nil, err
+	}
+	return &membership, nil
+}
+
+// FetchDivisionStandings ranks everyone in a division by the quiz points they've earned so far this season
+func FetchDivisionStandings(season *Season, division int) ([]LeagueStanding, error) {
+	var memberships []LeagueMembership
+	if err := Database.Where("season_id = ? AND division = ?", season.ID, division).Preload("User").Find(&memberships).Error; err != nil {
+		return nil, err
+	}
+
+	points, err := leaguePointsForSeason(Database, season)
+	if err != nil {
+		return nil, err
+	}
+
+	rankMembers(memberships, points)
+
+	standings := make([]LeagueStanding, 0, len(memberships))
+	for index, membership := range memberships {
+		standings = append(standings, LeagueStanding{
+			UserID:   membership.UserID,
+			Username: membership.User.Username,
+			Points:   points[membership.UserID],
+			Rank:     index + 1,
+		})
+	}
+	return standings, nil
+}
+
+// Fetches the seasons a user has finished, newest first
+func FetchClosedMembershipsByUserID(userID uint) (*[]LeagueMembership, error) {
+	var memberships []LeagueMembership
+	err := Database.Joins("Season").
+		Where("league_memberships.user_id = ? AND \"Season\".status = ?", userID, SeasonClosed).
+		Order("\"Season\".starts_at DESC").
+		Find(&memberships).Error
+	if err != nil {
+		return &[]LeagueMembership{}, err
+	}
+	return &memberships, nil
+}
+
+// leaguePointsForSeason adds up the quiz points each user earned during the season
+func leaguePointsForSeason(db *gorm.DB, season *Season) (map[uint]int, error) {
+	var totals []struct {
+		UserID uint
+		Total  int
+	}
+	err := db.Model(&PointsEntry{}).
+		Select("user_id, SUM(amount) AS total").
+		Where("reason IN ? AND created_at >= ? AND created_at < ?", leaguePointReasons, season.StartsAt, season.EndsAt).
+		Group("user_id").
+		Scan(&totals).Error
+	if err != nil {
+		return nil, err
+	}
+
+	points := make(map[uint]int, len(totals))
+	for _, total := range totals {
+		points[total.UserID] = total.Total
+	}
+	return points, nil
+}
+
+// movesForDivision works out how many members go up and how many go down. Nobody goes up from the
+// top division or down from the bottom one. In a division too small for MoveCount each way the top
+// and bottom would overlap, so each is cut to half the division and anyone in the middle stays.
+func movesForDivision(division int, bottomDivision int, members int, settings LeagueSettings) (int, int) {
+	promote, relegate := 0, 0
+	if division > 1 {
+		promote = settings.MoveCount
+	}
+	if division < bottomDivision {
+		relegate = settings.MoveCount
+	}
+	if promote > 0 && relegate > 0 {
+		promote, relegate = min(promote, members/2), min(relegate, members/2)
+	}
+	return promote, relegate
+}
+
+// rankMembers sorts members by points (highest first), using user ID to break ties so ranks are stable
+func rankMembers(members []LeagueMembership, points map[uint]int) {
+	sort.Slice(members, func(i, j int) bool {
+		if points[members[i].UserID] != points[members[j].UserID] {
+			return points[members[i].UserID] > points[members[j].UserID]
+		}
+		return members[i].UserID < members[j].UserID
+	})
+}
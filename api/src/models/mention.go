@@ -0,0 +1,211 @@
+package models
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf16"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Only this many different people can be mentioned in one question or comment
+const MaxMentionsPerText = 10
+
+// An @ starts a mention unless it's in the middle of a word (so emails don't count)
+var mentionPattern = regexp.MustCompile(`(^|[^\p{L}\p{N}_])@([\p{L}\p{N}_]+)`)
+
+// A Mention is a user being @mentioned in a question (PostID set) or a comment (CommentID set).
+// Each person is only mentioned once per question or comment, however many times their name
+// appears, so they're only notified once. Handle is the name as it was written (lowercased),
+// so the mention still points at the right person if they change their username later.
+type Mention struct {
+	gorm.Model
+	UserID    uint     `json:"user_id" gorm:"uniqueIndex:idx_mentions_post,where:deleted_at IS NULL;uniqueIndex:idx_mentions_comment,where:deleted_at IS NULL;constraint:OnDelete:CASCADE"`
+	AuthorID  uint     `json:"author_id"`
+	PostID    *uint    `json:"post_id" gorm:"uniqueIndex:idx_mentions_post,where:deleted_at IS NULL;index"`
+	CommentID *uint    `json:"comment_id" gorm:"uniqueIndex:idx_mentions_comment,where:deleted_at IS NULL;index"`
+	Handle    string   `json:"handle" gorm:"size:50"`
+	User      User     `json:"-"`
+	Post      *Post    `json:"-"`
+	Comment   *Comment `json:"-"`
+}
+
+// A MentionSpan is where a mention appears in some text. Start and End count UTF-16 code units
+// (like JavaScript string indexes) so clients can turn them straight into links.
+type MentionSpan struct {
+	Start    int
+	End      int
+	UserID   uint
+	Username string // the user's current username, which may differ from what was written
+}
+
+type mentionMatch struct {
+	handle     string // lowercased, without the @
+	start, end int    // byte offsets of the @handle in the text
+}
+
+// parseMentions finds the @handles in some text, in order
+func parseMentions(text string) []mentionMatch {
+	var matches []mentionMatch
+	for _, match := range mentionPattern.FindAllStringSubmatchIndex(text, -1) {
+		// match[4]:match[5] is the handle, and the @ is just before it
+		matches = append(matches, mentionMatch{
+			handle: strings.ToLower(text[match[4]:match[5]]),
+			start:  match[4] - 1,
+			end:    match[5],
+		})
+	}
+	return matches
+}
+
+// RecordMentions stores the mentions in a question or comment, and notifies anyone mentioned
+// for the first time. Handles that don't match a user are ignored. It's called again when the
+// text is edited, and only people who weren't already mentioned are notified.
+func RecordMentions(authorID uint, target ReactionTarget, postID uint, text string) error {
+	// The different people mentioned, in the order they first appear
+	var handles []string
+	seen := map[string]bool{}
+	for _, match := range parseMentions(text) {
+		if !seen[match.handle] && len(handles) < MaxMentionsPerText {
+			seen[match.handle] = true
+			handles = append(handles, match.handle)
+		}
+	}
+	if len(handles) == 0 {
+		return nil
+	}
+
+	var users []User
+	if err := Database.Where("LOWER(username) IN ?", handles).Find(&users).Error; err != nil {
+		return err
+	}
+	usersByHandle := map[string]User{}
+	for _, user := range users {
+		handle := strings.ToLower(user.Username)
+		// Usernames are unique including case, so if two only differ by case prefer the one typed exactly
+		if _, exists := usersByHandle[handle]; !exists || strings.Contains(text, "@"+user.Username) {
+			usersByHandle[handle] = user
+		}
+	}
+
+	var author User
+	if err := Database.First(&author, authorID).Error; err != nil {
+		return err
+	}
+
+	// Notifications are sent once the mentions are saved, so nobody is told about one that wasn't
+	var notifications []NotificationEvent
+	err := Database.Transaction(func(tx *gorm.DB) error {
+		for _, handle := range handles {
+			user, exists := usersByHandle[handle]
+			if !exists {
+				continue
+			}
+
+			mention := Mention{UserID: user.ID, AuthorID: authorID, Handle: handle}
+			place := "a question"
+			if target.IsPost() {
+				mention.PostID = &target.ID
+			} else {
+				mention.CommentID = &target.ID
+				place = "a comment"
+			}
+
+			result := tx.Clauses(clause.OnConflict{
+				Columns:     []clause.Column{{Name: "user_id"}, {Name: target.column}},
+				TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "deleted_at IS NULL"}}},
+				DoNothing:   true,
+			}).Create(&mention)
+			if result.Error != nil {
+				return result.Error
+			}
+
+			// Already mentioned here (e.g. the text was edited)
+			if result.RowsAffected == 0 {
+				continue
+			}
+
+			notifications = append(notifications, NotificationEvent{
+				UserID:  user.ID,
+				ActorID: authorID,
+				Kind:    NotificationMentioned,
+				PostID:  &postID,
+				Message: fmt.Sprintf("%s mentioned you in %s", author.Username, place),
+			})
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for _, notification := range notifications {
+		if err := Notify(Database, notification); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// FetchMentions loads the mentions for some posts or comments (all of the same type), keyed by
+// their ID. Mentions of deleted users are left out.
+func FetchMentions(targets []ReactionTarget) (map[uint][]Mention, error) {
+	mentions := map[uint][]Mention{}
+	if len(targets) == 0 {
+		return mentions, nil
+	}
+
+	column := targets[0].column
+	ids := make([]uint, 0, len(targets))
+	for _, target := range targets {
+		ids = append(ids, target.ID)
+	}
+
+	var rows []Mention
+	if err := Database.InnerJoins("User").Where("mentions."+column+" IN ?", ids).Find(&rows).Error; err != nil {
+		return nil, err
+	}
+	for _, mention := range rows {
+		id := mention.CommentID
+		if column == "post_id" {
+			id = mention.PostID
+		}
+		mentions[*id] = append(mentions[*id], mention)
+	}
+	return mentions, nil
+}
+
+// MentionSpans finds where each of the stored mentions appears in the text. Handles that
+// aren't stored mentions (e.g. a user that doesn't exist, or has been deleted) are left as text.
+func MentionSpans(text string, mentions []Mention) []MentionSpan {
+	spans := []MentionSpan{}
+	if len(mentions) == 0 {
+		return spans
+	}
+
+	mentionsByHandle := map[string]Mention{}
+	for _, mention := range mentions {
+		mentionsByHandle[mention.Handle] = mention
+	}
+
+	for _, match := range parseMentions(text) {
+		mention, exists := mentionsByHandle[match.handle]
+		if !exists {
+			continue
+		}
+		start := utf16Length(text[:match.start])
+		spans = append(spans, MentionSpan{
+			Start:    start,
+			End:      start + utf16Length(text[match.start:match.end]),
+			UserID:   mention.UserID,
+			Username: mention.User.Username,
+		})
+	}
+	return spans
+}
+
+func utf16Length(text string) int {
+	return len(utf16.Encode([]rune(text)))
+}
@@ -0,0 +1,217 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Notification kinds
+const (
+	NotificationPostOutdated     = "post_outdated"     // one of your posts has passed its valid_until / review_after date
+	NotificationMentioned        = "mentioned"         // someone @mentioned you in a question or comment
+	NotificationCommented        = "commented"         // someone commented on your question
+	NotificationLiked            = "liked"             // someone liked your question
+	NotificationFollowed         = "followed"          // someone followed you
+	NotificationModeratorWarning = "moderator_warning" // a moderator has warned you about something you posted (or your behaviour)
+)
+
+// What's said about the people behind a grouped notification, e.g. "5 people liked your question"
+var groupedNotificationMessages = map[string]string{
+	NotificationCommented: "commented on your question",
+	NotificationLiked:     "liked your question",
+	NotificationFollowed:  "followed you",
+}
+
+// A Notification tells a user that something happened that they might care about.
+// Repeated events (like several people liking the same question) are grouped into one
+// notification while it's unread: GroupKey says what it's about, ActorID is the person who
+// most recently caused it and ActorCount is how many different people have. UpdatedAt is when
+// the latest of them happened, and the inbox is ordered by it. The partial unique index means
+// there's only ever one unread notification per group, however many events arrive at once.
+type Notification struct {
+	gorm.Model
+	UserID     uint       `json:"user_id" gorm:"index;uniqueIndex:idx_notifications_unread_group,where:group_key <> '' AND read_at IS NULL AND deleted_at IS NULL;constraint:OnDelete:CASCADE"`
+	Kind       string     `json:"kind" gorm:"size:30"`
+	Message    string     `json:"message"`
+	PostID     *uint      `json:"post_id"`
+	ActorID    *uint      `json:"actor_id"`
+	ActorCount int        `json:"actor_count" gorm:"not null;default:0"`
+	GroupKey   string     `json:"-" gorm:"size:50;index;uniqueIndex:idx_notifications_unread_group,where:group_key <> '' AND read_at IS NULL AND deleted_at IS NULL"`
+	ReadAt     *time.Time `json:"read_at"`
+	User       User       `json:"-"`
+	Actor      *User      `json:"-"`
+}
+
+// readDuplicateUnreadGroups marks all but the newest unread notification in each group as read,
+// so idx_notifications_unread_group can be created on databases from before it existed
+func readDuplicateUnreadGroups() error {
+	if !Database.Migrator().HasTable(&Notification{}) || !Database.Migrator().HasColumn(&Notification{}, "group_key") {
+		return nil
+	}
+	return Database.Exec(`UPDATE notifications SET read_at = NOW()
+		WHERE group_key <> '' AND read_at IS NULL AND deleted_at IS NULL
+		AND id NOT IN (
+			SELECT MAX(id) FROM notifications
+			WHERE group_key <> '' AND read_at IS NULL AND deleted_at IS NULL
+			GROUP BY user_id, group_key
+		)`).Error
+}
+
+// A NotificationActor records that someone is behind a grouped notification,
+// so they're only counted once however many times they do it
+type NotificationActor struct {
+	NotificationID uint `gorm:"primaryKey;constraint:OnDelete:CASCADE"`
+	UserID         uint `gorm:"primaryKey;constraint:OnDelete:CASCADE"`
+}
+
+func (notification *Notification) Save() (*Notification, error) {
+	err := Database.Create(notification).Error
+	if err != nil {
+		return &Notification{}, err
+	}
+
+	publishNotification(notification)
+	return notification, nil
+}
+
+// A NotificationEvent is something one user did that another should hear about
+type NotificationEvent struct {
+	UserID  uint   // who to tell
+	ActorID uint   // who did it
+	Kind    string // one of the Notification kinds
+	PostID  *uint  // the post it's about, if there is one
+	Message string // only needed for kinds that aren't grouped
+}
+
+// Notify tells the user about the event. Kinds with a grouped message are folded into the user's
+// unread notification about the same thing, if there is one. People aren't told about their own
+// actions, or about anything done by someone they've blocked (or who has blocked them).
+func Notify(db *gorm.DB, event NotificationEvent) error {
+	if event.UserID == event.ActorID {
+		return nil
+	}
+	if blocked, err := isBlockedWithAny(db, event.UserID, []uint{event.ActorID}); err != nil || blocked {
+		return err
+	}
+
+	verb, grouped := groupedNotificationMessages[event.Kind]
+	if !grouped {
+		notification := Notification{
+			UserID:     event.UserID,
+			Kind:       event.Kind,
+			Message:    event.Message,
+			PostID:     event.PostID,
+			ActorID:    &event.ActorID,
+			ActorCount: 1,
+		}
+		if err := db.Create(&notification).Error; err != nil {
+			return err
+		}
+		publishNotification(&notification)
+		return nil
+	}
+
+	groupKey := event.Kind
+	if event.PostID != nil {
+		groupKey = fmt.Sprintf("%s:%d", event.Kind, *event.PostID)
+	}
+
+	var notification Notification
+	changed := false
+	err := db.Transaction(func(tx *gorm.DB) error {
+		// Start a new notification if there's nothing unread about this yet. If there is (or another
+		// event has just started one), the insert does nothing and we lock the one that's there.
+		notification = Notification{
+			UserID:   event.UserID,
+			Kind:     event.Kind,
+			PostID:   event.PostID,
+			GroupKey: groupKey,
+		}
+		result := tx.Clauses(clause.OnConflict{
+			Columns:     []clause.Column{{Name: "user_id"}, {Name: "group_key"}},
+			TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "group_key <> '' AND read_at IS NULL AND deleted_at IS NULL"}}},
+			DoNothing:   true,
+		}).Create(&notification)
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			notification = Notification{}
+			err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+				Where("user_id = ? AND group_key = ? AND read_at IS NULL", event.UserID, groupKey).
+				First(&notification).Error
+			if err != nil {
+				return err
+			}
+		}
+
+		// Someone who's already counted (e.g. they liked, unliked and liked again) doesn't count twice
+		result = tx.Clauses(clause.OnConflict{DoNothing: true}).
+			Create(&NotificationActor{NotificationID: notification.ID, UserID: event.ActorID})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+
+		var actor User
+		if err := tx.First(&actor, event.ActorID).Error; err != nil {
+			return err
+		}
+		count := notification.ActorCount + 1
+		message := fmt.Sprintf("%s %s", actor.Username, verb)
+		if count > 1 {
+			message = fmt.Sprintf("%d people %s", count, verb)
+		}
+
+		// Saving bumps updated_at, which moves the notification back to the top of the inbox
+		notification.ActorID, notification.ActorCount, notification.Message = &event.ActorID, count, message
+		changed = true
+		return tx.Select("actor_id", "actor_count", "message", "updated_at").Save(&notification).Error
+	})
+	if err != nil {
+		return err
+	}
+
+	if changed {
+		publishNotification(&notification)
+	}
+	return nil
+}
+
+// The inbox is ordered by when each notification last had something added to it. updated_at is
+// ranked on as a whole number of microseconds, which the cursor can store exactly.
+var notificationKeys = keyset{table: "notifications", rank: "(EXTRACT(EPOCH FROM notifications.updated_at) * 1000000)::bigint"}
+
+func notificationCursor(notification *Notification) (Cursor, error) {
+	rank := float64(notification.UpdatedAt.UnixMicro())
+	return Cursor{CreatedAt: notification.CreatedAt, ID: notification.ID, Rank: &rank}, nil
+}
+
+// Fetches a page of the user's notifications, most recent first
+func FetchNotificationsPage(userID uint, page Page) (*[]Notification, PageInfo, error) {
+	query := Database.Model(&Notification{}).Preload("Actor").Where("notifications.user_id = ?", userID)
+	notifications, info, err := fetchPage(query, page, notificationKeys, notificationCursor)
+	if err != nil {
+		return &[]Notification{}, PageInfo{}, err
+	}
+	return &notifications, info, nil
+}
+
+func CountUnreadNotifications(userID uint) (int64, error) {
+	var count int64
+	err := Database.Model(&Notification{}).Where("user_id = ? AND read_at IS NULL", userID).Count(&count).Error
+	return count, err
+}
+
+// MarkNotificationsRead marks the user's notifications as read, or just the ones with
+// the given IDs. Notifications belonging to other users are left alone.
+func MarkNotificationsRead(userID uint, ids []uint, now time.Time) error {
+	query := Database.Model(&Notification{}).Where("user_id = ? AND read_at IS NULL", userID)
+	if len(ids) > 0 {
+		query = query.Where("id IN ?", ids)
+	}
+	// UpdateColumn so reading a notification doesn't move it in the inbox
+	return query.UpdateColumn("read_at", now).Error
+}
@@ -0,0 +1,182 @@
+package models
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// A Cursor marks a position in a list. Lists are ordered by created_at then id, so a cursor
+// still points at the same place when new rows arrive while someone is scrolling.
+// Ranked lists (like the trending feed) also order by a rank first, which is stored too.
+type Cursor struct {
+	CreatedAt time.Time `json:"t"`
+	ID        uint      `json:"id"`
+	Rank      *float64  `json:"r,omitempty"`
+}
+
+// Encode turns the cursor into an opaque string that's safe to put in a URL
+func (cursor Cursor) Encode() string {
+	data, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// DecodeCursor reads a cursor made by Encode
+func DecodeCursor(encoded string) (*Cursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+
+	var cursor Cursor
+	if err := json.Unmarshal(data, &cursor); err != nil || cursor.ID == 0 {
+		return nil, ErrInvalidCursor
+	}
+	return &cursor, nil
+}
+
+// A Page asks for up to Limit rows after (or before) a cursor.
+// With neither cursor set it asks for the first page.
+type Page struct {
+	Limit  int
+	After  *Cursor
+	Before *Cursor
+}
+
+// PageInfo holds the cursors for the pages either side of the one fetched (nil if there isn't one)
+type PageInfo struct {
+	NextCursor *string
+	PrevCursor *string
+}
+
+// A keyset describes how a list is ordered, so fetchPage knows how to continue from a cursor
+type keyset struct {
+	table     string
+	rank      string // optional SQL expression ranked on before created_at, highest first
+	ascending bool   // oldest first rather than newest first
+}
+
+func (keys keyset) columns() string {
+	columns := fmt.Sprintf("%s.created_at, %s.id", keys.table, keys.table)
+	if keys.rank != "" {
+		columns = keys.rank + ", " + columns
+	}
+	return columns
+}
+
+func (keys keyset) values(cursor *Cursor) []interface{} {
+	values := []interface{}{cursor.CreatedAt, cursor.ID}
+	if keys.rank != "" {
+		rank := 0.0
+		if cursor.Rank != nil {
+			rank = *cursor.Rank
+		}
+		values = append([]interface{}{rank}, values...)
+	}
+	return values
+}
+
+func (keys keyset) order(reverse bool) string {
+	direction := "DESC"
+	if keys.ascending != reverse {
+		direction = "ASC"
+	}
+
+	parts := strings.Split(keys.columns(), ", ")
+	for index := range parts {
+		parts[index] += " " + direction
+	}
+	return strings.Join(parts, ", ")
+}
+
+// fetchPage runs the query for one page, using the cursor to carry on from where the last page
+// stopped (keyset pagination) rather than an offset, so it stays quick however deep you scroll.
+// cursorOf makes the cursor for a row, and is only called for the first and last rows.
+func fetchPage[T any](query *gorm.DB, page Page, keys keyset, cursorOf func(*T) (Cursor, error)) ([]T, PageInfo, error) {
+	limit := page.Limit
+	if limit <= 0 || limit > MaxPageSize {
+		limit = DefaultPageSize
+	}
+
+	// Rows further down the list compare as "less than" when newest first, and "greater than" when oldest first
+	forward, backward := "<", ">"
+	if keys.ascending {
+		forward, backward = ">", "<"
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?, ", len(keys.values(&Cursor{}))), ", ")
+	backwards := page.Before != nil
+	if page.After != nil {
+		query = query.Where(fmt.Sprintf("(%s) %s (%s)", keys.columns(), forward, placeholders), keys.values(page.After)...)
+	}
+	if backwards {
+		query = query.Where(fmt.Sprintf("(%s) %s (%s)", keys.columns(), backward, placeholders), keys.values(page.Before)...)
+	}
+
+	// Fetch one extra row to find out if there's another page. Going backwards the rows
+	// are fetched in reverse (nearest the cursor first) and flipped round afterwards.
+	var rows []T
+	if err := query.Order(keys.order(backwards)).Limit(limit + 1).Find(&rows).Error; err != nil {
+		return []T{}, PageInfo{}, err
+	}
+	hasMore := len(rows) > limit
+	if hasMore {
+		rows = rows[:limit]
+	}
+	if backwards {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+
+	var info PageInfo
+	if len(rows) == 0 {
+		return rows, info, nil
+	}
+
+	if (!backwards && hasMore) || backwards {
+		cursor, err := cursorOf(&rows[len(rows)-1])
+		if err != nil {
+			return []T{}, PageInfo{}, err
+		}
+		next := cursor.Encode()
+		info.NextCursor = &next
+	}
+	if (backwards && hasMore) || page.After != nil {
+		cursor, err := cursorOf(&rows[0])
+		if err != nil {
+			return []T{}, PageInfo{}, err
+		}
+		prev := cursor.Encode()
+		info.PrevCursor = &prev
+	}
+	return rows, info, nil
+}
+
+func postCursor(post *Post) (Cursor, error) {
+	return Cursor{CreatedAt: post.CreatedAt, ID: post.ID}, nil
+}
+
+func commentCursor(comment *Comment) (Cursor, error) {
+	return Cursor{CreatedAt: comment.CreatedAt, ID: comment.ID}, nil
+}
+
+func reactionCursor(reaction *Reaction) (Cursor, error) {
+	return Cursor{CreatedAt: reaction.CreatedAt, ID: reaction.ID}, nil
+}
+
+func followCursor(follow *Follow) (Cursor, error) {
+	return Cursor{CreatedAt: follow.CreatedAt, ID: follow.ID}, nil
+}
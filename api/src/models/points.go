@@ -0,0 +1,61 @@
+package models
+
+import (
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Why points moved. Unlike XP, points can be spent (e.g. on bounties), so entries can be negative.
+const (
+	PointsStartingBalance = "starting_balance"
+	PointsCorrectAnswer   = "correct_answer"
+	PointsBountyEscrow    = "bounty_escrow" // the author's stake is held while the bounty is open
+	PointsBountyWon       = "bounty_won"    // paid to whoever wins the bounty
+	PointsBountyRefund    = "bounty_refund" // the author gets their stake back when nobody answers in time, or the bounty is cancelled
+
+	// When an answer change re-scores attempts, these move points to match the new results
+	PointsAnswerRescored = "answer_rescored" // correct_answer points given or taken back
+	PointsBountyRescored = "bounty_rescored" // a bounty prize moved to whoever should have won it
+	PointsRefundRescored = "refund_rescored" // the author's refunded stake, if the bounty is no longer theirs (or now is)
+)
+
+const (
+	StartingPoints      = 100
+	CorrectAnswerPoints = 10
+)
+
+// A PointsEntry is one line in a user's points ledger. Balances are worked out by adding up
+// the ledger, and the unique index stops the same thing being paid out twice.
+type PointsEntry struct {
+	gorm.Model
+	UserID     uint   `json:"user_id" gorm:"uniqueIndex:idx_points_entries_source;constraint:OnDelete:CASCADE"`
+	Amount     int    `json:"amount"`
+	Reason     string `json:"reason" gorm:"size:30;uniqueIndex:idx_points_entries_source"`
+	SourceType string `json:"source_type" gorm:"size:30;uniqueIndex:idx_points_entries_source"` // e.g. "post", "bounty", "user"
+	SourceID   uint   `json:"source_id" gorm:"uniqueIndex:idx_points_entries_source"`
+}
+
+// AddPoints adds an entry to the user's points ledger using the given database or transaction.
+// Adding the same reason for the same source twice is ignored.
+func AddPoints(db *gorm.DB, userID uint, amount int, reason string, sourceType string, sourceID uint) error {
+	entry := PointsEntry{UserID: userID, Amount: amount, Reason: reason, SourceType: sourceType, SourceID: sourceID}
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&entry).Error
+}
+
+// Adds up the user's points ledger
+func PointsBalanceForUser(db *gorm.DB, userID uint) (int, error) {
+	var total int
+	err := db.Model(&PointsEntry{}).Where("user_id = ?", userID).Select("COALESCE(SUM(amount), 0)").Scan(&total).Error
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// GrantStartingPoints gives every user who hasn't had one yet their starting balance
+func GrantStartingPoints() error {
+	return Database.Exec(`INSERT INTO points_entries (created_at, updated_at, user_id, amount, reason, source_type, source_id)
+		SELECT NOW(), NOW(), users.id, ?, ?, 'user', users.id
+		FROM users WHERE users.deleted_at IS NULL
+		ON CONFLICT DO NOTHING`, StartingPoints, PointsStartingBalance).Error
+}
@@ -1,17 +1,47 @@
 package models
 
 import (
+	"errors"
+	"time"
+
+	"github.com/makersacademy/go-react-acebook-template/api/src/answercheck"
 	"gorm.io/gorm"
 )
 
+// The different ways an author can choose to reveal the answer to their question
+const (
+	RevealImmediately       = "immediate"           // the answer is shown straight away (the original behaviour)
+	RevealAfterAttempt      = "after_attempt"       // the answer is shown once the viewer has had a guess
+	RevealAfterCorrectCount = "after_correct_count" // the answer is shown once N different users have got it right
+	RevealAtTime            = "at_time"             // the answer is shown from a fixed date and time
+)
+
 type Post struct {
 	gorm.Model
-	UserID   uint      `json:"user_id" gorm:"constraint:OnDelete:CASCADE"`
-	User     User      `json:"user"`
-	Question string    `json:"question"`
-	Answer   string    `json:"answer"`
-	Comments []Comment `json:"comments"`
-	Likes    []Like    `json:"likes"`
+	UserID             uint              `json:"user_id" gorm:"constraint:OnDelete:CASCADE"`
+	User               User              `json:"user"`
+	Question           string            `json:"question"`
+	Answer             string            `json:"answer"`
+	RevealPolicy       string            `json:"reveal_policy" gorm:"size:30;default:immediate"`
+	RevealAfterCorrect int               `json:"reveal_after_correct"`
+	RevealAt           *time.Time        `json:"reveal_at"`
+	ValidUntil         *time.Time        `json:"valid_until"`                             // the answer is only true until this date
+	ReviewAfter        *time.Time        `json:"review_after"`                            // the author should double check the answer after this date
+	OutdatedFlaggedAt  *time.Time        `json:"outdated_flagged_at"`                     // set by the staleness job once either date has passed
+	LikeCount          int               `json:"like_count" gorm:"not null;default:0"`    // kept in step by Like.Save and Like.Delete
+	CommentCount       int               `json:"comment_count" gorm:"not null;default:0"` // kept in step by Comment.Save and DeleteCommentByID
+	HiddenAt           *time.Time        `json:"hidden_at"`                               // set when a moderator hides the post. Only its author can still see it
+	AlternateAnswers   []AlternateAnswer `json:"alternate_answers"`
+	Comments           []Comment         `json:"comments"`
+	Reactions          []Reaction        `json:"reactions"`
+}
+
+// An AlternateAnswer is another answer that also counts as correct,
+// usually added when the author accepts a dispute
+type AlternateAnswer struct {
+	gorm.Model
+	PostID uint   `json:"post_id" gorm:"index;constraint:OnDelete:CASCADE"`
+	Answer string `json:"answer"`
 }
 
 func (post *Post) Save() (*Post, error) {
@@ -23,35 +53,37 @@ func (post *Post) Save() (*Post, error) {
 	return post, nil
 }
 
-func FetchLikedPostsByUserID(userID uint) (*[]Post, error) {
-	var posts []Post
-	err := Database.Model(&Post{}).Joins("JOIN likes ON likes.post_id = posts.id").Where("likes.user_id = ?", userID).Joins("User").Find(&posts).Error
+func FetchLikedPostsByUserID(userID uint, viewerID uint, page Page) (*[]Post, PageInfo, error) {
+	query := Database.Model(&Post{}).Scopes(listedFor(viewerID, "posts")).
+		Joins("JOIN reactions ON reactions.post_id = posts.id AND reactions.deleted_at IS NULL").
+		Where("reactions.user_id = ? AND reactions.kind = ?", userID, ReactionLike)
+	posts, info, err := fetchPage(query, page, keyset{table: "posts"}, postCursor)
 	if err != nil {
-		return &[]Post{}, err
+		return &[]Post{}, PageInfo{}, err
 	}
-	return &posts, nil
+	return &posts, info, nil
 }
 
-func FetchAllPosts() (*[]Post, error) {
-	var posts []Post
-	err := Database.Find(&posts).Error
+// Fetches a page of every post, leaving out any the viewer shouldn't see (see listedFor)
+func FetchAllPosts(viewerID uint, page Page) (*[]Post, PageInfo, error) {
+	posts, info, err := fetchPage(Database.Model(&Post{}).Scopes(listedFor(viewerID, "posts")), page, keyset{table: "posts"}, postCursor)
 
 	if err != nil {
-		return &[]Post{}, err
+		return &[]Post{}, PageInfo{}, err
 	}
 
-	return &posts, nil
+	return &posts, info, nil
 }
 
-func FetchPostsByUserID(userID uint) (*[]Post, error) {
-	var posts []Post
-	err := Database.Where("user_id = ?", userID).Find(&posts).Error
+func FetchPostsByUserID(userID uint, viewerID uint, page Page) (*[]Post, PageInfo, error) {
+	query := Database.Model(&Post{}).Scopes(listedFor(viewerID, "posts")).Where("posts.user_id = ?", userID)
+	posts, info, err := fetchPage(query, page, keyset{table: "posts"}, postCursor)
 
 	if err != nil {
-		return &[]Post{}, err
+		return &[]Post{}, PageInfo{}, err
 	}
 
-	return &posts, nil
+	return &posts, info, nil
 }
 
 func FetchPostByID(id uint) (*Post, error) {
@@ -65,16 +97,30 @@ func FetchPostByID(id uint) (*Post, error) {
 	return &post, nil
 }
 
+// UpdatePost applies the updates to the post. If the answer changes, every attempt at the post
+// is re-scored against it in the same transaction, just like accepting a dispute.
 func UpdatePost(id uint, updates map[string]interface{}) (*Post, error) {
 	var post Post
 
-	// First find the post
-	if err := Database.First(&post, id).Error; err != nil {
-		return nil, err
-	}
+	err := Database.Transaction(func(tx *gorm.DB) error {
+		// First find the post
+		if err := tx.First(&post, id).Error; err != nil {
+			return err
+		}
+		previousAnswer := post.Answer
+
+		// Attempt to update the post in the database
+		if err := tx.Model(&post).Updates(updates).Error; err != nil {
+			return err
+		}
 
-	// Attempt to update the post in the database
-	if err := Database.Model(&post).Updates(updates).Error; err != nil {
+		if answer, exists := updates["answer"].(string); exists && answer != previousAnswer {
+			change := AnswerChange{Answer: answer}
+			return RescoreAttemptsForPost(tx, id, &change, time.Now())
+		}
+		return nil
+	})
+	if err != nil {
 		return nil, err
 	}
 
@@ -94,10 +140,177 @@ func DeletePost(id uint) error {
 		return err
 	}
 
-	// Delete the post record from the database
-	if err := Database.Delete(&post).Error; err != nil {
-		return err
+	// Delete the post record from the database, and give back the stake on any open bounty
+	return Database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Delete(&post).Error; err != nil {
+			return err
+		}
+		return cancelBounty(tx, post.ID, time.Now())
+	})
+}
+
+// Checks that a reveal policy (and the settings it needs) makes sense before we store it
+func ValidateRevealPolicy(policy string, revealAfterCorrect int, revealAt *time.Time) error {
+	switch policy {
+	case "", RevealImmediately, RevealAfterAttempt:
+		return nil
+	case RevealAfterCorrectCount:
+		if revealAfterCorrect < 1 {
+			return errors.New("reveal_after_correct must be at least 1")
+		}
+		return nil
+	case RevealAtTime:
+		if revealAt == nil {
+			return errors.New("reveal_at is required for the at_time reveal policy")
+		}
+		return nil
+	default:
+		return errors.New("unknown reveal policy")
+	}
+}
+
+// IsAnswerVisibleTo decides whether the viewer is allowed to see the answer,
+// based on the reveal policy the author picked. Authors can always see their own answer,
+// and so can anyone who has already answered correctly.
+func (post *Post) IsAnswerVisibleTo(viewerID uint) (bool, error) {
+	var progress AnswerProgress
+	var err error
+	if progress.Attempted, err = HasUserAttemptedPost(viewerID, post.ID); err != nil {
+		return false, err
+	}
+	if progress.AnsweredCorrectly, err = HasUserAnsweredCorrectly(viewerID, post.ID); err != nil {
+		return false, err
+	}
+	if progress.CorrectUsers, err = CountCorrectUsersForPost(post.ID); err != nil {
+		return false, err
 	}
+	return post.isAnswerVisible(viewerID, progress, time.Now()), nil
+}
+
+// KeepsAnswerHiddenUntilSolved reports whether the reveal policy keeps the answer from everyone
+// until somebody gets it right, at least until the given time. A bounty needs this, or it could
+// be won by reading the answer.
+func (post *Post) KeepsAnswerHiddenUntilSolved(until time.Time) bool {
+	switch post.RevealPolicy {
+	case RevealAfterCorrectCount:
+		return post.RevealAfterCorrect >= 1
+	case RevealAtTime:
+		return post.RevealAt != nil && !post.RevealAt.Before(until)
+	}
+	return false
+}
+
+// answerHiddenUntil is KeepsAnswerHiddenUntilSolved as a query: it leaves out posts whose answer
+// anyone could read before the given time, including after_correct_count posts that have
+// already had enough correct answers
+func answerHiddenUntil(until time.Time) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		correctUsers := Database.Model(&Attempt{}).
+			Select("COUNT(DISTINCT attempts.user_id)").
+			Where("attempts.post_id = posts.id AND attempts.correct")
+		return db.Where("((posts.reveal_policy = ? AND posts.reveal_after_correct > (?)) OR (posts.reveal_policy = ? AND posts.reveal_at >= ?))",
+			RevealAfterCorrectCount, correctUsers, RevealAtTime, until)
+	}
+}
+
+// AnswerProgress is what the reveal policies need to know about a viewer and a post
+type AnswerProgress struct {
+	Attempted         bool  // the viewer has had a go at the question
+	AnsweredCorrectly bool  // the viewer has got it right
+	CorrectUsers      int64 // how many different people have got it right
+}
 
-	return nil
+func (post *Post) isAnswerVisible(viewerID uint, progress AnswerProgress, now time.Time) bool {
+	if post.UserID == viewerID {
+		return true
+	}
+
+	switch post.RevealPolicy {
+	case "", RevealImmediately:
+		return true
+	case RevealAfterAttempt:
+		if progress.Attempted {
+			return true
+		}
+	case RevealAfterCorrectCount:
+		if progress.CorrectUsers >= int64(post.RevealAfterCorrect) {
+			return true
+		}
+	case RevealAtTime:
+		if post.RevealAt != nil && !now.Before(*post.RevealAt) {
+			return true
+		}
+	}
+
+	// The policy hasn't released the answer yet, but people who got it right already know it
+	return progress.AnsweredCorrectly
+}
+
+// AcceptedAnswers returns the main answer plus any alternates that also count as correct
+func (post *Post) AcceptedAnswers(db *gorm.DB) ([]string, error) {
+	var alternates []AlternateAnswer
+	if err := db.Where("post_id = ?", post.ID).Find(&alternates).Error; err != nil {
+		return nil, err
+	}
+
+	answers := []string{post.Answer}
+	for _, alternate := range alternates {
+		answers = append(answers, alternate.Answer)
+	}
+	return answers, nil
+}
+
+// IsGuessCorrect checks a guess against every accepted answer for the post
+func (post *Post) IsGuessCorrect(guess string) (bool, error) {
+	answers, err := post.AcceptedAnswers(Database)
+	if err != nil {
+		return false, err
+	}
+	return isCorrectForAny(guess, answers), nil
+}
+
+func isCorrectForAny(guess string, answers []string) bool {
+	for _, answer := range answers {
+		if answercheck.IsCorrect(guess, answer) {
+			return true
+		}
+	}
+	return false
+}
+
+// MayBeOutdated is true once the staleness job has flagged the post for review
+func (post *Post) MayBeOutdated() bool {
+	return post.OutdatedFlaggedAt != nil
+}
+
+// Fetches posts whose valid_until or review_after date has passed but haven't been flagged yet
+func FetchExpiredUnflaggedPosts(now time.Time) (*[]Post, error) {
+	var posts []Post
+	err := Database.Where("outdated_flagged_at IS NULL").
+		Where("valid_until <= ? OR review_after <= ?", now, now).
+		Find(&posts).Error
+	if err != nil {
+		return &[]Post{}, err
+	}
+	return &posts, nil
+}
+
+// FlagPostAsOutdated marks the post as possibly outdated. It returns false if the
+// post was already flagged (e.g. by another run of the job), so callers don't notify twice.
+func FlagPostAsOutdated(id uint, now time.Time) (bool, error) {
+	result := Database.Model(&Post{}).Where("id = ? AND outdated_flagged_at IS NULL", id).Update("outdated_flagged_at", now)
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected > 0, nil
+}
+
+// Fetches the user's posts that have been flagged as possibly outdated
+func FetchPostsNeedingReviewByUserID(userID uint) (*[]Post, error) {
+	var posts []Post
+	err := Database.Where("user_id = ? AND outdated_flagged_at IS NOT NULL", userID).Order("outdated_flagged_at").Find(&posts).Error
+	if err != nil {
+		return &[]Post{}, err
+	}
+	return &posts, nil
 }
@@ -0,0 +1,191 @@
+package models
+
+import (
+	"time"
+)
+
+// PostExtras holds everything that goes alongside a page of posts when they're sent to a
+// viewer: authors, comments (and their authors), reactions to both, what the viewer has liked
+// and attempted, bounties, @mentions in both, and the accepted answers (to spot comments that
+// give them away). Like counts are stored on the post itself. LoadPostExtras fills it in a fixed
+// number of queries however many posts there are, rather than a handful of queries per post.
+type PostExtras struct {
+	Users            map[uint]User
+	Comments         map[uint][]Comment
+	LikedByViewer    map[uint]bool
+	PostReactions    map[uint]ReactionSummary
+	CommentReactions map[uint]ReactionSummary
+	Bounties         map[uint]Bounty
+	Progress         map[uint]AnswerProgress
+	Answers          map[uint][]string
+	PostMentions     map[uint][]Mention
+	CommentMentions  map[uint][]Mention
+	viewerID         uint
+	now              time.Time
+}
+
+// LoadPostExtras loads the extras for the given posts as seen by the viewer
+func LoadPostExtras(posts []Post, viewerID uint) (*PostExtras, error) {
+	extras := &PostExtras{
+		Users:         map[uint]User{},
+		Comments:      map[uint][]Comment{},
+		LikedByViewer: map[uint]bool{},
+		Bounties:      map[uint]Bounty{},
+		Progress:      map[uint]AnswerProgress{},
+		Answers:       map[uint][]string{},
+		viewerID:      viewerID,
+		now:           time.Now(),
+	}
+	if len(posts) == 0 {
+		return extras, nil
+	}
+
+	postIDs := make([]uint, 0, len(posts))
+	userIDs := make([]uint, 0, len(posts))
+	for _, post := range posts {
+		postIDs = append(postIDs, post.ID)
+		userIDs = append(userIDs, post.UserID)
+		extras.Answers[post.ID] = []string{post.Answer}
+	}
+
+	// Comments, oldest first, leaving out any the viewer shouldn't see
+	var comments []Comment
+	if err := visibleComments(Database).Scopes(listedFor(viewerID, "comments")).Where("post_id IN ?", postIDs).Order("created_at, id").Find(&comments).Error; err != nil {
+		return nil, err
+	}
+	for _, comment := range comments {
+		extras.Comments[comment.PostID] = append(extras.Comments[comment.PostID], comment)
+		userIDs = append(userIDs, comment.UserID)
+	}
+
+	// Post and comment authors in one go
+	users, err := FindUsersByIDs(userIDs)
+	if err != nil {
+		return nil, err
+	}
+	extras.Users = users
+
+	// Which of the posts the viewer has liked
+	var likedPostIDs []uint
+	if err := Database.Model(&Reaction{}).Where("user_id = ? AND post_id IN ? AND kind = ?", viewerID, postIDs, ReactionLike).
+		Pluck("post_id", &likedPostIDs).Error; err != nil {
+		return nil, err
+	}
+	for _, postID := range likedPostIDs {
+		extras.LikedByViewer[postID] = true
+	}
+
+	// Reactions to the posts and their comments
+	postTargets := make([]ReactionTarget, 0, len(postIDs))
+	for _, postID := range postIDs {
+		postTargets = append(postTargets, OnPost(postID))
+	}
+	postReactions, err := FetchReactionSummaries(postTargets, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	extras.PostReactions = postReactions
+
+	commentTargets := make([]ReactionTarget, 0, len(comments))
+	for _, comment := range comments {
+		commentTargets = append(commentTargets, OnComment(comment.ID))
+	}
+	commentReactions, err := FetchReactionSummaries(commentTargets, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	extras.CommentReactions = commentReactions
+
+	// Mentions in the questions and comments
+	if extras.PostMentions, err = FetchMentions(postTargets); err != nil {
+		return nil, err
+	}
+	if extras.CommentMentions, err = FetchMentions(commentTargets); err != nil {
+		return nil, err
+	}
+
+	// Bounties
+	var bounties []Bounty
+	if err := Database.Where("post_id IN ?", postIDs).Find(&bounties).Error; err != nil {
+		return nil, err
+	}
+	for _, bounty := range bounties {
+		extras.Bounties[bounty.PostID] = bounty
+	}
+
+	// Alternate answers, which count as giving the answer away too
+	var alternates []AlternateAnswer
+	if err := Database.Where("post_id IN ?", postIDs).Find(&alternates).Error; err != nil {
+		return nil, err
+	}
+	for _, alternate := range alternates {
+		extras.Answers[alternate.PostID] = append(extras.Answers[alternate.PostID], alternate.Answer)
+	}
+
+	// What the reveal policies need: the viewer's attempts and how many people got each post right
+	progress, err := loadAnswerProgress(postIDs, viewerID)
+	if err != nil {
+		return nil, err
+	}
+	extras.Progress = progress
+
+	return extras, nil
+}
+
+// loadAnswerProgress works out each post's AnswerProgress for the viewer in one query
+func loadAnswerProgress(postIDs []uint, viewerID uint) (map[uint]AnswerProgress, error) {
+	var attempts []struct {
+		PostID       uint
+		Viewer       bool
+		ViewerRight  bool
+		CorrectUsers int64
+	}
+	if err := Database.Model(&Attempt{}).
+		Select(`post_id,
+			BOOL_OR(user_id = ?) AS viewer,
+			BOOL_OR(user_id = ? AND correct) AS viewer_right,
+			COUNT(DISTINCT user_id) FILTER (WHERE correct) AS correct_users`, viewerID, viewerID).
+		Where("post_id IN ?", postIDs).Group("post_id").Scan(&attempts).Error; err != nil {
+		return nil, err
+	}
+
+	progress := make(map[uint]AnswerProgress, len(attempts))
+	for _, row := range attempts {
+		progress[row.PostID] = AnswerProgress{
+			Attempted:         row.Viewer,
+			AnsweredCorrectly: row.ViewerRight,
+			CorrectUsers:      row.CorrectUsers,
+		}
+	}
+	return progress, nil
+}
+
+// AnswersVisibleTo decides which of the posts' answers the viewer can see, keyed by post, in one
+// query rather than one per post (see Post.IsAnswerVisibleTo)
+func AnswersVisibleTo(posts []Post, viewerID uint) (map[uint]bool, error) {
+	postIDs := make([]uint, 0, len(posts))
+	for _, post := range posts {
+		postIDs = append(postIDs, post.ID)
+	}
+	progress, err := loadAnswerProgress(postIDs, viewerID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	visible := make(map[uint]bool, len(posts))
+	for index := range posts {
+		visible[posts[index].ID] = posts[index].isAnswerVisible(viewerID, progress[posts[index].ID], now)
+	}
+	return visible, nil
+}
+
+// IsAnswerVisible decides whether the viewer the extras were loaded for can see the post's answer
+func (extras *PostExtras) IsAnswerVisible(post *Post) bool {
+	return post.isAnswerVisible(extras.viewerID, extras.Progress[post.ID], extras.now)
+}
+
+// IsSpoilerHidden decides whether a comment on the post gives the answer away to the viewer
+func (extras *PostExtras) IsSpoilerHidden(post *Post, comment *Comment) bool {
+	return comment.isSpoilerHidden(extras.viewerID, extras.IsAnswerVisible(post), extras.Answers[post.ID])
+}
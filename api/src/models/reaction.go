@@ -0,0 +1,286 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/makersacademy/go-react-acebook-template/api/src/events"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// The kinds of reaction people can leave. A "like" is just one kind of reaction, and likes on
+// posts are also counted in the post's like_count so NumOfLikes keeps working as it always has.
+const (
+	ReactionLike      = "like"
+	ReactionLearned   = "learned_something"
+	ReactionTooEasy   = "too_easy"
+	ReactionMindBlown = "mind_blown"
+	ReactionDisputed  = "disputed"
+)
+
+var ReactionKinds = []string{ReactionLike, ReactionLearned, ReactionTooEasy, ReactionMindBlown, ReactionDisputed}
+
+func IsReactionKind(kind string) bool {
+	for _, reactionKind := range ReactionKinds {
+		if kind == reactionKind {
+			return true
+		}
+	}
+	return false
+}
+
+// A Reaction is a user reacting to either a post or a comment (only one of PostID and CommentID
+// is set). A user can leave each kind of reaction once per post or comment. The unique indexes
+// skip soft deleted reactions, so a reaction can be taken back (soft deleted) and left again later.
+type Reaction struct {
+	gorm.Model
+	UserID    uint     `json:"user_id" gorm:"uniqueIndex:idx_reactions_post,where:deleted_at IS NULL;uniqueIndex:idx_reactions_comment,where:deleted_at IS NULL;constraint:OnDelete:CASCADE"`
+	PostID    *uint    `json:"post_id" gorm:"uniqueIndex:idx_reactions_post,where:deleted_at IS NULL;index"`
+	CommentID *uint    `json:"comment_id" gorm:"uniqueIndex:idx_reactions_comment,where:deleted_at IS NULL;index"`
+	Kind      string   `json:"kind" gorm:"size:30;uniqueIndex:idx_reactions_post,where:deleted_at IS NULL;uniqueIndex:idx_reactions_comment,where:deleted_at IS NULL"`
+	User      User     `json:"-"`
+	Post      *Post    `json:"-"`
+	Comment   *Comment `json:"-"`
+}
+
+// A ReactionTarget is the post or comment being reacted to (or mentioned in)
+type ReactionTarget struct {
+	column string // "post_id" or "comment_id"
+	ID     uint
+}
+
+func OnPost(postID uint) ReactionTarget {
+	return ReactionTarget{column: "post_id", ID: postID}
+}
+
+func OnComment(commentID uint) ReactionTarget {
+	return ReactionTarget{column: "comment_id", ID: commentID}
+}
+
+func (target ReactionTarget) IsPost() bool {
+	return target.column == "post_id"
+}
+
+// A ReactionSummary is what gets sent with a post or comment: how many of each kind of reaction
+// it has, and which kinds the viewer has left
+type ReactionSummary struct {
+	Counts map[string]int
+	Mine   []string
+}
+
+// React leaves a reaction for the user if they haven't already left that kind. It's safe to call
+// again (e.g. on a retry or a double click): the unique index turns a second reaction into a
+// no-op. It returns the user's reaction, and whether this call created it.
+func React(userID uint, target ReactionTarget, kind string) (*Reaction, bool, error) {
+	reaction := Reaction{UserID: userID, Kind: kind}
+	if target.IsPost() {
+		reaction.PostID = &target.ID
+	} else {
+		reaction.CommentID = &target.ID
+	}
+	created := false
+
+	err := Database.Transaction(func(tx *gorm.DB) error {
+		result := tx.Clauses(clause.OnConflict{
+			Columns:     []clause.Column{{Name: "user_id"}, {Name: target.column}, {Name: "kind"}},
+			TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "deleted_at IS NULL"}}},
+			DoNothing:   true,
+		}).Create(&reaction)
+		if result.Error != nil {
+			return result.Error
+		}
+
+		if result.RowsAffected == 0 {
+			// Already reacted, so hand back the reaction that's there
+			return tx.Where("user_id = ? AND "+target.column+" = ? AND kind = ?", userID, target.ID, kind).First(&reaction).Error
+		}
+
+		created = true
+		if target.IsPost() && kind == ReactionLike {
+			return adjustPostCounter(tx, target.ID, "like_count", 1)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, false, err
+	}
+
+	if created && target.IsPost() && kind == ReactionLike {
+		publishLikeEvent(events.LikeCreated, &reaction)
+	}
+	return &reaction, created, nil
+}
+
+// Unreact takes back the user's reaction of that kind if there is one. Like React it's safe to
+// call again. It returns the reaction that was removed, or nil if there wasn't one.
+func Unreact(userID uint, target ReactionTarget, kind string) (*Reaction, error) {
+	var reaction Reaction
+	removed := false
+
+	err := Database.Transaction(func(tx *gorm.DB) error {
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("user_id = ? AND "+target.column+" = ? AND kind = ?", userID, target.ID, kind).
+			First(&reaction).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil // not reacted, so nothing to do
+		}
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Delete(&reaction).Error; err != nil {
+			return err
+		}
+
+		removed = true
+		if target.IsPost() && kind == ReactionLike {
+			return adjustPostCounter(tx, target.ID, "like_count", -1)
+		}
+		return nil
+	})
+	if err != nil || !removed {
+		return nil, err
+	}
+
+	if target.IsPost() && kind == ReactionLike {
+		publishLikeEvent(events.LikeDeleted, &reaction)
+	}
+	return &reaction, nil
+}
+
+// FetchReactionSummaries works out the reaction summary for each of the given posts or comments
+// (depending on the target column) in two queries, however many there are
+func FetchReactionSummaries(targets []ReactionTarget, viewerID uint) (map[uint]ReactionSummary, error) {
+	summaries := map[uint]ReactionSummary{}
+	if len(targets) == 0 {
+		return summaries, nil
+	}
+
+	column := targets[0].column
+	ids := make([]uint, 0, len(targets))
+	for _, target := range targets {
+		ids = append(ids, target.ID)
+		summaries[target.ID] = ReactionSummary{Counts: map[string]int{}, Mine: []string{}}
+	}
+
+	var counts []struct {
+		TargetID uint
+		Kind     string
+		Count    int
+	}
+	if err := Database.Model(&Reaction{}).
+		Select(column+" AS target_id, kind, COUNT(*) AS count").
+		Where(column+" IN ?", ids).
+		Group(column + ", kind").
+		Scan(&counts).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range counts {
+		summaries[row.TargetID].Counts[row.Kind] = row.Count
+	}
+
+	var mine []struct {
+		TargetID uint
+		Kind     string
+	}
+	if err := Database.Model(&Reaction{}).
+		Select(column+" AS target_id, kind").
+		Where("user_id = ? AND "+column+" IN ?", viewerID, ids).
+		Order("kind").
+		Scan(&mine).Error; err != nil {
+		return nil, err
+	}
+	for _, row := range mine {
+		summary := summaries[row.TargetID]
+		summary.Mine = append(summary.Mine, row.Kind)
+		summaries[row.TargetID] = summary
+	}
+
+	return summaries, nil
+}
+
+// LikePost likes a post for the user. See React.
+func LikePost(userID uint, postID uint) (*Reaction, bool, error) {
+	return React(userID, OnPost(postID), ReactionLike)
+}
+
+// UnlikePost removes the user's like from a post. See Unreact.
+func UnlikePost(userID uint, postID uint) (*Reaction, error) {
+	return Unreact(userID, OnPost(postID), ReactionLike)
+}
+
+// Fetches one page of a post's likes, newest first
+func FetchLikesPageByPostID(postID uint, page Page) (*[]Reaction, PageInfo, error) {
+	query := Database.Model(&Reaction{}).Where("reactions.post_id = ? AND reactions.kind = ?", postID, ReactionLike)
+	likes, info, err := fetchPage(query, page, keyset{table: "reactions"}, reactionCursor)
+	if err != nil {
+		return &[]Reaction{}, PageInfo{}, err
+	}
+	return &likes, info, nil
+}
+
+// This below is used to check if a user has already liked a post
+// It returns the like if it exists, otherwise it returns nil
+// This helps give us the like/unlike functionality on the frontend
+func FindLikeByUserIDAndPostID(userID uint, postID uint) (*Reaction, error) {
+	var like Reaction
+	err := Database.Where("user_id = ? AND post_id = ? AND kind = ?", userID, postID, ReactionLike).First(&like).Error
+	if err != nil {
+		return nil, err
+	}
+	return &like, nil
+}
+
+// MigrateLikesToReactions moves rows from the old likes table into reactions as "like" reactions,
+// keeping their IDs so XP already awarded for them still lines up. Likes whose ID has since been
+// used by a new reaction get a fresh one. The likes table is only dropped once every live like
+// is in reactions; if the counts don't match nothing changes and an error is returned.
+// It does nothing once the likes table has gone.
+func MigrateLikesToReactions() error {
+	if !Database.Migrator().HasTable("likes") {
+		return nil
+	}
+
+	return Database.Transaction(func(tx *gorm.DB) error {
+		// ON CONFLICT skips any duplicate likes, which the old table didn't prevent
+		if err := tx.Exec(`INSERT INTO reactions (id, created_at, updated_at, deleted_at, user_id, post_id, kind)
+			SELECT id, created_at, updated_at, deleted_at, user_id, post_id, ? FROM likes ORDER BY id
+			ON CONFLICT DO NOTHING`, ReactionLike).Error; err != nil {
+			return err
+		}
+
+		// New reactions need IDs after the copied ones
+		if err := tx.Exec(`SELECT setval(pg_get_serial_sequence('reactions', 'id'), COALESCE(MAX(id), 0) + 1, false) FROM reactions`).Error; err != nil {
+			return err
+		}
+
+		// Any live like that didn't make it across had its ID taken, so copy it with a new one
+		if err := tx.Exec(`INSERT INTO reactions (created_at, updated_at, user_id, post_id, kind)
+			SELECT DISTINCT ON (user_id, post_id) created_at, updated_at, user_id, post_id, ? FROM likes
+			WHERE deleted_at IS NULL AND NOT EXISTS (`+likeCopiedSQL+`)
+			ORDER BY user_id, post_id, id
+			ON CONFLICT DO NOTHING`, ReactionLike, ReactionLike).Error; err != nil {
+			return err
+		}
+
+		// Check every live like is now a reaction before getting rid of the originals
+		var likes, copied int64
+		if err := tx.Raw(`SELECT COUNT(DISTINCT (user_id, post_id)) FROM likes WHERE deleted_at IS NULL`).Scan(&likes).Error; err != nil {
+			return err
+		}
+		if err := tx.Raw(`SELECT COUNT(DISTINCT (user_id, post_id)) FROM likes
+			WHERE deleted_at IS NULL AND EXISTS (`+likeCopiedSQL+`)`, ReactionLike).Scan(&copied).Error; err != nil {
+			return err
+		}
+		if copied != likes {
+			return fmt.Errorf("only %d of %d likes were copied into reactions, so the likes table has been kept", copied, likes)
+		}
+
+		return tx.Exec("DROP TABLE likes").Error
+	})
+}
+
+// Whether a row of the old likes table has a live "like" reaction (the kind is the only parameter)
+const likeCopiedSQL = `SELECT 1 FROM reactions WHERE reactions.user_id = likes.user_id
+	AND reactions.post_id = likes.post_id AND reactions.kind = ? AND reactions.deleted_at IS NULL`
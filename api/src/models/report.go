@@ -0,0 +1,313 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// What can be reported
+const (
+	ReportPost    = "post"
+	ReportComment = "comment"
+	ReportUser    = "user"
+)
+
+// Why something was reported
+var ReportReasons = []string{"spam", "harassment", "hate_speech", "inappropriate", "misinformation", "spoiler", "other"}
+
+// Where a report is in the moderation queue
+const (
+	ReportOpen     = "open"     // waiting for a moderator
+	ReportClaimed  = "claimed"  // a moderator is looking at it
+	ReportResolved = "resolved" // a moderator has dealt with it
+)
+
+// What a moderator can do about a report
+const (
+	ModerationDismiss = "dismiss" // nothing wrong, leave it be
+	ModerationHide    = "hide"    // hide the post or comment from everyone but its author
+	ModerationWarn    = "warn"    // send the author (or reported user) a warning
+	ModerationSuspend = "suspend" // stop the author (or reported user) signing in or changing anything for a while
+)
+
+// Moderation log entries also record claims
+const ModerationClaim = "claim"
+
+var (
+	ErrReportResolved   = errors.New("report has already been resolved")
+	ErrReportClaimed    = errors.New("report has been claimed by another moderator")
+	ErrReportNotClaimed = errors.New("report must be claimed before it's resolved")
+	ErrInvalidAction    = errors.New("that action can't be used on this report")
+)
+
+// A Report is someone asking the moderators to look at a post, comment or user.
+// Someone can only have one unresolved report about the same thing at a time.
+type Report struct {
+	gorm.Model
+	ReporterID   uint       `json:"reporter_id" gorm:"uniqueIndex:idx_reports_unresolved,where:status <> 'resolved' AND deleted_at IS NULL;constraint:OnDelete:CASCADE"`
+	TargetType   string     `json:"target_type" gorm:"size:10;uniqueIndex:idx_reports_unresolved,where:status <> 'resolved' AND deleted_at IS NULL;index:idx_reports_target"`
+	TargetID     uint       `json:"target_id" gorm:"uniqueIndex:idx_reports_unresolved,where:status <> 'resolved' AND deleted_at IS NULL;index:idx_reports_target"`
+	Reason       string     `json:"reason" gorm:"size:30"`
+	Details      string     `json:"details"`
+	Status       string     `json:"status" gorm:"size:20;not null;default:open;index"`
+	ClaimedByID  *uint      `json:"claimed_by_id"`
+	ClaimedAt    *time.Time `json:"claimed_at"`
+	ResolvedByID *uint      `json:"resolved_by_id"`
+	ResolvedAt   *time.Time `json:"resolved_at"`
+	Action       string     `json:"action" gorm:"size:20"` // what the moderator did, once it's resolved
+	Reporter     User       `json:"-"`
+}
+
+// A ModerationLogEntry records something a moderator did, so there's an audit trail of every
+// claim and decision. ReportID is the report it was done for.
+type ModerationLogEntry struct {
+	gorm.Model
+	ModeratorID uint   `json:"moderator_id" gorm:"index"`
+	ReportID    *uint  `json:"report_id" gorm:"index"`
+	Action      string `json:"action" gorm:"size:20"`
+	TargetType  string `json:"target_type" gorm:"size:10;index:idx_moderation_log_target"`
+	TargetID    uint   `json:"target_id" gorm:"index:idx_moderation_log_target"`
+	Note        string `json:"note"`
+	Moderator   User   `json:"-"`
+}
+
+func IsReportReason(reason string) bool {
+	for _, known := range ReportReasons {
+		if reason == known {
+			return true
+		}
+	}
+	return false
+}
+
+// ReportTargetAuthor finds who is responsible for the reported thing: the author of a post or
+// comment, or the user themselves. It fails with gorm.ErrRecordNotFound if it doesn't exist.
+func ReportTargetAuthor(db *gorm.DB, targetType string, targetID uint) (uint, error) {
+	switch targetType {
+	case ReportPost:
+		var post Post
+		err := db.Select("id", "user_id").First(&post, targetID).Error
+		return post.UserID, err
+	case ReportComment:
+		var comment Comment
+		err := db.Select("id", "user_id").First(&comment, targetID).Error
+		return comment.UserID, err
+	case ReportUser:
+		var user User
+		err := db.Select("id").First(&user, targetID).Error
+		return user.ID, err
+	}
+	return 0, gorm.ErrRecordNotFound
+}
+
+// CreateReport files a report. If the reporter already has an unresolved report about the same
+// thing, that one is returned instead, with created false.
+func CreateReport(report *Report) (*Report, bool, error) {
+	report.Status = ReportOpen
+	result := Database.Clauses(clause.OnConflict{
+		Columns:     []clause.Column{{Name: "reporter_id"}, {Name: "target_type"}, {Name: "target_id"}},
+		TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "status <> 'resolved' AND deleted_at IS NULL"}}},
+		DoNothing:   true,
+	}).Create(report)
+	if result.Error != nil {
+		return &Report{}, false, result.Error
+	}
+	if result.RowsAffected > 0 {
+		return report, true, nil
+	}
+
+	var existing Report
+	err := Database.Where("reporter_id = ? AND target_type = ? AND target_id = ? AND status <> ?",
+		report.ReporterID, report.TargetType, report.TargetID, ReportResolved).First(&existing).Error
+	if err != nil {
+		return &Report{}, false, err
+	}
+	return &existing, false, nil
+}
+
+func FetchReportByID(id uint) (*Report, error) {
+	var report Report
+	if err := Database.Preload("Reporter").First(&report, id).Error; err != nil {
+		return &Report{}, err
+	}
+	return &report, nil
+}
+
+// Fetches a page of reports with any of the statuses, oldest first (the order they should be dealt with)
+func FetchReportsPage(statuses []string, page Page) (*[]Report, PageInfo, error) {
+	query := Database.Model(&Report{}).Preload("Reporter").Where("reports.status IN ?", statuses)
+	reports, info, err := fetchPage(query, page, keyset{table: "reports", ascending: true}, reportCursor)
+	if err != nil {
+		return &[]Report{}, PageInfo{}, err
+	}
+	return &reports, info, nil
+}
+
+func reportCursor(report *Report) (Cursor, error) {
+	return Cursor{CreatedAt: report.CreatedAt, ID: report.ID}, nil
+}
+
+// ClaimReport lets a moderator say they're dealing with a report, so two moderators don't work on
+// the same one. Claiming a report you've already claimed does nothing. It fails with
+// ErrReportClaimed if another moderator has claimed it, and ErrReportResolved if it's been dealt with.
+func ClaimReport(id uint, moderatorID uint, now time.Time) (*Report, error) {
+	var report Report
+	err := Database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&report, id).Error; err != nil {
+			return err
+		}
+		switch {
+		case report.Status == ReportResolved:
+			return ErrReportResolved
+		case report.ClaimedByID != nil && *report.ClaimedByID == moderatorID:
+			return nil
+		case report.ClaimedByID != nil:
+			return ErrReportClaimed
+		}
+
+		report.Status, report.ClaimedByID, report.ClaimedAt = ReportClaimed, &moderatorID, &now
+		if err := tx.Select("status", "claimed_by_id", "claimed_at").Save(&report).Error; err != nil {
+			return err
+		}
+		return logModeration(tx, moderatorID, &report, ModerationClaim, "")
+	})
+	if err != nil {
+		return &Report{}, err
+	}
+	return FetchReportByID(report.ID)
+}
+
+// A Resolution is what a moderator decided to do about a report
+type Resolution struct {
+	Action         string
+	Note           string     // why, for the audit trail (and included in warnings)
+	SuspendedUntil *time.Time // only for ModerationSuspend
+}
+
+// ResolveReport carries out the moderator's decision on a report they've claimed, and resolves
+// every other unresolved report about the same thing along with it. It fails with
+// ErrReportNotClaimed if the moderator hasn't claimed it, and ErrInvalidAction if the action
+// doesn't fit (e.g. hiding a user).
+func ResolveReport(id uint, moderatorID uint, resolution Resolution, now time.Time) (*Report, error) {
+	var report Report
+	var warning *Notification
+	err := Database.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&report, id).Error; err != nil {
+			return err
+		}
+		if report.Status == ReportResolved {
+			return ErrReportResolved
+		}
+		if report.ClaimedByID == nil || *report.ClaimedByID != moderatorID {
+			return ErrReportNotClaimed
+		}
+
+		authorID, err := ReportTargetAuthor(tx.Unscoped(), report.TargetType, report.TargetID)
+		if err != nil {
+			return err
+		}
+
+		// ========== Carry out the decision ==========
+		switch resolution.Action {
+		case ModerationDismiss:
+		case ModerationHide:
+			var table interface{}
+			switch report.TargetType {
+			case ReportPost:
+				table = &Post{}
+			case ReportComment:
+				table = &Comment{}
+			default:
+				return ErrInvalidAction // only posts and comments can be hidden
+			}
+			if err := tx.Unscoped().Model(table).Where("id = ?", report.TargetID).UpdateColumn("hidden_at", now).Error; err != nil {
+				return err
+			}
+			if report.TargetType == ReportPost {
+				if err := cancelBounty(tx, report.TargetID, now); err != nil {
+					return err
+				}
+			}
+		case ModerationWarn:
+			message := fmt.Sprintf("A moderator has warned you about your %s", report.TargetType)
+			if report.TargetType == ReportUser {
+				message = "A moderator has warned you about your behaviour"
+			}
+			if resolution.Note != "" {
+				message += ": " + resolution.Note
+			}
+			warning = &Notification{UserID: authorID, Kind: NotificationModeratorWarning, Message: message}
+			if report.TargetType == ReportPost {
+				warning.PostID = &report.TargetID
+			}
+			if err := tx.Create(warning).Error; err != nil {
+				return err
+			}
+		case ModerationSuspend:
+			if resolution.SuspendedUntil == nil || !resolution.SuspendedUntil.After(now) {
+				return ErrInvalidAction
+			}
+			// A longer suspension that's already running isn't cut short
+			err := tx.Unscoped().Model(&User{}).
+				Where("id = ? AND (suspended_until IS NULL OR suspended_until < ?)", authorID, *resolution.SuspendedUntil).
+				UpdateColumn("suspended_until", *resolution.SuspendedUntil).Error
+			if err != nil {
+				return err
+			}
+		default:
+			return ErrInvalidAction
+		}
+
+		// ========== Close this and any other reports about the same thing ==========
+		err = tx.Model(&Report{}).
+			Where("target_type = ? AND target_id = ? AND status <> ?", report.TargetType, report.TargetID, ReportResolved).
+			Updates(map[string]interface{}{"status": ReportResolved, "resolved_by_id": moderatorID, "resolved_at": now, "action": resolution.Action}).Error
+		if err != nil {
+			return err
+		}
+		report.Status, report.ResolvedByID, report.ResolvedAt, report.Action = ReportResolved, &moderatorID, &now, resolution.Action
+
+		return logModeration(tx, moderatorID, &report, resolution.Action, resolution.Note)
+	})
+	if err != nil {
+		return &Report{}, err
+	}
+
+	if warning != nil {
+		publishNotification(warning)
+	}
+	return FetchReportByID(report.ID)
+}
+
+func logModeration(tx *gorm.DB, moderatorID uint, report *Report, action string, note string) error {
+	return tx.Create(&ModerationLogEntry{
+		ModeratorID: moderatorID,
+		ReportID:    &report.ID,
+		Action:      action,
+		TargetType:  report.TargetType,
+		TargetID:    report.TargetID,
+		Note:        note,
+	}).Error
+}
+
+func moderationLogCursor(entry *ModerationLogEntry) (Cursor, error) {
+	return Cursor{CreatedAt: entry.CreatedAt, ID: entry.ID}, nil
+}
+
+// Fetches a page of the moderation log, newest first. If targetType is set, only entries
+// about that post, comment or user are included.
+func FetchModerationLogPage(targetType string, targetID uint, page Page) (*[]ModerationLogEntry, PageInfo, error) {
+	query := Database.Model(&ModerationLogEntry{}).Preload("Moderator")
+	if targetType != "" {
+		query = query.Where("moderation_log_entries.target_type = ? AND moderation_log_entries.target_id = ?", targetType, targetID)
+	}
+	entries, info, err := fetchPage(query, page, keyset{table: "moderation_log_entries"}, moderationLogCursor)
+	if err != nil {
+		return &[]ModerationLogEntry{}, PageInfo{}, err
+	}
+	return &entries, info, nil
+}
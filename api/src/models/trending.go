@@ -0,0 +1,133 @@
+package models
+
+import (
+	"math"
+	"time"
+
+	"gorm.io/gorm/clause"
+)
+
+// Feed sort orders
+const (
+	SortNew      = "new"      // newest first
+	SortTop      = "top"      // most liked first
+	SortTrending = "trending" // highest trending score first
+)
+
+// A PostScore holds the precomputed trending score for a post, along with the counts it was
+// worked out from. Scores are refreshed by a background job so the feed never has to count
+// likes, comments and attempts while someone is waiting for it.
+type PostScore struct {
+	PostID     uint      `json:"post_id" gorm:"primaryKey;autoIncrement:false;constraint:OnDelete:CASCADE"`
+	Likes      int       `json:"likes"`
+	Comments   int       `json:"comments"`
+	Attempts   int       `json:"attempts"`
+	AnswerRate float64   `json:"answer_rate"` // fraction of people who tried the question and got it right
+	Score      float64   `json:"score" gorm:"index"`
+	ComputedAt time.Time `json:"computed_at"`
+	Post       Post      `json:"-"`
+}
+
+// TrendingWeights control how much each kind of activity counts towards a post's score,
+// and how quickly scores fall off as posts get older
+type TrendingWeights struct {
+	Like    float64
+	Comment float64
+	Attempt float64
+	Gravity float64
+}
+
+// TrendingScore works out a Hacker News style score: activity divided by age raised to a
+// gravity, so newer posts need less activity to rank highly. Questions that stump people
+// get up to double credit for their activity.
+func (weights TrendingWeights) TrendingScore(score PostScore, age time.Duration) float64 {
+	activity := weights.Like*float64(score.Likes) +
+		weights.Comment*float64(score.Comments) +
+		weights.Attempt*float64(score.Attempts)
+	if score.Attempts > 0 {
+		activity *= 2 - score.AnswerRate
+	}
+
+	ageHours := math.Max(age.Hours(), 0)
+	return activity / math.Pow(ageHours+2, weights.Gravity)
+}
+
+// RecomputePostScores counts up the activity on every post and saves a fresh trending score for each
+func RecomputePostScores(weights TrendingWeights, now time.Time) error {
+	var rows []struct {
+		PostID    uint
+		CreatedAt time.Time
+		Likes     int
+		Comments  int
+		Attempts  int
+		Correct   int
+	}
+	err := Database.Raw(`SELECT posts.id AS post_id, posts.created_at,
+			posts.like_count AS likes,
+			posts.comment_count AS comments,
+			(SELECT COUNT(DISTINCT attempts.user_id) FROM attempts WHERE attempts.post_id = posts.id AND attempts.deleted_at IS NULL) AS attempts,
+			(SELECT COUNT(DISTINCT attempts.user_id) FROM attempts WHERE attempts.post_id = posts.id AND attempts.deleted_at IS NULL AND attempts.correct) AS correct
+		FROM posts WHERE posts.deleted_at IS NULL`).Scan(&rows).Error
+	if err != nil {
+		return err
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	scores := make([]PostScore, 0, len(rows))
+	for _, row := range rows {
+		score := PostScore{
+			PostID:     row.PostID,
+			Likes:      row.Likes,
+			Comments:   row.Comments,
+			Attempts:   row.Attempts,
+			ComputedAt: now,
+		}
+		if row.Attempts > 0 {
+			score.AnswerRate = float64(row.Correct) / float64(row.Attempts)
+		}
+		score.Score = weights.TrendingScore(score, now.Sub(row.CreatedAt))
+		scores = append(scores, score)
+	}
+
+	return Database.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "post_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{"likes", "comments", "attempts", "answer_rate", "score", "computed_at"}),
+	}).CreateInBatches(&scores, 500).Error
+}
+
+// The SQL each sort ranks posts by before falling back to newest first
+var sortRanks = map[string]string{
+	SortTrending: "COALESCE(post_scores.score, 0)",
+	SortTop:      "posts.like_count",
+}
+
+// FetchSortedPosts fetches a page of posts in the given sort order. If since is set, only posts
+// created after it are included. Posts created since the scores were last worked out haven't got
+// a score yet, so they're treated as zero (newest first) until the next run. Scores can change
+// between runs, so a ranked feed may repeat or skip a post across pages when they're refreshed.
+func FetchSortedPosts(sort string, since *time.Time, viewerID uint, page Page) (*[]Post, PageInfo, error) {
+	query := Database.Model(&Post{}).Scopes(listedFor(viewerID, "posts")).Joins("LEFT JOIN post_scores ON post_scores.post_id = posts.id")
+	if since != nil {
+		query = query.Where("posts.created_at >= ?", *since)
+	}
+
+	keys := keyset{table: "posts", rank: sortRanks[sort]}
+	cursorOf := postCursor
+	if keys.rank != "" {
+		// The rank isn't part of a Post, so look it up for the posts at either end of the page
+		cursorOf = func(post *Post) (Cursor, error) {
+			var rank float64
+			err := Database.Model(&Post{}).Joins("LEFT JOIN post_scores ON post_scores.post_id = posts.id").
+				Where("posts.id = ?", post.ID).Select(keys.rank).Scan(&rank).Error
+			return Cursor{CreatedAt: post.CreatedAt, ID: post.ID, Rank: &rank}, err
+		}
+	}
+
+	posts, info, err := fetchPage(query, page, keys, cursorOf)
+	if err != nil {
+		return &[]Post{}, PageInfo{}, err
+	}
+	return &posts, info, nil
+}
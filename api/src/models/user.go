@@ -1,21 +1,63 @@
 package models
 
 import (
+	"time"
+
 	"gorm.io/gorm"
 )
 
 type User struct {
 	gorm.Model
-	Username          string `json:"username" gorm:"uniqueIndex;size:50"`
-	Email             string `json:"email" gorm:"uniqueIndex;size:255"`
-	Password          string `json:"password"`
-	FirstName         string `json:"firstName" gorm:"size:50"`
-	Surname           string `json:"surname" gorm:"size:50"`
-	Bio               string `json:"bio"`
-	ProfilePictureURL string `json:"profilePicture" gorm:"size:255"`
+	Username          string     `json:"username" gorm:"uniqueIndex;size:50"`
+	Email             string     `json:"email" gorm:"uniqueIndex;size:255"`
+	Password          string     `json:"password"`
+	FirstName         string     `json:"firstName" gorm:"size:50"`
+	Surname           string     `json:"surname" gorm:"size:50"`
+	Bio               string     `json:"bio"`
+	ProfilePictureURL string     `json:"profilePicture" gorm:"size:255"`
+	Role              string     `json:"role" gorm:"size:20;not null;default:member"`
+	SuspendedUntil    *time.Time `json:"suspended_until"` // set by a moderator. The user can't sign in or change anything until then
 	Posts             []Post
 	Comments          []Comment
-	Likes             []Like
+	Reactions         []Reaction
+	Blocks            []Block `json:"-" gorm:"foreignKey:BlockerID"` // the people this user has blocked
+	Mutes             []Mute  `json:"-" gorm:"foreignKey:MuterID"`   // the people this user has muted
+}
+
+// The roles a user can have. Moderators can look after other people's content.
+const (
+	RoleMember    = "member"
+	RoleModerator = "moderator"
+)
+
+func (user *User) IsModerator() bool {
+	return user.Role == RoleModerator
+}
+
+func (user *User) IsSuspended(now time.Time) bool {
+	return user.SuspendedUntil != nil && user.SuspendedUntil.After(now)
+}
+
+// FindSuspension returns when the user's suspension ends, or nil if they aren't suspended
+func FindSuspension(userID string, now time.Time) (*time.Time, error) {
+	var user User
+	if err := Database.Select("id", "suspended_until").Where("id = ?", userID).First(&user).Error; err != nil {
+		return nil, err
+	}
+	if !user.IsSuspended(now) {
+		return nil, nil
+	}
+	return user.SuspendedUntil, nil
+}
+
+// MakeModerators gives the moderator role to the users with these usernames.
+// It returns how many users were changed.
+func MakeModerators(usernames []string) (int64, error) {
+	if len(usernames) == 0 {
+		return 0, nil
+	}
+	result := Database.Model(&User{}).Where("username IN ?", usernames).Update("role", RoleModerator)
+	return result.RowsAffected, result.Error
 }
 
 func (user *User) Save() (*User, error) {
@@ -37,6 +79,23 @@ func FindUser(id string) (*User, error) {
 	return &user, nil
 }
 
+// FindUsersByIDs loads several users in one query, keyed by ID. Any that don't exist are left out.
+func FindUsersByIDs(ids []uint) (map[uint]User, error) {
+	users := map[uint]User{}
+	if len(ids) == 0 {
+		return users, nil
+	}
+
+	var found []User
+	if err := Database.Where("id IN ?", ids).Find(&found).Error; err != nil {
+		return nil, err
+	}
+	for _, user := range found {
+		users[user.ID] = user
+	}
+	return users, nil
+}
+
 func FindUserByEmail(email string) (*User, error) {
 	var user User
 	err := Database.Where("email = ?", email).First(&user).Error
@@ -79,8 +138,10 @@ func DeleteUser(id uint) error {
 		return err
 	}
 
-	// Soft delete the user's likes
-	if err := tx.Where("user_id = ?", id).Delete(&Like{}).Error; err != nil {
+	// Soft delete the user's reactions (likes included). They're all given the same deleted_at as
+	// the user, so a restore brings back exactly these and not ones the user had already taken back.
+	deletedAt := time.Now()
+	if err := tx.Model(&Reaction{}).Where("user_id = ?", id).UpdateColumn("deleted_at", deletedAt).Error; err != nil {
 		tx.Rollback()
 		return err
 	}
@@ -92,7 +153,13 @@ func DeleteUser(id uint) error {
 	}
 
 	// Then soft delete the user
-	if err := tx.Delete(&User{}, id).Error; err != nil {
+	if err := tx.Model(&User{}).Where("id = ?", id).UpdateColumn("deleted_at", deletedAt).Error; err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	// Their likes and comments no longer count towards other people's posts
+	if err := recountPostCounters(tx, postsInteractedWithByUser(tx, id)); err != nil {
 		tx.Rollback()
 		return err
 	}
@@ -113,6 +180,8 @@ func RestoreDeletedUser(email string) (*User, error) {
 		return nil, err
 	}
 
+	deletedAt := user.DeletedAt // remember when, to find the reactions deleted with them
+
 	// Restore the user by clearing the DeletedAt field
 	if err := tx.Unscoped().Model(&user).Update("deleted_at", nil).Error; err != nil {
 		tx.Rollback()
@@ -125,8 +194,8 @@ func RestoreDeletedUser(email string) (*User, error) {
 		return nil, err
 	}
 
-	// Restore the user's likes
-	if err := tx.Unscoped().Model(&Like{}).Where("user_id = ? AND deleted_at IS NOT NULL", user.ID).Update("deleted_at", nil).Error; err != nil {
+	// Restore the reactions (likes included) that were deleted along with the user
+	if err := tx.Unscoped().Model(&Reaction{}).Where("user_id = ? AND deleted_at = ?", user.ID, deletedAt).Update("deleted_at", nil).Error; err != nil {
 		tx.Rollback()
 		return nil, err
 	}
@@ -137,6 +206,12 @@ func RestoreDeletedUser(email string) (*User, error) {
 		return nil, err
 	}
 
+	// Their likes and comments count towards other people's posts again
+	if err := recountPostCounters(tx, postsInteractedWithByUser(tx, user.ID)); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
 	// Commit the transaction
 	if err := tx.Commit().Error; err != nil {
 		return nil, err
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"gorm.io/gorm"
+)
+
+// Who gets to see whose posts and comments. Everything that lists or fetches them for someone
+// goes through these scopes, so the rules are kept in one place rather than in every controller.
+// table is the table being queried, which must have user_id (the author) and hidden_at columns.
+
+// listedFor leaves out anything the viewer shouldn't see in a list: everything accessibleTo
+// leaves out, and content from anyone they've muted
+func listedFor(viewerID uint, table string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.Scopes(accessibleTo(viewerID, table)).
+			Where(table+".user_id NOT IN (?)", Database.Model(&Mute{}).Select("muted_id").Where("muter_id = ?", viewerID))
+	}
+}
+
+// accessibleTo leaves out anything the viewer can't open, comment on or react to: content hidden
+// by a moderator (unless the viewer wrote it), and content from anyone they've blocked or been
+// blocked by. Muted people's content can still be opened directly.
+func accessibleTo(viewerID uint, table string) func(*gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		return db.
+			Where(table+".hidden_at IS NULL OR "+table+".user_id = ?", viewerID).
+			Where(table+".user_id NOT IN (?)", Database.Model(&Block{}).Select("blocked_id").Where("blocker_id = ?", viewerID)).
+			Where(table+".user_id NOT IN (?)", Database.Model(&Block{}).Select("blocker_id").Where("blocked_id = ?", viewerID))
+	}
+}
+
+// FetchPostForViewer fetches a post the viewer is allowed to see. If they aren't it fails
+// with gorm.ErrRecordNotFound, the same as if it didn't exist.
+func FetchPostForViewer(id uint, viewerID uint) (*Post, error) {
+	var post Post
+	err := Database.Scopes(accessibleTo(viewerID, "posts")).First(&post, id).Error
+	if err != nil {
+		return &Post{}, err
+	}
+	return &post, nil
+}
+
+// FetchCommentForViewer fetches a comment the viewer is allowed to see, like FetchPostForViewer.
+// Comments on posts the viewer can't see are left out too.
+func FetchCommentForViewer(id uint, viewerID uint) (*Comment, error) {
+	var comment Comment
+	err := Database.Scopes(accessibleTo(viewerID, "comments")).
+		Where("comments.post_id IN (?)", Database.Model(&Post{}).Select("posts.id").Scopes(accessibleTo(viewerID, "posts"))).
+		First(&comment, id).Error
+	if err != nil {
+		return &Comment{}, err
+	}
+	return &comment, nil
+}
@@ -0,0 +1,165 @@
+package models
+
+import (
+	"math"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Why XP was awarded. Each reason has a fixed amount, see XPAmounts.
+const (
+	XPPostCreated    = "post_created"
+	XPCorrectAnswer  = "correct_answer"
+	XPLikeReceived   = "like_received"
+	XPLikeRemoved    = "like_removed" // reverses a like_received entry when the like is taken back
+	XPDailyStreak    = "daily_streak"
+	XPAnswerRescored = "answer_rescored" // gives or takes back correct_answer XP when an answer change re-scores an attempt
+)
+
+var XPAmounts = map[string]int{
+	XPPostCreated:   10,
+	XPCorrectAnswer: 20,
+	XPLikeReceived:  5,
+	XPLikeRemoved:   -5,
+	XPDailyStreak:   5, // multiplied by the streak length (capped) when awarded
+}
+
+// Daily streak bonuses stop growing after this many days
+const MaxDailyStreakMultiplier = 10
+
+// An XPEntry is one line in a user's XP ledger. Totals are always worked out by adding up
+// the ledger, so they can be audited (and rebuilt) rather than trusting a single counter.
+// The unique index stops the same thing being rewarded twice.
+type XPEntry struct {
+	gorm.Model
+	UserID     uint   `json:"user_id" gorm:"uniqueIndex:idx_xp_entries_source;constraint:OnDelete:CASCADE"`
+	Amount     int    `json:"amount"`
+	Reason     string `json:"reason" gorm:"size:30;uniqueIndex:idx_xp_entries_source"`
+	SourceType string `json:"source_type" gorm:"size:30;uniqueIndex:idx_xp_entries_source"` // e.g. "post", "like", "daily"
+	SourceID   uint   `json:"source_id" gorm:"uniqueIndex:idx_xp_entries_source"`
+}
+
+// AwardXP adds an entry to the user's ledger. Awarding the same reason for the same source
+// twice is ignored, so it's safe to call again after a retry.
+func AwardXP(userID uint, amount int, reason string, sourceType string, sourceID uint) error {
+	entry := XPEntry{UserID: userID, Amount: amount, Reason: reason, SourceType: sourceType, SourceID: sourceID}
+	return Database.Clauses(clause.OnConflict{DoNothing: true}).Create(&entry).Error
+}
+
+// Adds up the user's XP ledger
+func TotalXPForUser(userID uint) (int, error) {
+	var total int
+	err := Database.Model(&XPEntry{}).Where("user_id = ?", userID).Select("COALESCE(SUM(amount), 0)").Scan(&total).Error
+	if err != nil {
+		return 0, err
+	}
+	return total, nil
+}
+
+// Fetches the user's XP ledger, newest first
+func FetchXPEntriesByUserID(userID uint) (*[]XPEntry, error) {
+	var entries []XPEntry
+	err := Database.Where("user_id = ?", userID).Order("created_at DESC, id DESC").Find(&entries).Error
+	if err != nil {
+		return &[]XPEntry{}, err
+	}
+	return &entries, nil
+}
+
+// A LevelCurve decides how much XP each level needs. Level 1 needs Base XP to complete,
+// and every level after that needs Growth times as much as the one before.
+type LevelCurve struct {
+	Base   float64
+	Growth float64
+}
+
+// LevelForXP converts an XP total into a level, how far into that level the user is,
+// and how much XP the level needs in total
+func (curve LevelCurve) LevelForXP(total int) (int, int, int) {
+	level := 1
+	remaining := float64(max(total, 0))
+	needed := math.Max(curve.Base, 1)
+
+	for remaining >= needed {
+		remaining -= needed
+		level++
+		needed = math.Ceil(needed * math.Max(curve.Growth, 1))
+	}
+
+	return level, int(remaining), int(needed)
+}
+
+// RebuildXPLedger replays existing history (posts, correct answers, daily streaks and likes)
+// into the ledger. It only adds entries that are missing and never changes or removes one, so
+// it's safe to run as often as you like.
+func RebuildXPLedger() error {
+	return Database.Transaction(func(tx *gorm.DB) error {
+		// One entry per post created
+		if err := tx.Exec(`INSERT INTO xp_entries (created_at, updated_at, user_id, amount, reason, source_type, source_id)
+			SELECT posts.created_at, NOW(), posts.user_id, ?, ?, 'post', posts.id
+			FROM posts WHERE posts.deleted_at IS NULL
+			ON CONFLICT DO NOTHING`, XPAmounts[XPPostCreated], XPPostCreated).Error; err != nil {
+			return err
+		}
+
+		// One entry per post a user has answered correctly without having seen the answer
+		if err := tx.Exec(`INSERT INTO xp_entries (created_at, updated_at, user_id, amount, reason, source_type, source_id)
+			SELECT MIN(attempts.created_at), NOW(), attempts.user_id, ?, ?, 'post', attempts.post_id
+			FROM attempts WHERE attempts.correct AND NOT attempts.answer_seen AND attempts.deleted_at IS NULL
+			AND attempts.post_id NOT IN (SELECT post_id FROM answer_changes)
+			GROUP BY attempts.user_id, attempts.post_id
+			ON CONFLICT DO NOTHING`, XPAmounts[XPCorrectAnswer], XPCorrectAnswer).Error; err != nil {
+			return err
+		}
+
+		// Posts whose answer has changed may already have had the reward moved by re-scoring, booked
+		// against the post or any of its answer changes. Anyone whose attempts are correct now but who
+		// has nothing left from all of that gets the reward against the latest change, as
+		// AnswerRewardSource would book it today.
+		if err := tx.Exec(`INSERT INTO xp_entries (created_at, updated_at, user_id, amount, reason, source_type, source_id)
+			SELECT MIN(attempts.created_at), NOW(), attempts.user_id, ?, ?, 'answer_change',
+				(SELECT MAX(id) FROM answer_changes WHERE answer_changes.post_id = attempts.post_id)
+			FROM attempts WHERE attempts.correct AND NOT attempts.answer_seen AND attempts.deleted_at IS NULL
+			AND attempts.post_id IN (SELECT post_id FROM answer_changes)
+			AND NOT EXISTS (
+				SELECT 1 FROM xp_entries
+				WHERE xp_entries.user_id = attempts.user_id AND xp_entries.reason IN (?, ?) AND xp_entries.deleted_at IS NULL
+				AND ((xp_entries.source_type = 'post' AND xp_entries.source_id = attempts.post_id)
+					OR (xp_entries.source_type = 'answer_change' AND xp_entries.source_id IN
+						(SELECT id FROM answer_changes WHERE answer_changes.post_id = attempts.post_id)))
+				HAVING COALESCE(SUM(xp_entries.amount), 0) <> 0
+			)
+			GROUP BY attempts.user_id, attempts.post_id
+			ON CONFLICT DO NOTHING`, XPAmounts[XPCorrectAnswer], XPCorrectAnswer, XPCorrectAnswer, XPAnswerRescored).Error; err != nil {
+			return err
+		}
+
+		// One streak bonus per daily question answered correctly without having seen the answer,
+		// worth as much as the streak it made (capped), counting back over consecutive correct days
+		if err := tx.Exec(`INSERT INTO xp_entries (created_at, updated_at, user_id, amount, reason, source_type, source_id)
+			SELECT COALESCE(streaks.finished_at, streaks.updated_at), NOW(), streaks.user_id, ? * LEAST(streaks.streak, ?), ?, 'daily', streaks.id
+			FROM (
+				SELECT days.*, ROW_NUMBER() OVER (PARTITION BY days.user_id, days.run ORDER BY days.date) AS streak
+				FROM (
+					SELECT daily_attempts.*, daily_attempts.date::date - (ROW_NUMBER() OVER (PARTITION BY daily_attempts.user_id ORDER BY daily_attempts.date))::int AS run
+					FROM daily_attempts WHERE daily_attempts.correct AND daily_attempts.deleted_at IS NULL
+				) AS days
+			) AS streaks
+			WHERE EXISTS (
+				SELECT 1 FROM attempts
+				WHERE attempts.user_id = streaks.user_id AND attempts.post_id = streaks.post_id
+				AND attempts.correct AND NOT attempts.answer_seen AND attempts.deleted_at IS NULL
+			)
+			ON CONFLICT DO NOTHING`, XPAmounts[XPDailyStreak], MaxDailyStreakMultiplier, XPDailyStreak).Error; err != nil {
+			return err
+		}
+
+		// One entry per like received from someone else
+		return tx.Exec(`INSERT INTO xp_entries (created_at, updated_at, user_id, amount, reason, source_type, source_id)
+			SELECT reactions.created_at, NOW(), posts.user_id, ?, ?, 'like', reactions.id
+			FROM reactions JOIN posts ON posts.id = reactions.post_id
+			WHERE reactions.kind = ? AND reactions.deleted_at IS NULL AND reactions.user_id <> posts.user_id
+			ON CONFLICT DO NOTHING`, XPAmounts[XPLikeReceived], XPLikeReceived, ReactionLike).Error
+	})
+}
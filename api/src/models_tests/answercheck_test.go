@@ -0,0 +1,40 @@
+package models_tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/makersacademy/go-react-acebook-template/api/src/answercheck"
+)
+
+func TestWholeAnswersAreCorrectAllowingForTypos(t *testing.T) {
+	assert.True(t, answercheck.IsCorrect("canberra", "Canberra"))
+	assert.True(t, answercheck.IsCorrect("  CANBERRA! ", "Canberra"))
+	assert.True(t, answercheck.IsCorrect("Canbera", "Canberra"))
+	assert.True(t, answercheck.IsCorrect("38 years old, his name was Cream Puff", "38 years old! His name was Cream Puff."))
+	assert.False(t, answercheck.IsCorrect("Sydney", "Canberra"))
+}
+
+func TestPartOfTheAnswerIsNotCorrect(t *testing.T) {
+	creamPuff := "38 years old! His name was Cream Puff."
+	for _, guess := range []string{"name", "his name", "years old", "cream puff"} {
+		assert.False(t, answercheck.IsCorrect(guess, creamPuff), guess)
+	}
+
+	tied := "As of 2025, Messi and Ronaldo are tied"
+	for _, guess := range []string{"2025", "tied", "messi", "ronaldo"} {
+		assert.False(t, answercheck.IsCorrect(guess, tied), guess)
+	}
+}
+
+func TestOnlyTheWholeAnswerGivesItAway(t *testing.T) {
+	creamPuff := "38 years old! His name was Cream Puff."
+	assert.False(t, answercheck.Contains("What was his name again?", creamPuff))
+	assert.False(t, answercheck.Contains("Was it Cream Puff?", creamPuff))
+	assert.True(t, answercheck.Contains("Wow, 38 years old, his name was Cream Puff. Amazing", creamPuff))
+
+	assert.True(t, answercheck.Contains("Canberra is correct", "Canberra"))
+	assert.True(t, answercheck.Contains("it's canbera!", "Canberra"))
+	assert.False(t, answercheck.Contains("I've been to Canberrans' houses", "Canberra"))
+}
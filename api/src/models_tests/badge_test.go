@@ -0,0 +1,146 @@
+package models_tests
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/makersacademy/go-react-acebook-template/api/src/achievements"
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+)
+
+// newUser creates a user with no history, and removes them and everything they made once the test is over
+func newUser(t *testing.T, name string) uint {
+	unique := fmt.Sprintf("%s_%d", name, time.Now().UnixNano())
+	user := &models.User{Username: unique, Email: unique + "@test.com", Password: "password"}
+	_, err := user.Save()
+	require.NoError(t, err)
+
+	t.Cleanup(func() {
+		ownPosts := models.Database.Unscoped().Model(&models.Post{}).Select("id").Where("user_id = ?", user.ID)
+		models.Database.Unscoped().Where("user_id = ? OR post_id IN (?)", user.ID, ownPosts).Delete(&models.Attempt{})
+		models.Database.Unscoped().Where("user_id = ? OR post_id IN (?)", user.ID, ownPosts).Delete(&models.Comment{})
+		models.Database.Unscoped().Where("user_id = ?", user.ID).Delete(&models.Post{})
+		models.Database.Unscoped().Where("user_id = ?", user.ID).Delete(&models.UserBadge{})
+		models.Database.Unscoped().Delete(user)
+	})
+	return user.ID
+}
+
+// ownPost returns one of the user's posts, creating it the first time
+func ownPost(t *testing.T, userID uint) uint {
+	var post models.Post
+	err := models.Database.Where("user_id = ?", userID).
+		Attrs(models.Post{UserID: userID, Question: "Badge question?", Answer: "Yes"}).
+		FirstOrCreate(&post).Error
+	require.NoError(t, err)
+	return post.ID
+}
+
+// badgeProgress adds count more of what each metric measures to the user's history
+var badgeProgress = map[string]func(t *testing.T, userID uint, count int){
+	"posts_created": func(t *testing.T, userID uint, count int) {
+		for i := 0; i < count; i++ {
+			post := &models.Post{UserID: userID, Question: "Badge question?", Answer: "Yes"}
+			_, err := post.Save()
+			require.NoError(t, err)
+		}
+	},
+	"comments_posted": func(t *testing.T, userID uint, count int) {
+		postID := ownPost(t, userID)
+		for i := 0; i < count; i++ {
+			comment := &models.Comment{PostID: postID, UserID: userID, Content: "Nice one"}
+			_, err := comment.Save()
+			require.NoError(t, err)
+		}
+	},
+	"most_likes_on_a_post": func(t *testing.T, userID uint, count int) {
+		postID := ownPost(t, userID)
+		err := models.Database.Model(&models.Post{}).Where("id = ?", postID).
+			UpdateColumn("like_count", gorm.Expr("like_count + ?", count)).Error
+		require.NoError(t, err)
+	},
+	"users_stumped": func(t *testing.T, userID uint, count int) {
+		postID := ownPost(t, userID)
+		for i := 0; i < count; i++ {
+			attempt := &models.Attempt{PostID: postID, UserID: newUser(t, "stumped"), Guess: "No idea", Correct: false}
+			_, err := attempt.Save()
+			require.NoError(t, err)
+		}
+	},
+	"longest_correct_streak": func(t *testing.T, userID uint, count int) {
+		postID := ownPost(t, userID)
+		for i := 0; i < count; i++ {
+			attempt := &models.Attempt{PostID: postID, UserID: userID, Guess: "Yes", Correct: true}
+			_, err := attempt.Save()
+			require.NoError(t, err)
+		}
+	},
+}
+
+func badgeCodes(t *testing.T, userID uint) map[string]time.Time {
+	userBadges, err := models.FetchBadgesByUserID(userID)
+	require.NoError(t, err)
+
+	codes := map[string]time.Time{}
+	for _, userBadge := range *userBadges {
+		codes[userBadge.BadgeCode] = userBadge.AwardedAt
+	}
+	return codes
+}
+
+func TestEveryBadgeIsEarnedAtItsThreshold(t *testing.T) {
+	for _, badge := range achievements.AllBadges() {
+		t.Run(badge.Code, func(t *testing.T) {
+			addProgress, exists := badgeProgress[badge.Metric]
+			require.True(t, exists, "no test progress for the %s metric", badge.Metric)
+			userID := newUser(t, "badge")
+
+			// One short of the threshold isn't enough
+			addProgress(t, userID, int(badge.Threshold)-1)
+			achievements.Evaluate(userID, badge.Events[0])
+			assert.NotContains(t, badgeCodes(t, userID), badge.Code)
+
+			// Reaching it earns the badge
+			addProgress(t, userID, 1)
+			achievements.Evaluate(userID, badge.Events[0])
+			assert.Contains(t, badgeCodes(t, userID), badge.Code)
+		})
+	}
+}
+
+func TestLongestCorrectStreakIsBrokenByAWrongGuess(t *testing.T) {
+	userID := newUser(t, "streak")
+	postID := ownPost(t, userID)
+
+	longest, err := models.CountLongestCorrectStreak(userID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), longest)
+
+	for _, correct := range []bool{true, true, false, true, true, true, false, false, true} {
+		attempt := &models.Attempt{PostID: postID, UserID: userID, Guess: "Yes", Correct: correct}
+		_, err := attempt.Save()
+		require.NoError(t, err)
+	}
+
+	longest, err = models.CountLongestCorrectStreak(userID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), longest)
+}
+
+func TestBackfillingBadgesTwiceGrantsThemOnce(t *testing.T) {
+	userID := newUser(t, "backfill")
+	badgeProgress["posts_created"](t, userID, 1)
+
+	require.NoError(t, achievements.Backfill())
+	first := badgeCodes(t, userID)
+	assert.Contains(t, first, "first_question")
+
+	// Running it again changes nothing, not even when the badges were awarded
+	require.NoError(t, achievements.Backfill())
+	assert.Equal(t, first, badgeCodes(t, userID))
+}
@@ -0,0 +1,47 @@
+package models_tests
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+)
+
+// postAuthors lists who wrote the posts on the first page of the viewer's feed
+func postAuthors(t *testing.T, viewerID uint) map[uint]bool {
+	posts, _, err := models.FetchAllPosts(viewerID, models.Page{Limit: models.MaxPageSize})
+	require.NoError(t, err)
+	authors := map[uint]bool{}
+	for _, post := range *posts {
+		authors[post.UserID] = true
+	}
+	return authors
+}
+
+func TestBlockHidesContentBothWaysAndMuteOneWay(t *testing.T) {
+	// User 5 blocks user 1, so neither sees the other's posts, or can open them
+	_, err := models.BlockUser(5, 1)
+	require.NoError(t, err)
+	defer models.UnblockUser(5, 1)
+
+	assert.False(t, postAuthors(t, 5)[1])
+	assert.False(t, postAuthors(t, 1)[5])
+	assert.True(t, postAuthors(t, 2)[1])
+
+	_, err = models.FetchPostForViewer(1, 5) // post 1 is user 1's
+	assert.True(t, errors.Is(err, gorm.ErrRecordNotFound))
+	_, err = models.FetchPostForViewer(1, 2)
+	assert.NoError(t, err)
+
+	// User 2 mutes user 4, which hides user 4's posts from user 2's feed but nothing else
+	require.NoError(t, models.MuteUser(2, 4))
+	defer models.UnmuteUser(2, 4)
+
+	assert.False(t, postAuthors(t, 2)[4])
+	_, err = models.FetchPostForViewer(5, 2) // post 5 is user 4's, and can still be opened directly
+	assert.NoError(t, err)
+}
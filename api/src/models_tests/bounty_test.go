@@ -0,0 +1,55 @@
+package models_tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+)
+
+// bountyPost gives a new author 100 points and a post with a 20 point bounty that expires in an hour
+func bountyPost(t *testing.T) (uint, uint) {
+	authorID := newUser(t, "bounty")
+	require.NoError(t, models.AddPoints(models.Database, authorID, models.StartingPoints, models.PointsStartingBalance, "user", authorID))
+	postID := ownPost(t, authorID)
+	_, err := models.CreateBounty(postID, authorID, 20, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	return authorID, postID
+}
+
+func TestDeletingOrHidingAPostCancelsItsBounty(t *testing.T) {
+	deletedAuthor, deleted := bountyPost(t)
+	hiddenAuthor, hidden := bountyPost(t)
+
+	// One post is deleted by its author, the other is hidden by a moderator
+	require.NoError(t, models.DeletePost(deleted))
+	report, _, err := models.CreateReport(&models.Report{ReporterID: newUser(t, "reporter"), TargetType: models.ReportPost, TargetID: hidden, Reason: "spam"})
+	require.NoError(t, err)
+	_, err = models.ClaimReport(report.ID, 3, time.Now())
+	require.NoError(t, err)
+	_, err = models.ResolveReport(report.ID, 3, models.Resolution{Action: models.ModerationHide}, time.Now())
+	require.NoError(t, err)
+
+	// Both authors get their stake back, and nothing more
+	_, points := balances(t, deletedAuthor, hiddenAuthor)
+	assert.Equal(t, models.StartingPoints, points[deletedAuthor])
+	assert.Equal(t, models.StartingPoints, points[hiddenAuthor])
+
+	var bounties []models.Bounty
+	require.NoError(t, models.Database.Where("post_id IN ?", []uint{deleted, hidden}).Find(&bounties).Error)
+	require.Len(t, bounties, 2)
+	for _, bounty := range bounties {
+		assert.Equal(t, models.BountyCancelled, bounty.Status)
+		assert.Nil(t, bounty.WinnerID)
+	}
+
+	// Once they'd have expired, the expiry job doesn't pay the authors for them
+	expired, err := models.FetchExpiredOpenBounties(time.Now().Add(2 * time.Hour))
+	require.NoError(t, err)
+	for _, bounty := range *expired {
+		assert.NotContains(t, []uint{deleted, hidden}, bounty.PostID)
+	}
+}
@@ -3,6 +3,7 @@ package models_tests
 import (
 	"os"
 	"testing"
+	"time"
 
 	"github.com/joho/godotenv"
 	"github.com/stretchr/testify/assert"
@@ -87,4 +88,69 @@ func TestDeleteCommentByID(t *testing.T) {
 	fetched, err := models.FetchCommentByID(savedComment.ID) // Attempt to fetch the comment again
 	require.Error(t, err) // Ensure there WAS an error while fetching the deleted comment (cause it should not exist)
 	assert.Equal(t, uint(0), fetched.ID) // Assert that the fetched comment ID is 0 (indicating it was not found)
-}
\ No newline at end of file
+}
+
+func TestDeletedParentStaysAsPlaceholder(t *testing.T) {
+	// Create a comment with a reply to it
+	parent := &models.Comment{Content: "Testing threaded parent", PostID: 1, UserID: 1}
+	_, err := parent.Save()
+	require.NoError(t, err)
+	reply := &models.Comment{Content: "Testing threaded reply", PostID: 1, UserID: 1, ParentID: &parent.ID, Depth: 1}
+	_, err = reply.Save()
+	require.NoError(t, err)
+
+	// Delete the parent, which still has a reply
+	err = models.DeleteCommentByID(parent.ID)
+	require.NoError(t, err)
+
+	// The parent should still come back (as a deleted placeholder) when fetching the thread
+	comments, _, err := models.FetchCommentsPageByPostID(1, nil, 1, models.Page{Limit: models.MaxPageSize})
+	require.NoError(t, err)
+	foundParent := false
+	for _, comment := range *comments {
+		if comment.ID == parent.ID {
+			foundParent = true
+			assert.True(t, comment.IsDeleted()) // The parent is shown as "[deleted]"
+			assert.Equal(t, 1, comment.ReplyCount) // The reply is still counted
+		}
+	}
+	assert.True(t, foundParent)
+
+	// Once the reply is deleted too, the parent leaves the thread
+	err = models.DeleteCommentByID(reply.ID)
+	require.NoError(t, err)
+	replies, _, err := models.FetchCommentsPageByPostID(1, &parent.ID, 1, models.Page{})
+	require.NoError(t, err)
+	assert.Empty(t, *replies)
+}
+
+func TestEditCommentKeepsRevision(t *testing.T) {
+	// Create a comment and edit it
+	comment := &models.Comment{Content: "Testing comment edti", PostID: 1, UserID: 1}
+	_, err := comment.Save()
+	require.NoError(t, err)
+	edited, err := models.EditComment(comment.ID, "Testing comment edit", time.Now())
+	require.NoError(t, err)
+
+	// The comment has the new content and is marked as edited
+	assert.Equal(t, "Testing comment edit", edited.Content)
+	assert.NotNil(t, edited.EditedAt)
+
+	// The old content is kept as a revision
+	revisions, err := models.FetchCommentRevisions(comment.ID)
+	require.NoError(t, err)
+	require.Len(t, *revisions, 1)
+	assert.Equal(t, "Testing comment edti", (*revisions)[0].Content)
+}
+
+func TestCommentGivesAwayAnswer(t *testing.T) {
+	answers := []string{"Canberra"}
+
+	// Comments that contain the answer (even with a typo) give it away
+	assert.True(t, (&models.Comment{Content: "Common mistake, but Canberra is correct."}).GivesAwayAnswer(answers))
+	assert.True(t, (&models.Comment{Content: "it's canbera!"}).GivesAwayAnswer(answers))
+
+	// Other comments don't, unless the author tagged them as a spoiler
+	assert.False(t, (&models.Comment{Content: "Great question"}).GivesAwayAnswer(answers))
+	assert.True(t, (&models.Comment{Content: "It's not Sydney", Spoiler: true}).GivesAwayAnswer(answers))
+}
@@ -0,0 +1,43 @@
+package models_tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+)
+
+func TestDirectConversationIsReusedAndTracksUnread(t *testing.T) {
+	// Starting a chat with the same person twice (from either side) gives the same conversation
+	conversation, _, err := models.StartConversation(1, []uint{2}, "")
+	require.NoError(t, err)
+	again, created, err := models.StartConversation(2, []uint{1}, "")
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, conversation.ID, again.ID)
+
+	// User 1's message is unread for user 2, but not for user 1
+	conversation, err = models.FetchConversationForMember(conversation.ID, 1)
+	require.NoError(t, err)
+	_, err = models.SendMessage(conversation, 1, "Did you get question 3?")
+	require.NoError(t, err)
+
+	unread, err := models.CountUnreadMessages(2, []uint{conversation.ID})
+	require.NoError(t, err)
+	assert.NotZero(t, unread[conversation.ID])
+	unread, err = models.CountUnreadMessages(1, []uint{conversation.ID})
+	require.NoError(t, err)
+	assert.Zero(t, unread[conversation.ID])
+
+	require.NoError(t, models.MarkConversationRead(conversation.ID, 2, time.Now()))
+	unread, err = models.CountUnreadMessages(2, []uint{conversation.ID})
+	require.NoError(t, err)
+	assert.Zero(t, unread[conversation.ID])
+
+	// Someone who isn't in the conversation can't see it
+	_, err = models.FetchConversationForMember(conversation.ID, 3)
+	assert.Error(t, err)
+}
@@ -0,0 +1,49 @@
+package models_tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+)
+
+// postCounts gets the like and comment counts stored on a post
+func postCounts(t *testing.T, postID uint) (int, int) {
+	post, err := models.FetchPostByID(postID)
+	require.NoError(t, err)
+	return post.LikeCount, post.CommentCount
+}
+
+func TestReconcilingCountersRepairsDrift(t *testing.T) {
+	postID := ownPost(t, newUser(t, "counted"))
+	fanID := newUser(t, "fan")
+
+	_, _, err := models.LikePost(fanID, postID)
+	require.NoError(t, err)
+	comment := &models.Comment{PostID: postID, UserID: fanID, Content: "Great question"}
+	_, err = comment.Save()
+	require.NoError(t, err)
+
+	// Knock the stored counters out of line with the real likes and comments
+	err = models.Database.Model(&models.Post{}).Where("id = ?", postID).
+		UpdateColumns(map[string]interface{}{"like_count": 7, "comment_count": 0}).Error
+	require.NoError(t, err)
+
+	// Reconciling reports the drift and fixes it
+	drifts, err := models.ReconcilePostCounters()
+	require.NoError(t, err)
+	assert.Contains(t, drifts, models.CounterDrift{PostID: postID, LikeCount: 7, ActualLikes: 1, CommentCount: 0, ActualComments: 1})
+
+	likes, comments := postCounts(t, postID)
+	assert.Equal(t, 1, likes)
+	assert.Equal(t, 1, comments)
+
+	// Nothing is out of line now, so running it again doesn't report the post
+	drifts, err = models.ReconcilePostCounters()
+	require.NoError(t, err)
+	for _, drift := range drifts {
+		assert.NotEqual(t, postID, drift.PostID)
+	}
+}
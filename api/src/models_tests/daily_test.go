@@ -0,0 +1,54 @@
+package models_tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+)
+
+func TestDailyHistoryIsPagedAndLeavesOutPostsTheViewerCantSee(t *testing.T) {
+	viewerID := newUser(t, "viewer")
+	askerID := newUser(t, "asker")
+	dates := []string{"1991-01-01", "1991-01-02", "1991-01-03", "1991-01-04"}
+	postIDs := map[string]uint{}
+	for _, date := range dates {
+		post := &models.Post{UserID: askerID, Question: "Daily question?", Answer: "Yes", RevealPolicy: models.RevealAfterAttempt}
+		_, err := post.Save()
+		require.NoError(t, err)
+		postIDs[date] = post.ID
+		require.NoError(t, models.Database.Create(&models.DailyQuestion{Date: date, PostID: post.ID}).Error)
+	}
+	t.Cleanup(func() { models.Database.Unscoped().Where("date IN ?", dates).Delete(&models.DailyQuestion{}) })
+
+	// A moderator hides the post from the 3rd, and the viewer has had a go at the one from the 2nd
+	require.NoError(t, models.Database.Model(&models.Post{}).Where("id = ?", postIDs["1991-01-03"]).UpdateColumn("hidden_at", time.Now()).Error)
+	attempt := &models.Attempt{PostID: postIDs["1991-01-02"], UserID: viewerID, Guess: "No", Correct: false}
+	_, err := attempt.Save()
+	require.NoError(t, err)
+
+	// Newest first, two at a time, without the hidden one
+	first, info, err := models.FetchDailyQuestionsBefore("1991-01-10", viewerID, models.Page{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, *first, 2)
+	assert.Equal(t, "1991-01-04", (*first)[0].Date)
+	assert.Equal(t, "1991-01-02", (*first)[1].Date)
+	require.NotNil(t, info.NextCursor)
+
+	after, err := models.DecodeCursor(*info.NextCursor)
+	require.NoError(t, err)
+	second, info, err := models.FetchDailyQuestionsBefore("1991-01-10", viewerID, models.Page{Limit: 2, After: after})
+	require.NoError(t, err)
+	require.Len(t, *second, 1)
+	assert.Equal(t, "1991-01-01", (*second)[0].Date)
+	assert.Nil(t, info.NextCursor)
+
+	// Only the answer to the one they've had a go at is visible
+	posts := []models.Post{(*first)[0].Post, (*first)[1].Post, (*second)[0].Post}
+	visible, err := models.AnswersVisibleTo(posts, viewerID)
+	require.NoError(t, err)
+	assert.Equal(t, map[uint]bool{postIDs["1991-01-04"]: false, postIDs["1991-01-02"]: true, postIDs["1991-01-01"]: false}, visible)
+}
@@ -0,0 +1,110 @@
+package models_tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+)
+
+// balances gets the XP and points of each user
+func balances(t *testing.T, userIDs ...uint) (map[uint]int, map[uint]int) {
+	xp, points := map[uint]int{}, map[uint]int{}
+	for _, userID := range userIDs {
+		total, err := models.TotalXPForUser(userID)
+		require.NoError(t, err)
+		xp[userID] = total
+		balance, err := models.PointsBalanceForUser(models.Database, userID)
+		require.NoError(t, err)
+		points[userID] = balance
+	}
+	return xp, points
+}
+
+// answer saves an attempt, rewarding it the way the attempts controller does if it's right
+func answer(t *testing.T, postID uint, userID uint, guess string, correct bool) {
+	attempt := &models.Attempt{PostID: postID, UserID: userID, Guess: guess, Correct: correct}
+	_, err := attempt.Save()
+	require.NoError(t, err)
+	if !correct {
+		return
+	}
+
+	sourceType, sourceID, err := models.AnswerRewardSource(models.Database, postID)
+	require.NoError(t, err)
+	require.NoError(t, models.AwardXP(userID, models.XPAmounts[models.XPCorrectAnswer], models.XPCorrectAnswer, sourceType, sourceID))
+	require.NoError(t, models.AddPoints(models.Database, userID, models.CorrectAnswerPoints, models.PointsCorrectAnswer, sourceType, sourceID))
+	_, err = models.ClaimBounty(postID, userID, time.Now())
+	require.NoError(t, err)
+}
+
+func TestAcceptingADisputeMovesRewardsAndTheBounty(t *testing.T) {
+	require.NoError(t, models.GrantStartingPoints())
+
+	post := &models.Post{Question: "Capital of France?", Answer: "Paris", RevealPolicy: models.RevealAfterCorrectCount, RevealAfterCorrect: 5, UserID: 1}
+	_, err := post.Save()
+	require.NoError(t, err)
+	_, err = models.CreateBounty(post.ID, 1, 20, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+
+	// User 2 guesses "Lyon" (wrong for now), then user 3 gets "Paris" and wins the bounty
+	answer(t, post.ID, 2, "Lyon", false)
+	answer(t, post.ID, 3, "Paris", true)
+	xpBefore, pointsBefore := balances(t, 2, 3)
+
+	// The author accepts a dispute saying the answer is really "Lyon"
+	dispute := &models.Dispute{PostID: post.ID, UserID: 4, ProposedAnswer: "Lyon", Justification: "It moved"}
+	_, err = dispute.Save()
+	require.NoError(t, err)
+	require.NoError(t, models.AcceptDispute(dispute.ID, models.ResolutionReplace, false))
+
+	// User 3's reward and bounty go to user 2, who got there first
+	xpAfter, pointsAfter := balances(t, 2, 3)
+	assert.Equal(t, xpBefore[2]+models.XPAmounts[models.XPCorrectAnswer], xpAfter[2])
+	assert.Equal(t, xpBefore[3]-models.XPAmounts[models.XPCorrectAnswer], xpAfter[3])
+	assert.Equal(t, pointsBefore[2]+models.CorrectAnswerPoints+20, pointsAfter[2])
+	assert.Equal(t, pointsBefore[3]-models.CorrectAnswerPoints-20, pointsAfter[3])
+
+	bounty, err := models.FetchBountyByPostID(post.ID)
+	require.NoError(t, err)
+	assert.Equal(t, models.BountyAnswered, bounty.Status)
+	require.NotNil(t, bounty.WinnerID)
+	assert.Equal(t, uint(2), *bounty.WinnerID)
+
+	// Changing the answer back by editing the post undoes it all, and user 3 wins again
+	_, err = models.UpdatePost(post.ID, map[string]interface{}{"answer": "Paris"})
+	require.NoError(t, err)
+	xpAgain, pointsAgain := balances(t, 2, 3)
+	assert.Equal(t, xpBefore, xpAgain)
+	assert.Equal(t, pointsBefore, pointsAgain)
+}
+
+func TestOnlyPeopleWhoKnowTheAnswerCanVoteOnADispute(t *testing.T) {
+	post := &models.Post{Question: "Capital of Australia?", Answer: "Canberra", RevealPolicy: models.RevealAfterAttempt, UserID: 1}
+	_, err := post.Save()
+	require.NoError(t, err)
+	answer(t, post.ID, 2, "Sydney", false)
+
+	dispute := &models.Dispute{PostID: post.ID, UserID: 2, ProposedAnswer: "Sydney", Justification: "Biggest city"}
+	_, err = dispute.Save()
+	require.NoError(t, err)
+
+	// The person who opened it can't vote for it
+	err = models.SaveDisputeVote(dispute, post, 2, true)
+	assert.ErrorIs(t, err, models.ErrOwnDisputeVote)
+
+	// User 3 hasn't had a go, so hasn't seen the answer yet
+	err = models.SaveDisputeVote(dispute, post, 3, true)
+	assert.ErrorIs(t, err, models.ErrAnswerNotVisible)
+
+	// Once they've had a go they can
+	answer(t, post.ID, 3, "Canberra", true)
+	require.NoError(t, models.SaveDisputeVote(dispute, post, 3, false))
+	support, oppose, err := models.CountDisputeVotes(dispute.ID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), support)
+	assert.Equal(t, int64(1), oppose)
+}
@@ -0,0 +1,51 @@
+package models_tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/makersacademy/go-react-acebook-template/api/src/events"
+)
+
+func TestHubReplaysMissedEvents(t *testing.T) {
+	hub := events.NewHub(3, 10)
+	first := hub.Publish(events.LikeCreated, nil, "user:1")
+	second := hub.Publish(events.LikeCreated, nil, "user:2")
+	third := hub.Publish(events.LikeCreated, nil, "user:1")
+
+	// Reconnecting after the first event only replays the later ones for the same topic
+	subscription, missed, complete := hub.Subscribe("user:1", 1, first.ID)
+	defer hub.Unsubscribe(subscription)
+	assert.True(t, complete)
+	require.Len(t, missed, 1)
+	assert.Equal(t, third.ID, missed[0].ID)
+	assert.Less(t, first.ID, second.ID)
+
+	// Once the first event has been forgotten, a client that saw nothing after it has missed too much
+	hub.Publish(events.LikeCreated, nil, "user:2")
+	hub.Publish(events.LikeCreated, nil, "user:2")
+	_, _, complete = hub.Subscribe("user:1", 1, first.ID)
+	assert.False(t, complete)
+}
+
+func TestHubDropsSlowSubscribers(t *testing.T) {
+	hub := events.NewHub(10, 2)
+	subscription, _, _ := hub.Subscribe("user:1", 1, 0)
+
+	// The subscription can hold two events. The third drops it rather than blocking.
+	for i := 0; i < 3; i++ {
+		hub.Publish(events.LikeCreated, nil, "user:1")
+	}
+	assert.Equal(t, 0, hub.SubscriberCount("user:1"))
+
+	received := 0
+	for range subscription.Events() {
+		received++
+	}
+	assert.Equal(t, 2, received)
+
+	// Unsubscribing after being dropped is fine
+	hub.Unsubscribe(subscription)
+}
@@ -0,0 +1,48 @@
+package models_tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+)
+
+func TestFollowersArePagedAndCounted(t *testing.T) {
+	followeeID := newUser(t, "popular")
+	var followerIDs []uint
+	for i := 0; i < 3; i++ {
+		followerID := newUser(t, "follower")
+		_, err := models.FollowUser(followerID, followeeID)
+		require.NoError(t, err)
+		followerIDs = append(followerIDs, followerID)
+	}
+
+	followers, following, err := models.CountFollows(followeeID)
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), followers)
+	assert.Equal(t, int64(0), following)
+
+	// Newest follower first, two at a time
+	first, info, err := models.FetchFollowersPage(followeeID, models.Page{Limit: 2})
+	require.NoError(t, err)
+	require.Len(t, *first, 2)
+	assert.Equal(t, followerIDs[2], (*first)[0].Follower.ID)
+	assert.Equal(t, followerIDs[1], (*first)[1].Follower.ID)
+	require.NotNil(t, info.NextCursor)
+
+	after, err := models.DecodeCursor(*info.NextCursor)
+	require.NoError(t, err)
+	second, info, err := models.FetchFollowersPage(followeeID, models.Page{Limit: 2, After: after})
+	require.NoError(t, err)
+	require.Len(t, *second, 1)
+	assert.Equal(t, followerIDs[0], (*second)[0].Follower.ID)
+	assert.Nil(t, info.NextCursor)
+
+	// And the other way round
+	followed, _, err := models.FetchFollowingPage(followerIDs[0], models.Page{})
+	require.NoError(t, err)
+	require.Len(t, *followed, 1)
+	assert.Equal(t, followeeID, (*followed)[0].Followee.ID)
+}
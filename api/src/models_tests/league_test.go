@@ -0,0 +1,121 @@
+package models_tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+)
+
+// newSeason creates the season for the given month, and removes it and the season after it
+// (where CloseSeason puts everyone) once the test is over
+func newSeason(t *testing.T, month time.Time) *models.Season {
+	key, _, endsAt := models.SeasonBounds(month)
+	nextKey, _, _ := models.SeasonBounds(endsAt)
+	clear := func() {
+		seasonIDs := models.Database.Unscoped().Model(&models.Season{}).Select("id").Where("key IN ?", []string{key, nextKey})
+		models.Database.Unscoped().Where("season_id IN (?)", seasonIDs).Delete(&models.LeagueMembership{})
+		models.Database.Unscoped().Where("key IN ?", []string{key, nextKey}).Delete(&models.Season{})
+	}
+	clear() // in case an earlier run stopped before cleaning up
+	t.Cleanup(clear)
+
+	season, err := models.EnsureSeason(models.Database, month)
+	require.NoError(t, err)
+	return season
+}
+
+// joinSeason puts a new user in a division, with the given quiz points earned during the season
+func joinSeason(t *testing.T, season *models.Season, division int, points int) uint {
+	userID := newUser(t, "league")
+	require.NoError(t, models.Database.Create(&models.LeagueMembership{SeasonID: season.ID, UserID: userID, Division: division}).Error)
+	earnPoints(t, season, userID, points, 1)
+	return userID
+}
+
+func earnPoints(t *testing.T, season *models.Season, userID uint, points int, sourceID uint) {
+	entry := models.PointsEntry{UserID: userID, Amount: points, Reason: models.PointsCorrectAnswer, SourceType: "test", SourceID: sourceID}
+	entry.CreatedAt = season.StartsAt.Add(time.Hour)
+	require.NoError(t, models.Database.Create(&entry).Error)
+}
+
+// seasonMemberships gets everyone's membership of a season, keyed by user
+func seasonMemberships(t *testing.T, seasonID uint) map[uint]models.LeagueMembership {
+	var memberships []models.LeagueMembership
+	require.NoError(t, models.Database.Where("season_id = ?", seasonID).Find(&memberships).Error)
+
+	byUser := map[uint]models.LeagueMembership{}
+	for _, membership := range memberships {
+		byUser[membership.UserID] = membership
+	}
+	return byUser
+}
+
+func TestClosingASeasonTwiceOnlyClosesItOnce(t *testing.T) {
+	season := newSeason(t, time.Date(1990, time.January, 1, 0, 0, 0, 0, time.UTC))
+	settings := models.LeagueSettings{DivisionSize: 2, MoveCount: 1}
+
+	top := joinSeason(t, season, 1, 30)
+	relegated := joinSeason(t, season, 1, 10)
+	promoted := joinSeason(t, season, 2, 20)
+	bottom := joinSeason(t, season, 2, 5)
+
+	require.NoError(t, models.CloseSeason(season.ID, settings, season.EndsAt))
+	closed := seasonMemberships(t, season.ID)
+
+	// Points earned after the first close (e.g. a late ledger entry) don't change anything
+	earnPoints(t, season, relegated, 100, 2)
+	require.NoError(t, models.CloseSeason(season.ID, settings, season.EndsAt.Add(time.Hour)))
+	assert.Equal(t, closed, seasonMemberships(t, season.ID))
+
+	assert.Equal(t, 2, closed[relegated].FinalRank)
+	assert.Equal(t, models.LeagueRelegated, closed[relegated].Outcome)
+	assert.Equal(t, 1, closed[promoted].FinalRank)
+	assert.Equal(t, models.LeaguePromoted, closed[promoted].Outcome)
+
+	// There's one set of memberships for next season, with the promotion and relegation applied
+	nextSeason, err := models.EnsureSeason(models.Database, season.EndsAt)
+	require.NoError(t, err)
+	next := seasonMemberships(t, nextSeason.ID)
+	require.Len(t, next, 4)
+	assert.Equal(t, 1, next[top].Division)
+	assert.Equal(t, 1, next[promoted].Division)
+	assert.Equal(t, 2, next[relegated].Division)
+	assert.Equal(t, 2, next[bottom].Division)
+}
+
+func TestSmallDivisionsDontPromoteAndRelegateTheSamePeople(t *testing.T) {
+	season := newSeason(t, time.Date(1990, time.March, 1, 0, 0, 0, 0, time.UTC))
+	settings := models.LeagueSettings{DivisionSize: 10, MoveCount: 2}
+
+	// The middle division has 3 people, fewer than the 4 needed to move 2 up and 2 down
+	alone := joinSeason(t, season, 1, 0)
+	first := joinSeason(t, season, 2, 30)
+	second := joinSeason(t, season, 2, 20)
+	third := joinSeason(t, season, 2, 10)
+	bottom := joinSeason(t, season, 3, 0)
+
+	require.NoError(t, models.CloseSeason(season.ID, settings, season.EndsAt))
+	closed := seasonMemberships(t, season.ID)
+
+	// Only the top and bottom of the middle division move, and whoever is in between stays
+	assert.Equal(t, models.LeaguePromoted, closed[first].Outcome)
+	assert.Equal(t, models.LeagueStayed, closed[second].Outcome)
+	assert.Equal(t, models.LeagueRelegated, closed[third].Outcome)
+
+	// The top and bottom divisions can each only move one way
+	assert.Equal(t, models.LeagueRelegated, closed[alone].Outcome)
+	assert.Equal(t, models.LeaguePromoted, closed[bottom].Outcome)
+
+	nextSeason, err := models.EnsureSeason(models.Database, season.EndsAt)
+	require.NoError(t, err)
+	next := seasonMemberships(t, nextSeason.ID)
+	assert.Equal(t, 1, next[first].Division)
+	assert.Equal(t, 2, next[second].Division)
+	assert.Equal(t, 3, next[third].Division)
+	assert.Equal(t, 2, next[alone].Division)
+	assert.Equal(t, 2, next[bottom].Division)
+}
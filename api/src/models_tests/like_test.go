@@ -0,0 +1,59 @@
+package models_tests
+
+import (
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+)
+
+func TestLikingTwiceOnlyCountsOnce(t *testing.T) {
+	postID := ownPost(t, newUser(t, "liked"))
+	fanID := newUser(t, "fan")
+
+	// Two likes sent at the same moment (a double click) only make one like
+	var wg sync.WaitGroup
+	created := make([]bool, 2)
+	errs := make([]error, 2)
+	for i := range created {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			_, created[i], errs[i] = models.LikePost(fanID, postID)
+		}(i)
+	}
+	wg.Wait()
+	require.NoError(t, errs[0])
+	require.NoError(t, errs[1])
+	assert.True(t, created[0] != created[1]) // exactly one of them made the like
+
+	likes, _ := postCounts(t, postID)
+	assert.Equal(t, 1, likes)
+
+	// Liking again later changes nothing either
+	_, createdAgain, err := models.LikePost(fanID, postID)
+	require.NoError(t, err)
+	assert.False(t, createdAgain)
+	likes, _ = postCounts(t, postID)
+	assert.Equal(t, 1, likes)
+
+	// Unliking twice only takes it away once
+	removed, err := models.UnlikePost(fanID, postID)
+	require.NoError(t, err)
+	assert.NotNil(t, removed)
+	removed, err = models.UnlikePost(fanID, postID)
+	require.NoError(t, err)
+	assert.Nil(t, removed)
+	likes, _ = postCounts(t, postID)
+	assert.Equal(t, 0, likes)
+
+	// The soft deleted like doesn't stop them liking it again
+	_, createdAgain, err = models.LikePost(fanID, postID)
+	require.NoError(t, err)
+	assert.True(t, createdAgain)
+	likes, _ = postCounts(t, postID)
+	assert.Equal(t, 1, likes)
+}
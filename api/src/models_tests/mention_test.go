@@ -0,0 +1,23 @@
+package models_tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+)
+
+func TestMentionSpans(t *testing.T) {
+	// Bob was mentioned as "@bob" but has since changed his username to Robert
+	mentions := []models.Mention{{UserID: 2, Handle: "bob", User: models.User{Username: "Robert"}}}
+	text := "😀 @Bob and @bob, but not a@bob.com or @nobody"
+
+	spans := models.MentionSpans(text, mentions)
+
+	// Both mentions of bob link to Robert, counting the emoji as 2 (like JavaScript does)
+	require.Len(t, spans, 2)
+	assert.Equal(t, models.MentionSpan{Start: 3, End: 7, UserID: 2, Username: "Robert"}, spans[0])
+	assert.Equal(t, models.MentionSpan{Start: 12, End: 16, UserID: 2, Username: "Robert"}, spans[1])
+}
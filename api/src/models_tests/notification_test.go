@@ -0,0 +1,77 @@
+package models_tests
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+)
+
+func TestNotifyGroupsRepeatedEvents(t *testing.T) {
+	// Start with nothing unread
+	require.NoError(t, models.MarkNotificationsRead(1, nil, time.Now()))
+	postID := uint(1)
+
+	// Two people like the post, and one of them likes it again after unliking
+	for _, actorID := range []uint{2, 3, 2} {
+		err := models.Notify(models.Database, models.NotificationEvent{UserID: 1, ActorID: actorID, Kind: models.NotificationLiked, PostID: &postID})
+		require.NoError(t, err)
+	}
+
+	// They're grouped into one notification, counting each person once
+	notifications, _, err := models.FetchNotificationsPage(1, models.Page{})
+	require.NoError(t, err)
+	require.NotEmpty(t, *notifications)
+	latest := (*notifications)[0]
+	assert.Equal(t, models.NotificationLiked, latest.Kind)
+	assert.Equal(t, 2, latest.ActorCount)
+	assert.Equal(t, "2 people liked your question", latest.Message)
+
+	unread, err := models.CountUnreadNotifications(1)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), unread)
+
+	// Once it's read, the next like starts a new notification
+	require.NoError(t, models.MarkNotificationsRead(1, []uint{latest.ID}, time.Now()))
+	err = models.Notify(models.Database, models.NotificationEvent{UserID: 1, ActorID: 3, Kind: models.NotificationLiked, PostID: &postID})
+	require.NoError(t, err)
+	notifications, _, err = models.FetchNotificationsPage(1, models.Page{})
+	require.NoError(t, err)
+	assert.NotEqual(t, latest.ID, (*notifications)[0].ID)
+	assert.Equal(t, 1, (*notifications)[0].ActorCount)
+}
+
+func TestEventsArrivingAtOnceShareOneNotification(t *testing.T) {
+	userID := newUser(t, "notified")
+	postID := ownPost(t, userID)
+	var actorIDs []uint
+	for i := 0; i < 5; i++ {
+		actorIDs = append(actorIDs, newUser(t, "liker"))
+	}
+
+	// Everyone likes the post at the same moment
+	var wg sync.WaitGroup
+	errs := make([]error, len(actorIDs))
+	for i, actorID := range actorIDs {
+		wg.Add(1)
+		go func(i int, actorID uint) {
+			defer wg.Done()
+			errs[i] = models.Notify(models.Database, models.NotificationEvent{UserID: userID, ActorID: actorID, Kind: models.NotificationLiked, PostID: &postID})
+		}(i, actorID)
+	}
+	wg.Wait()
+	for _, err := range errs {
+		require.NoError(t, err)
+	}
+
+	// There's still only one unread notification, counting all of them
+	notifications, _, err := models.FetchNotificationsPage(userID, models.Page{})
+	require.NoError(t, err)
+	require.Len(t, *notifications, 1)
+	assert.Equal(t, 5, (*notifications)[0].ActorCount)
+	assert.Equal(t, "5 people liked your question", (*notifications)[0].Message)
+}
@@ -0,0 +1,28 @@
+package models_tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+)
+
+func TestCursorRoundTrip(t *testing.T) {
+	rank := 12.5
+	cursor := models.Cursor{CreatedAt: time.Date(2025, 4, 1, 12, 0, 0, 0, time.UTC), ID: 20, Rank: &rank}
+
+	decoded, err := models.DecodeCursor(cursor.Encode())
+	require.NoError(t, err)
+
+	assert.True(t, cursor.CreatedAt.Equal(decoded.CreatedAt))
+	assert.Equal(t, cursor.ID, decoded.ID)
+	assert.Equal(t, rank, *decoded.Rank)
+}
+
+func TestDecodeCursorRejectsRubbish(t *testing.T) {
+	_, err := models.DecodeCursor("not a cursor")
+	assert.ErrorIs(t, err, models.ErrInvalidCursor)
+}
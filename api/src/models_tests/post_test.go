@@ -0,0 +1,76 @@
+package models_tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+)
+
+func TestAnswerHiddenUntilViewerAttempts(t *testing.T) {
+	// Create a post that only reveals its answer after the viewer has had a guess
+	post := &models.Post{
+		Question:     "Testing reveal after attempt",
+		Answer:       "Hidden",
+		RevealPolicy: models.RevealAfterAttempt,
+		UserID:       1, // The author (must exist in the database)
+	}
+	savedPost, err := post.Save()
+	require.NoError(t, err)
+
+	// The author can always see their own answer
+	visible, err := savedPost.IsAnswerVisibleTo(1)
+	require.NoError(t, err)
+	assert.True(t, visible)
+
+	// Another user can't see it before they've attempted
+	visible, err = savedPost.IsAnswerVisibleTo(2)
+	require.NoError(t, err)
+	assert.False(t, visible)
+
+	// Make a (wrong) attempt as the other user
+	attempt := &models.Attempt{PostID: savedPost.ID, UserID: 2, Guess: "Wrong", Correct: false}
+	_, err = attempt.Save()
+	require.NoError(t, err)
+
+	// Now the answer is revealed to them
+	visible, err = savedPost.IsAnswerVisibleTo(2)
+	require.NoError(t, err)
+	assert.True(t, visible)
+}
+
+func TestAnswerHiddenUntilRevealTime(t *testing.T) {
+	// Create a post that reveals its answer tomorrow
+	tomorrow := time.Now().Add(24 * time.Hour)
+	post := &models.Post{
+		Question:     "Testing reveal at time",
+		Answer:       "Later",
+		RevealPolicy: models.RevealAtTime,
+		RevealAt:     &tomorrow,
+		UserID:       1,
+	}
+	savedPost, err := post.Save()
+	require.NoError(t, err)
+
+	// Nobody else can see the answer yet
+	visible, err := savedPost.IsAnswerVisibleTo(2)
+	require.NoError(t, err)
+	assert.False(t, visible)
+
+	// Once the reveal time has passed it's visible to everyone
+	yesterday := time.Now().Add(-24 * time.Hour)
+	savedPost.RevealAt = &yesterday
+	visible, err = savedPost.IsAnswerVisibleTo(2)
+	require.NoError(t, err)
+	assert.True(t, visible)
+}
+
+func TestValidateRevealPolicy(t *testing.T) {
+	assert.NoError(t, models.ValidateRevealPolicy(models.RevealImmediately, 0, nil))
+	assert.Error(t, models.ValidateRevealPolicy(models.RevealAfterCorrectCount, 0, nil)) // needs a count
+	assert.Error(t, models.ValidateRevealPolicy(models.RevealAtTime, 0, nil))            // needs a time
+	assert.Error(t, models.ValidateRevealPolicy("whenever", 0, nil))                     // not a real policy
+}
@@ -0,0 +1,67 @@
+package models_tests
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/gorm"
+
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+)
+
+func TestResolvingAReportWithHideHidesThePostFromEveryoneButItsAuthor(t *testing.T) {
+	now := time.Now()
+	defer models.Database.Model(&models.Post{}).Where("id = ?", 1).UpdateColumn("hidden_at", nil)
+
+	// User 2 reports post 1 (user 1's), and reporting it again returns the same report
+	report, created, err := models.CreateReport(&models.Report{ReporterID: 2, TargetType: models.ReportPost, TargetID: 1, Reason: "spam"})
+	require.NoError(t, err)
+	assert.True(t, created)
+	again, created, err := models.CreateReport(&models.Report{ReporterID: 2, TargetType: models.ReportPost, TargetID: 1, Reason: "other"})
+	require.NoError(t, err)
+	assert.False(t, created)
+	assert.Equal(t, report.ID, again.ID)
+
+	// Only the moderator who claimed it can resolve it
+	_, err = models.ResolveReport(report.ID, 3, models.Resolution{Action: models.ModerationHide}, now)
+	assert.True(t, errors.Is(err, models.ErrReportNotClaimed))
+	_, err = models.ClaimReport(report.ID, 3, now)
+	require.NoError(t, err)
+	_, err = models.ClaimReport(report.ID, 4, now)
+	assert.True(t, errors.Is(err, models.ErrReportClaimed))
+
+	resolved, err := models.ResolveReport(report.ID, 3, models.Resolution{Action: models.ModerationHide, Note: "Spam"}, now)
+	require.NoError(t, err)
+	assert.Equal(t, models.ReportResolved, resolved.Status)
+
+	// The post is gone for everyone else, but its author still sees it, marked as hidden
+	_, err = models.FetchPostForViewer(1, 2)
+	assert.True(t, errors.Is(err, gorm.ErrRecordNotFound))
+	assert.False(t, postIsListed(t, 2, 1))
+
+	post, err := models.FetchPostForViewer(1, 1)
+	require.NoError(t, err)
+	assert.NotNil(t, post.HiddenAt)
+
+	// The claim and the decision are both in the log
+	entries, _, err := models.FetchModerationLogPage(models.ReportPost, 1, models.Page{Limit: models.MaxPageSize})
+	require.NoError(t, err)
+	require.Len(t, *entries, 2)
+	assert.Equal(t, models.ModerationHide, (*entries)[0].Action)
+	assert.Equal(t, models.ModerationClaim, (*entries)[1].Action)
+}
+
+// postIsListed checks whether the post is on the first page of the viewer's feed
+func postIsListed(t *testing.T, viewerID uint, postID uint) bool {
+	posts, _, err := models.FetchAllPosts(viewerID, models.Page{Limit: models.MaxPageSize})
+	require.NoError(t, err)
+	for _, post := range *posts {
+		if post.ID == postID {
+			return true
+		}
+	}
+	return false
+}
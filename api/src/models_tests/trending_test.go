@@ -0,0 +1,28 @@
+package models_tests
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+)
+
+func TestTrendingScoreDecaysWithAge(t *testing.T) {
+	weights := models.TrendingWeights{Like: 1, Comment: 2, Attempt: 0.5, Gravity: 1.8}
+	score := models.PostScore{Likes: 10, Comments: 2}
+
+	fresh := weights.TrendingScore(score, time.Hour)
+	old := weights.TrendingScore(score, 48*time.Hour)
+
+	assert.Greater(t, fresh, old)
+}
+
+func TestTrendingScoreFavoursHarderQuestions(t *testing.T) {
+	weights := models.TrendingWeights{Like: 1, Comment: 2, Attempt: 0.5, Gravity: 1.8}
+	easy := models.PostScore{Attempts: 10, AnswerRate: 1}
+	hard := models.PostScore{Attempts: 10, AnswerRate: 0.1}
+
+	assert.Greater(t, weights.TrendingScore(hard, time.Hour), weights.TrendingScore(easy, time.Hour))
+}
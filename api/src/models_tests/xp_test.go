@@ -0,0 +1,88 @@
+package models_tests
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+)
+
+func TestLevelForXP(t *testing.T) {
+	curve := models.LevelCurve{Base: 100, Growth: 1.5}
+
+	level, progress, target := curve.LevelForXP(0)
+	assert.Equal(t, 1, level) // Everyone starts at level 1
+	assert.Equal(t, 0, progress)
+	assert.Equal(t, 100, target)
+
+	level, progress, target = curve.LevelForXP(180)
+	assert.Equal(t, 2, level)     // 100 XP completes level 1
+	assert.Equal(t, 80, progress) // 80 XP into level 2
+	assert.Equal(t, 150, target)  // Level 2 needs 1.5 times as much
+}
+
+func TestAwardXPIsIdempotent(t *testing.T) {
+	before, err := models.TotalXPForUser(1)
+	require.NoError(t, err)
+
+	// Award XP for the same source twice
+	require.NoError(t, models.AwardXP(1, 10, models.XPPostCreated, "test", 999))
+	require.NoError(t, models.AwardXP(1, 10, models.XPPostCreated, "test", 999))
+
+	// It only counts once
+	after, err := models.TotalXPForUser(1)
+	require.NoError(t, err)
+	assert.Equal(t, before+10, after)
+}
+
+func TestRebuildingTheXPLedgerReplaysEverySource(t *testing.T) {
+	userID := newUser(t, "rebuild")
+	askerID := newUser(t, "asker")
+	newPost := func() uint {
+		post := &models.Post{UserID: askerID, Question: "Rebuild question?", Answer: "Yes"}
+		_, err := post.Save()
+		require.NoError(t, err)
+		t.Cleanup(func() { models.Database.Unscoped().Where("post_id = ?", post.ID).Delete(&models.AnswerChange{}) })
+		return post.ID
+	}
+	answered := func(postID uint) {
+		attempt := &models.Attempt{PostID: postID, UserID: userID, Guess: "Yes", Correct: true}
+		_, err := attempt.Save()
+		require.NoError(t, err)
+	}
+
+	// A post answered correctly, worth 20
+	plain := newPost()
+	answered(plain)
+
+	// A post whose answer changed, answered correctly and never rewarded, worth 20
+	changed := newPost()
+	require.NoError(t, models.Database.Create(&models.AnswerChange{PostID: changed, Answer: "Yes"}).Error)
+	answered(changed)
+
+	// A post where re-scoring already gave the reward, so it's worth nothing more
+	rescored := newPost()
+	change := models.AnswerChange{PostID: rescored, Answer: "Yes"}
+	require.NoError(t, models.Database.Create(&change).Error)
+	answered(rescored)
+	require.NoError(t, models.AwardXP(userID, models.XPAmounts[models.XPCorrectAnswer], models.XPAnswerRescored, "answer_change", change.ID))
+
+	// Two days in a row then a gap on the daily question, worth 5, 10 and 5
+	for _, date := range []string{"1990-01-01", "1990-01-02", "1990-01-04"} {
+		dailyAttempt := models.DailyAttempt{UserID: userID, Date: date, PostID: plain, Guesses: 1, Results: "Y", Correct: true}
+		require.NoError(t, models.Database.Create(&dailyAttempt).Error)
+	}
+
+	require.NoError(t, models.RebuildXPLedger())
+	total, err := models.TotalXPForUser(userID)
+	require.NoError(t, err)
+	assert.Equal(t, 20+20+20+5+10+5, total)
+
+	// Running it again adds nothing
+	require.NoError(t, models.RebuildXPLedger())
+	again, err := models.TotalXPForUser(userID)
+	require.NoError(t, err)
+	assert.Equal(t, total, again)
+}
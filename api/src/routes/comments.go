@@ -11,5 +11,9 @@ func setupCommentRoutes(baseRouter *gin.RouterGroup) {
 
 	comments.POST("", middleware.AuthenticationMiddleware, controllers.CreateComment)
 	comments.GET("/post/:post_id", middleware.AuthenticationMiddleware, controllers.GetCommentsByPostID)
+	comments.PUT("/:id", middleware.AuthenticationMiddleware, controllers.EditComment)
 	comments.DELETE("/:id", middleware.AuthenticationMiddleware, controllers.DeleteCommentByID)
+	comments.GET("/:id/revisions", middleware.AuthenticationMiddleware, controllers.GetCommentRevisions)
+	comments.PUT("/:id/reactions/:kind", middleware.AuthenticationMiddleware, controllers.ReactToComment)
+	comments.DELETE("/:id/reactions/:kind", middleware.AuthenticationMiddleware, controllers.UnreactToComment)
 }
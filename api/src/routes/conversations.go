@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/makersacademy/go-react-acebook-template/api/src/controllers"
+	"github.com/makersacademy/go-react-acebook-template/api/src/middleware"
+)
+
+func setupConversationRoutes(baseRouter *gin.RouterGroup) {
+	conversations := baseRouter.Group("/conversations")
+
+	conversations.GET("", middleware.AuthenticationMiddleware, controllers.GetConversations)
+	conversations.POST("", middleware.AuthenticationMiddleware, controllers.CreateConversation)
+	conversations.GET("/:id/messages", middleware.AuthenticationMiddleware, controllers.GetConversationMessages)
+	conversations.POST("/:id/messages", middleware.AuthenticationMiddleware, controllers.SendMessage)
+	conversations.POST("/:id/read", middleware.AuthenticationMiddleware, controllers.MarkConversationRead)
+}
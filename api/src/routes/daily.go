@@ -0,0 +1,16 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/makersacademy/go-react-acebook-template/api/src/controllers"
+	"github.com/makersacademy/go-react-acebook-template/api/src/middleware"
+)
+
+func setupDailyRoutes(baseRouter *gin.RouterGroup) {
+	daily := baseRouter.Group("/daily")
+
+	daily.GET("", middleware.AuthenticationMiddleware, controllers.GetDailyQuestion)          // Returns today's question and your progress on it
+	daily.POST("/hint", middleware.AuthenticationMiddleware, controllers.UseDailyHint)        // Uses up one of today's hints
+	daily.POST("/guess", middleware.AuthenticationMiddleware, controllers.GuessDailyQuestion) // Submits a guess at today's question
+	daily.GET("/history", middleware.AuthenticationMiddleware, controllers.GetDailyHistory)   // Returns past daily questions and how you did
+}
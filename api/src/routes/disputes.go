@@ -0,0 +1,17 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/makersacademy/go-react-acebook-template/api/src/controllers"
+	"github.com/makersacademy/go-react-acebook-template/api/src/middleware"
+)
+
+func setupDisputeRoutes(baseRouter *gin.RouterGroup) {
+	disputes := baseRouter.Group("/disputes")
+
+	disputes.POST("", middleware.AuthenticationMiddleware, controllers.CreateDispute)
+	disputes.GET("/post/:post_id", middleware.AuthenticationMiddleware, controllers.GetDisputesByPostID)
+	disputes.PUT("/:id/accept", middleware.AuthenticationMiddleware, controllers.AcceptDispute) // Author accepts the proposed answer
+	disputes.PUT("/:id/reject", middleware.AuthenticationMiddleware, controllers.RejectDispute) // Author rejects the dispute with a reason
+	disputes.POST("/:id/votes", middleware.AuthenticationMiddleware, controllers.VoteOnDispute) // Community vote for disputes the author ignores
+}
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/makersacademy/go-react-acebook-template/api/src/controllers"
+	"github.com/makersacademy/go-react-acebook-template/api/src/middleware"
+)
+
+func setupEventRoutes(baseRouter *gin.RouterGroup) {
+	eventsRouter := baseRouter.Group("/events")
+
+	eventsRouter.GET("/stream", middleware.StreamAuthenticationMiddleware, controllers.StreamEvents) // Server-Sent Events, stays open
+}
@@ -0,0 +1,13 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/makersacademy/go-react-acebook-template/api/src/controllers"
+	"github.com/makersacademy/go-react-acebook-template/api/src/middleware"
+)
+
+func setupLeagueRoutes(baseRouter *gin.RouterGroup) {
+	leagues := baseRouter.Group("/leagues")
+
+	leagues.GET("/current", middleware.AuthenticationMiddleware, controllers.GetCurrentLeague) // Returns your division table for this season
+}
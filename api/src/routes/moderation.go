@@ -0,0 +1,18 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/makersacademy/go-react-acebook-template/api/src/controllers"
+	"github.com/makersacademy/go-react-acebook-template/api/src/middleware"
+)
+
+func setupModerationRoutes(baseRouter *gin.RouterGroup) {
+	baseRouter.POST("/reports", middleware.AuthenticationMiddleware, controllers.CreateReport)
+
+	moderation := baseRouter.Group("/moderation")
+
+	moderation.GET("/reports", middleware.AuthenticationMiddleware, controllers.GetReports)
+	moderation.POST("/reports/:id/claim", middleware.AuthenticationMiddleware, controllers.ClaimReport)
+	moderation.POST("/reports/:id/resolve", middleware.AuthenticationMiddleware, controllers.ResolveReport)
+	moderation.GET("/log", middleware.AuthenticationMiddleware, controllers.GetModerationLog)
+}
@@ -0,0 +1,15 @@
+package routes
+
+import (
+	"github.com/gin-gonic/gin"
+	"github.com/makersacademy/go-react-acebook-template/api/src/controllers"
+	"github.com/makersacademy/go-react-acebook-template/api/src/middleware"
+)
+
+func setupNotificationRoutes(baseRouter *gin.RouterGroup) {
+	notifications := baseRouter.Group("/notifications")
+
+	notifications.GET("", middleware.AuthenticationMiddleware, controllers.GetNotifications)
+	notifications.GET("/unread_count", middleware.AuthenticationMiddleware, controllers.GetUnreadNotificationCount)
+	notifications.POST("/read", middleware.AuthenticationMiddleware, controllers.MarkNotificationsRead)
+}
@@ -12,9 +12,17 @@ func setupPostRoutes(baseRouter *gin.RouterGroup) {
 	posts.POST("", middleware.AuthenticationMiddleware, controllers.CreatePost)
 	posts.GET("", middleware.AuthenticationMiddleware, controllers.GetAllPosts)
 	posts.GET("/:id", middleware.AuthenticationMiddleware, controllers.GetPostByID)
-	posts.GET("/user/:id", middleware.AuthenticationMiddleware, controllers.GetPostsByUserID) // Returns all posts by a specific user
-	posts.GET("/self", middleware.AuthenticationMiddleware, controllers.GetCurrentUserPosts)  // Returns all posts by the currently logged in user
-	posts.DELETE("/:id", middleware.AuthenticationMiddleware, controllers.DeletePostByID)     // Deletes a post by ID
-	posts.PUT("/:id", middleware.AuthenticationMiddleware, controllers.UpdatePost)            // Updates a post by its ID
+	posts.GET("/user/:id", middleware.AuthenticationMiddleware, controllers.GetPostsByUserID)                    // Returns all posts by a specific user
+	posts.GET("/self", middleware.AuthenticationMiddleware, controllers.GetCurrentUserPosts)                     // Returns all posts by the currently logged in user
+	posts.GET("/self/review", middleware.AuthenticationMiddleware, controllers.GetCurrentUserPostsNeedingReview) // Returns the logged in user's posts that may be outdated
+	posts.DELETE("/:id", middleware.AuthenticationMiddleware, controllers.DeletePostByID)                        // Deletes a post by ID
+	posts.PUT("/:id", middleware.AuthenticationMiddleware, controllers.UpdatePost)                               // Updates a post by its ID
+	posts.POST("/:id/attempts", middleware.AuthenticationMiddleware, controllers.CreateAttempt)                  // Submits a guess at the answer to a post
+	posts.POST("/:id/bounty", middleware.AuthenticationMiddleware, controllers.CreateBounty)                     // Puts a points bounty on your own post
+	posts.PUT("/:id/like", middleware.AuthenticationMiddleware, controllers.LikePost)                            // Likes a post (safe to retry)
+	posts.DELETE("/:id/like", middleware.AuthenticationMiddleware, controllers.UnlikePost)                       // Unlikes a post (safe to retry)
+	posts.PUT("/:id/reactions/:kind", middleware.AuthenticationMiddleware, controllers.ReactToPost)              // Reacts to a post, e.g. "mind_blown" (safe to retry)
+	posts.DELETE("/:id/reactions/:kind", middleware.AuthenticationMiddleware, controllers.UnreactToPost)         // Takes back a reaction to a post (safe to retry)
+	posts.GET("/:id/live", middleware.StreamAuthenticationMiddleware, controllers.LivePost)                      // WebSocket of comments being added and deleted, and who's watching
 
 }
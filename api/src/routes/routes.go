@@ -8,5 +8,12 @@ func SetupRoutes(engine *gin.Engine) {
 	setupPostRoutes(apiRouter)
 	setupCommentRoutes(apiRouter)
 	setupLikeRoutes(apiRouter)
+	setupDisputeRoutes(apiRouter)
+	setupDailyRoutes(apiRouter)
+	setupLeagueRoutes(apiRouter)
+	setupNotificationRoutes(apiRouter)
+	setupConversationRoutes(apiRouter)
+	setupModerationRoutes(apiRouter)
+	setupEventRoutes(apiRouter)
 	setupAuthenticationRoutes(apiRouter)
 }
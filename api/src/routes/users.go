@@ -13,6 +13,18 @@ func setupUserRoutes(baseRouter *gin.RouterGroup) {
 	users.PUT("", middleware.AuthenticationMiddleware, controllers.UpdateUser)
 	users.GET("/me", middleware.AuthenticationMiddleware, controllers.GetCurrentUser)
 	users.DELETE("/me", middleware.AuthenticationMiddleware, controllers.DeleteUser)
+	users.GET("/me/blocks", middleware.AuthenticationMiddleware, controllers.GetBlockedUsers)
+	users.GET("/me/mutes", middleware.AuthenticationMiddleware, controllers.GetMutedUsers)
 	users.GET("/:id/likes", middleware.AuthenticationMiddleware, controllers.GetLikedPostsByUserID)
+	users.GET("/:id/xp-history", middleware.AuthenticationMiddleware, controllers.GetXPHistoryByUserID)
+	users.GET("/:id/seasons", middleware.AuthenticationMiddleware, controllers.GetSeasonHistoryByUserID)
+	users.POST("/:id/follow", middleware.AuthenticationMiddleware, controllers.FollowUser)
+	users.DELETE("/:id/follow", middleware.AuthenticationMiddleware, controllers.UnfollowUser)
+	users.POST("/:id/block", middleware.AuthenticationMiddleware, controllers.BlockUser)
+	users.DELETE("/:id/block", middleware.AuthenticationMiddleware, controllers.UnblockUser)
+	users.POST("/:id/mute", middleware.AuthenticationMiddleware, controllers.MuteUser)
+	users.DELETE("/:id/mute", middleware.AuthenticationMiddleware, controllers.UnmuteUser)
+	users.GET("/:id/followers", middleware.AuthenticationMiddleware, controllers.GetFollowersByUserID)
+	users.GET("/:id/following", middleware.AuthenticationMiddleware, controllers.GetFollowingByUserID)
 	users.GET("/:id", middleware.AuthenticationMiddleware, controllers.GetUserByID)
 }
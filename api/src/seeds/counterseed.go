@@ -0,0 +1,18 @@
+package seeds
+
+import (
+	"fmt"
+
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+	"gorm.io/gorm"
+)
+
+func CounterSeeds(db *gorm.DB) {
+	// The seeds above save likes and comments directly, so count them up onto their posts
+	drifts, err := models.ReconcilePostCounters()
+	if err != nil {
+		fmt.Printf("Error when counting likes and comments: %s\n", err)
+	} else {
+		fmt.Printf("Successfully counted likes and comments for %d posts\n", len(drifts))
+	}
+}
@@ -0,0 +1,26 @@
+package seeds
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+	"gorm.io/gorm"
+)
+
+func DailySeeds(db *gorm.DB) {
+	// Curated daily questions for the next few days, starting today.
+	// Any day without one gets picked automatically from the well-liked posts.
+	today := time.Now()
+	schedule := []uint{1, 5, 4}
+
+	for day, postID := range schedule {
+		date := models.DailyDate(today.AddDate(0, 0, day))
+		err := models.ScheduleDailyQuestion(date, postID)
+		if err != nil {
+			fmt.Printf("Error when scheduling daily question for %s\n", date)
+		} else {
+			fmt.Printf("Successfully scheduled post %d as the daily question for %s\n", postID, date)
+		}
+	}
+}
@@ -13,32 +13,43 @@ func LikeSeeds(db *gorm.DB) {
 	baseTime := time.Now().AddDate(0, 0, -10)
 	// ⬆️ We'll use this to create likes at different times (helpful for frontend sorting)
 
-	// Example Likes created below
-	// We create instances of the like model all within a slice to iterate over later
+	// Example Likes (and a few other reactions) created below
+	// We create a slice of the reactions to iterate over later, then turn each into a Reaction.
 	// Timestamps are set after the related post's creation time
-	likes := []models.Like{
-		{UserID: 1, PostID: 2, Model: gorm.Model{CreatedAt: baseTime.Add(1*time.Hour + 30*time.Minute)}},
-		{UserID: 2, PostID: 1, Model: gorm.Model{CreatedAt: baseTime.Add(1 * time.Hour)}},
-		{UserID: 3, PostID: 1, Model: gorm.Model{CreatedAt: baseTime.Add(1*time.Hour + 5*time.Minute)}},
-		{UserID: 4, PostID: 1, Model: gorm.Model{CreatedAt: baseTime.Add(1*time.Hour + 10*time.Minute)}},
-		{UserID: 5, PostID: 2, Model: gorm.Model{CreatedAt: baseTime.Add(2 * time.Hour)}},
-		{UserID: 2, PostID: 3, Model: gorm.Model{CreatedAt: baseTime.Add(3 * time.Hour)}},
-		{UserID: 1, PostID: 5, Model: gorm.Model{CreatedAt: baseTime.Add(9 * time.Hour)}},
-		{UserID: 3, PostID: 6, Model: gorm.Model{CreatedAt: baseTime.Add(26 * time.Hour)}},
-		{UserID: 4, PostID: 7, Model: gorm.Model{CreatedAt: baseTime.Add(50 * time.Hour)}},
-		{UserID: 5, PostID: 4, Model: gorm.Model{CreatedAt: baseTime.Add(5 * time.Hour)}},
-		{UserID: 1, PostID: 7, Model: gorm.Model{CreatedAt: baseTime.Add(52 * time.Hour)}},
+	type seedReaction struct {
+		UserID    uint
+		PostID    uint
+		Kind      string
+		CreatedAt time.Time
+	}
+	reactions := []seedReaction{
+		{UserID: 1, PostID: 2, Kind: models.ReactionLike, CreatedAt: baseTime.Add(1*time.Hour + 30*time.Minute)},
+		{UserID: 2, PostID: 1, Kind: models.ReactionLike, CreatedAt: baseTime.Add(1 * time.Hour)},
+		{UserID: 3, PostID: 1, Kind: models.ReactionLike, CreatedAt: baseTime.Add(1*time.Hour + 5*time.Minute)},
+		{UserID: 4, PostID: 1, Kind: models.ReactionLike, CreatedAt: baseTime.Add(1*time.Hour + 10*time.Minute)},
+		{UserID: 5, PostID: 2, Kind: models.ReactionLike, CreatedAt: baseTime.Add(2 * time.Hour)},
+		{UserID: 2, PostID: 3, Kind: models.ReactionLike, CreatedAt: baseTime.Add(3 * time.Hour)},
+		{UserID: 1, PostID: 5, Kind: models.ReactionLike, CreatedAt: baseTime.Add(9 * time.Hour)},
+		{UserID: 3, PostID: 6, Kind: models.ReactionLike, CreatedAt: baseTime.Add(26 * time.Hour)},
+		{UserID: 4, PostID: 7, Kind: models.ReactionLike, CreatedAt: baseTime.Add(50 * time.Hour)},
+		{UserID: 5, PostID: 4, Kind: models.ReactionLike, CreatedAt: baseTime.Add(5 * time.Hour)},
+		{UserID: 1, PostID: 7, Kind: models.ReactionLike, CreatedAt: baseTime.Add(52 * time.Hour)},
+		{UserID: 3, PostID: 1, Kind: models.ReactionMindBlown, CreatedAt: baseTime.Add(1*time.Hour + 6*time.Minute)},
+		{UserID: 5, PostID: 2, Kind: models.ReactionLearned, CreatedAt: baseTime.Add(2*time.Hour + 1*time.Minute)},
+		{UserID: 4, PostID: 5, Kind: models.ReactionTooEasy, CreatedAt: baseTime.Add(10 * time.Hour)},
 	}
 
-	// Here we iterate over the slice of likes, save each to a database and print
+	// Here we iterate over the slice of reactions, save each to a database and print
 	// either a confirmation or error
-	for _, like := range likes {
-		err := db.Save(&like).Error
+	for _, seed := range reactions {
+		postID := seed.PostID
+		reaction := models.Reaction{UserID: seed.UserID, PostID: &postID, Kind: seed.Kind, Model: gorm.Model{CreatedAt: seed.CreatedAt}}
+		err := db.Save(&reaction).Error
 		if err != nil {
-			fmt.Printf("Error when creating like for Post ID: %d by User ID: %d\n", like.PostID, like.UserID)
+			fmt.Printf("Error when creating %s reaction for Post ID: %d by User ID: %d\n", seed.Kind, seed.PostID, seed.UserID)
 		} else {
-			fmt.Printf("Successfully created like for Post ID: %d by User ID: %d (Created at: %s)\n",
-				like.PostID, like.UserID, like.CreatedAt.Format("2006-01-02 15:04:05"))
+			fmt.Printf("Successfully created %s reaction for Post ID: %d by User ID: %d (Created at: %s)\n",
+				seed.Kind, seed.PostID, seed.UserID, reaction.CreatedAt.Format("2006-01-02 15:04:05"))
 		}
 	}
 }
@@ -0,0 +1,18 @@
+package seeds
+
+import (
+	"fmt"
+
+	"github.com/makersacademy/go-react-acebook-template/api/src/models"
+	"gorm.io/gorm"
+)
+
+func PointsSeeds(db *gorm.DB) {
+	// Every user starts with some points so they can put bounties on their questions
+	err := models.GrantStartingPoints()
+	if err != nil {
+		fmt.Printf("Error when granting starting points: %s\n", err)
+	} else {
+		fmt.Printf("Successfully granted %d starting points to every user\n", models.StartingPoints)
+	}
+}
@@ -13,12 +13,15 @@ func PostSeeds(db *gorm.DB) {
 	baseTime := time.Now().AddDate(0, 0, -10)
 	// ⬆️ We'll use this to create posts at different times (helpful for frontend sorting)
 
+	// Some answers are only true "as of" a date, so they get a review date
+	nextSuperBowl := time.Date(2026, time.February, 9, 0, 0, 0, 0, time.UTC)
+
 	//Example Posts created below
 	//We create instances of the post model all within a slice to iterate over later
 	posts := []models.Post{
 		{UserID: 1, Question: "What is the capital city of australia?", Answer: "Canberra", Model: gorm.Model{CreatedAt: baseTime.Add(30 * time.Minute)}},
 		{UserID: 5, Question: "Which famous crime writer wrote the script for Orson Welles 1949 film noir classic The Third Man?", Answer: "Graham Greene", Model: gorm.Model{CreatedAt: baseTime.Add(1 * time.Hour)}},
-		{UserID: 3, Question: "Which American Football team has the highest number of superbowl wins?", Answer: "As of 2025 The New England Patriots are tied with the PittsBurgh Steelers", Model: gorm.Model{CreatedAt: baseTime.Add(2 * time.Hour)}},
+		{UserID: 3, Question: "Which American Football team has the highest number of superbowl wins?", Answer: "As of 2025 The New England Patriots are tied with the PittsBurgh Steelers", ReviewAfter: &nextSuperBowl, Model: gorm.Model{CreatedAt: baseTime.Add(2 * time.Hour)}},
 		{UserID: 1, Question: "When was the first ever photograph of a black hole taken?", Answer: "2019", Model: gorm.Model{CreatedAt: baseTime.Add(4 * time.Hour)}},
 		{UserID: 4, Question: "Which film won best picture at the 2017 Oscars?", Answer: "Moonlight", Model: gorm.Model{CreatedAt: baseTime.Add(8 * time.Hour)}},
 		{UserID: 2, Question: "How old was the oldest cat in the world?", Answer: "38 years old! His name was Cream Puff.", Model: gorm.Model{CreatedAt: baseTime.Add(24 * time.Hour)}},
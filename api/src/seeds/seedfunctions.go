@@ -10,11 +10,95 @@ func SeedDatabase(db *gorm.DB) {
 	PostSeeds(db)
 	CommentSeeds(db)
 	LikeSeeds(db)
+	CounterSeeds(db)
+	DailySeeds(db)
+	PointsSeeds(db)
 }
 
 func DropTablesifExist(db *gorm.DB) {
 	// This function executes raw SQL to drop all tables before reseeding
 
+	// moderation_log_entries table
+	db.Exec("DROP TABLE IF EXISTS moderation_log_entries")
+
+	// reports table
+	db.Exec("DROP TABLE IF EXISTS reports")
+
+	// messages table
+	db.Exec("DROP TABLE IF EXISTS messages")
+
+	// conversation_members table
+	db.Exec("DROP TABLE IF EXISTS conversation_members")
+
+	// conversations table
+	db.Exec("DROP TABLE IF EXISTS conversations")
+
+	// mutes table
+	db.Exec("DROP TABLE IF EXISTS mutes")
+
+	// blocks table
+	db.Exec("DROP TABLE IF EXISTS blocks")
+
+	// notification_actors table
+	db.Exec("DROP TABLE IF EXISTS notification_actors")
+
+	// mentions table
+	db.Exec("DROP TABLE IF EXISTS mentions")
+
+	// comment_revisions table
+	db.Exec("DROP TABLE IF EXISTS comment_revisions")
+
+	// reactions table
+	db.Exec("DROP TABLE IF EXISTS reactions")
+
+	// post_scores table
+	db.Exec("DROP TABLE IF EXISTS post_scores")
+
+	// follows table
+	db.Exec("DROP TABLE IF EXISTS follows")
+
+	// league_memberships table
+	db.Exec("DROP TABLE IF EXISTS league_memberships")
+
+	// seasons table
+	db.Exec("DROP TABLE IF EXISTS seasons")
+
+	// bounties table
+	db.Exec("DROP TABLE IF EXISTS bounties")
+
+	// points_entries table
+	db.Exec("DROP TABLE IF EXISTS points_entries")
+
+	// user_badges table
+	db.Exec("DROP TABLE IF EXISTS user_badges")
+
+	// xp_entries table
+	db.Exec("DROP TABLE IF EXISTS xp_entries")
+
+	// daily_attempts table
+	db.Exec("DROP TABLE IF EXISTS daily_attempts")
+
+	// daily_questions table
+	db.Exec("DROP TABLE IF EXISTS daily_questions")
+
+	// notifications table
+	db.Exec("DROP TABLE IF EXISTS notifications")
+
+	// dispute_votes table
+	db.Exec("DROP TABLE IF EXISTS dispute_votes")
+
+	// disputes table
+	db.Exec("DROP TABLE IF EXISTS disputes")
+
+	// answer_changes table
+	db.Exec("DROP TABLE IF EXISTS answer_changes")
+
+	// alternate_answers table
+	db.Exec("DROP TABLE IF EXISTS alternate_answers")
+
+	// attempts table
+	db.Exec("DROP TABLE IF EXISTS attempts")
+
 	// likes table
 	db.Exec("DROP TABLE IF EXISTS likes")
 	
@@ -12,7 +12,7 @@ func UserSeeds(db *gorm.DB) {
 	//Example Users created below
 	//We create instances of the user model all within a slice to iterate over later
 	users := []models.User{
-		{Username: "quizguy", Email: "quiz@email.com", Password: passwordhashing.HashPasswords("ilovequiz"), FirstName: "John", Surname: "Smith", Bio: "I love me a good pub quiz"},
+		{Username: "quizguy", Email: "quiz@email.com", Password: passwordhashing.HashPasswords("ilovequiz"), FirstName: "John", Surname: "Smith", Bio: "I love me a good pub quiz", Role: models.RoleModerator},
 		{Username: "CoolCat", Email: "cat@cat.com", Password: passwordhashing.HashPasswords("mouse"), FirstName: "Percival", Surname: "Green", Bio: "I am interested in cat based quizes"},
 		{Username: "NoSoftQuestions", Email: "cynic@eyebrowraise.com", Password: passwordhashing.HashPasswords("socrates1992"), FirstName: "Lucy", Surname: "Stone", Bio: "No one has ever answered one of my questions correctly"},
 		{Username: "CustardLover", Email: "custard@pudding.com", Password: passwordhashing.HashPasswords("cake"), FirstName: "Carol", Surname: "Harvester", Bio: "I only signed up because i thought this was a baking website"},